@@ -0,0 +1,96 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// The launcher starts the variant of a native image best suited to the CPU it runs on. It is invoked as
+//
+//	launcher <directory> <executable> <variant>... -- <arguments>...
+//
+// with the variants ordered from the most to the least preferred, and replaces itself with
+// <directory>/<variant>/<executable>, or <directory>/<executable> if there are no variants. The runtime options in
+// $BPL_NATIVE_IMAGE_GC_ARGS, such as -XX:MaxHeapSize=512m, are passed before the arguments.
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/paketo-buildpacks/native-image/v5/native/march"
+)
+
+// GCArgs is the environment variable holding the garbage collector options passed to the native image
+const GCArgs = "BPL_NATIVE_IMAGE_GC_ARGS"
+
+func main() {
+	if err := launch(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func launch(args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("usage: launcher <directory> <executable> <variant>... -- <arguments>...")
+	}
+	dir, executable := args[0], args[1]
+
+	var variants []string
+	args = args[2:]
+	for len(args) > 0 {
+		arg := args[0]
+		args = args[1:]
+		if arg == "--" {
+			break
+		}
+		variants = append(variants, arg)
+	}
+
+	path := filepath.Join(dir, executable)
+	if len(variants) > 0 {
+		variant, err := selectVariant(variants)
+		if err != nil {
+			return err
+		}
+		path = filepath.Join(dir, variant, executable)
+	}
+
+	args = append(strings.Fields(os.Getenv(GCArgs)), args...)
+	if err := syscall.Exec(path, append([]string{path}, args...), os.Environ()); err != nil {
+		return fmt.Errorf("unable to execute %s\n%w", path, err)
+	}
+	return nil
+}
+
+func selectVariant(variants []string) (string, error) {
+	in, err := os.Open("/proc/cpuinfo")
+	if err != nil {
+		return "", fmt.Errorf("unable to open /proc/cpuinfo\n%w", err)
+	}
+	defer in.Close()
+
+	features, err := march.Features(in)
+	if err != nil {
+		return "", fmt.Errorf("unable to read CPU features\n%w", err)
+	}
+
+	variant, ok := march.Select(variants, features)
+	if !ok {
+		return "", fmt.Errorf("none of the native image variants %v is supported by this CPU", variants)
+	}
+	return variant, nil
+}
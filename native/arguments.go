@@ -18,14 +18,15 @@ package native
 
 import (
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
-	"sort"
 	"strings"
 
 	"github.com/magiconair/properties"
 	"github.com/mattn/go-shellwords"
-	"github.com/paketo-buildpacks/libpak"
+	"github.com/paketo-buildpacks/libpak/bard"
+	"github.com/paketo-buildpacks/libpak/sherpa"
 )
 
 type Arguments interface {
@@ -34,17 +35,28 @@ type Arguments interface {
 
 // BaselineArguments provides a set of arguments that are always set
 type BaselineArguments struct {
-	StackID string
+	StackID   string
+	Target    Target
+	DebugInfo bool
+	GC        string
 }
 
 // Configure provides an initial set of arguments, it ignores any input arguments
 func (b BaselineArguments) Configure(_ []string) ([]string, string, error) {
 	var newArguments []string
 
-	if libpak.IsTinyStack(b.StackID) {
+	if NewRunImageForTarget(b.Target, b.StackID).LinksLibCOnly() {
 		newArguments = append(newArguments, "-H:+StaticExecutableWithDynamicLibC")
 	}
 
+	if b.DebugInfo {
+		newArguments = append(newArguments, "-g")
+	}
+
+	if b.GC != "" {
+		newArguments = append(newArguments, fmt.Sprintf("%s%s", gcArgumentPrefix, b.GC))
+	}
+
 	return newArguments, "", nil
 }
 
@@ -86,10 +98,7 @@ func (u UserFileArguments) Configure(inputArgs []string) ([]string, string, erro
 		return []string{}, "", fmt.Errorf("read arguments from %s\n%w", u.ArgumentsFile, err)
 	}
 
-	fileArgs := strings.Split(string(rawArgs), "\n")
-	if len(fileArgs) == 1 {
-		fileArgs = strings.Split(string(rawArgs), " ")
-	}
+	fileArgs := splitArgumentsFile(string(rawArgs))
 
 	if containsArg("-jar", fileArgs) {
 		fileArgs = replaceJarArguments(fileArgs)
@@ -105,6 +114,32 @@ func (u UserFileArguments) Configure(inputArgs []string) ([]string, string, erro
 
 }
 
+// splitArgumentsFile splits the contents of an argument file into arguments, one per line or separated by spaces if
+// there is a single line
+func splitArgumentsFile(raw string) []string {
+	args := strings.Split(raw, "\n")
+	if len(args) == 1 {
+		args = strings.Split(raw, " ")
+	}
+	return args
+}
+
+// readArgumentsFile reads the arguments of an argument file, omitting blank ones
+func readArgumentsFile(file string) ([]string, error) {
+	raw, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s\n%w", file, err)
+	}
+
+	var args []string
+	for _, a := range splitArgumentsFile(string(raw)) {
+		if a = strings.TrimSpace(a); a != "" {
+			args = append(args, a)
+		}
+	}
+	return args, nil
+}
+
 // containsArg checks if needle is found in haystack
 //
 // needle and haystack entries are processed as key=val strings where only the key must match
@@ -126,6 +161,16 @@ type ExplodedJarArguments struct {
 	ApplicationPath string
 	LayerPath       string
 	Manifest        *properties.Properties
+
+	// MainClass overrides the Start-Class or Main-Class of the manifest and ExecutableName the name of the executable,
+	// which defaults to the main class
+	MainClass      string
+	ExecutableName string
+
+	// IgnoreClassPath ignores $CLASSPATH, which describes the application rather than a JAR extracted from it
+	IgnoreClassPath bool
+
+	Logger bard.Logger
 }
 
 // NoStartOrMainClass is an error returned when a start or main class cannot be found
@@ -137,59 +182,181 @@ func (e NoStartOrMainClass) Error() string {
 
 // Configure appends arguments to inputArgs for building from an exploded JAR directory
 func (e ExplodedJarArguments) Configure(inputArgs []string) ([]string, string, error) {
-	startClass, ok := e.Manifest.Get("Start-Class")
-	if !ok {
-		startClass, ok = e.Manifest.Get("Main-Class")
-		if !ok {
-			return []string{}, "", NoStartOrMainClass{}
+	startClass := e.MainClass
+	if startClass == "" {
+		var ok bool
+		if startClass, ok = e.Manifest.Get("Start-Class"); !ok {
+			if startClass, ok = e.Manifest.Get("Main-Class"); !ok {
+				return []string{}, "", NoStartOrMainClass{}
+			}
 		}
 	}
 
-	cp := os.Getenv("CLASSPATH")
-	if cp == "" {
-		// CLASSPATH should have been done by upstream buildpacks, but just in case
-		cp = e.ApplicationPath
-		if v, ok := e.Manifest.Get("Class-Path"); ok {
-			cp = strings.Join([]string{cp, v}, string(filepath.ListSeparator))
-		}
+	name := startClass
+	if e.ExecutableName != "" {
+		name = e.ExecutableName
+	}
+
+	cp, err := e.ClassPath()
+	if err != nil {
+		return []string{}, "", fmt.Errorf("unable to determine class path\n%w", err)
 	}
 
 	inputArgs = append(inputArgs,
-		fmt.Sprintf("-H:Name=%s", filepath.Join(e.LayerPath, startClass)),
+		fmt.Sprintf("-H:Name=%s", filepath.Join(e.LayerPath, name)),
 		"-cp", cp,
 		startClass,
 	)
 
-	return inputArgs, startClass, nil
+	return inputArgs, name, nil
+}
+
+// ClassPath returns the class path of the exploded JAR directory
+func (e ExplodedJarArguments) ClassPath() (string, error) {
+	if !e.IgnoreClassPath {
+		if cp := os.Getenv("CLASSPATH"); cp != "" {
+			return cp, nil
+		}
+	}
+
+	// CLASSPATH should have been done by upstream buildpacks, but just in case
+	if IsSpringBoot(e.Manifest) {
+		return SpringBootClassPath(e.ApplicationPath, e.Manifest)
+	}
+
+	entries, err := ManifestClassPath(e.Manifest, e.ApplicationPath, e.Logger)
+	if err != nil {
+		return "", fmt.Errorf("unable to read Class-Path of manifest\n%w", err)
+	}
+
+	return strings.Join(append([]string{e.ApplicationPath}, entries...), string(filepath.ListSeparator)), nil
+}
+
+// ManifestClassPath returns the Class-Path of manifest as paths. The attribute is a space separated list of URLs
+// relative to base, the directory of the JAR. Entries that do not exist are omitted with a warning.
+func ManifestClassPath(manifest *properties.Properties, base string, logger bard.Logger) ([]string, error) {
+	var entries []string
+
+	for _, raw := range strings.Fields(manifest.GetString("Class-Path", "")) {
+		u, err := url.Parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse Class-Path entry %s\n%w", raw, err)
+		}
+
+		if u.Scheme != "" && u.Scheme != "file" {
+			warn(logger, fmt.Sprintf("Class-Path entry %s is not a file and is ignored", raw))
+			continue
+		}
+
+		path := filepath.FromSlash(u.Path)
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(base, path)
+		}
+
+		if ok, err := sherpa.Exists(path); err != nil {
+			return nil, fmt.Errorf("unable to check for %s\n%w", path, err)
+		} else if !ok {
+			warn(logger, fmt.Sprintf("Class-Path entry %s does not exist and is ignored", raw))
+			continue
+		}
+
+		entries = append(entries, path)
+	}
+
+	return entries, nil
 }
 
 // JarArguments provides a set of arguments specific to building from a jar file
 type JarArguments struct {
 	ApplicationPath string
 	JarFilePattern  string
+
+	// ExecutableName overrides the name of the executable, which is derived from the name of the JAR
+	ExecutableName string
+
+	// ExtractionPath is where a Spring Boot JAR, whose nested libraries native-image cannot read, is extracted to be
+	// built like an exploded JAR. Spring Boot JARs are built as is if empty.
+	ExtractionPath string
+
+	// MainClass overrides the Start-Class or Main-Class of the JAR
+	MainClass string
+
+	// Exclusions are patterns of JARs that are never selected if JarFilePattern matches several
+	Exclusions []string
+
+	Logger bard.Logger
 }
 
 func (j JarArguments) Configure(inputArgs []string) ([]string, string, error) {
-	file := filepath.Join(j.ApplicationPath, j.JarFilePattern)
-	candidates, err := filepath.Glob(file)
+	jar, err := FindJAR(j.ApplicationPath, j.JarFilePattern, j.Exclusions, j.Logger)
 	if err != nil {
-		return []string{}, "", fmt.Errorf("unable to find JAR with %s\n%w", j.JarFilePattern, err)
+		return []string{}, "", err
 	}
 
-	if len(candidates) != 1 {
-		sort.Strings(candidates)
-		return []string{}, "", fmt.Errorf("unable to find single JAR in %s, candidates: %s", j.JarFilePattern, candidates)
-	}
-
-	jarFileName := filepath.Base(candidates[0])
+	jarFileName := filepath.Base(jar)
 	startClass := strings.TrimSuffix(jarFileName, ".jar")
 
 	if containsArg("-jar", inputArgs) {
 		inputArgs = replaceJarArguments(inputArgs)
 	}
-	inputArgs = append(inputArgs, "-jar", candidates[0])
 
-	return inputArgs, startClass, nil
+	manifest, boot, err := SpringBootJAR(jar)
+	if err != nil {
+		return []string{}, "", fmt.Errorf("unable to check for Spring Boot JAR\n%w", err)
+	}
+
+	name := startClass
+	if j.ExecutableName != "" {
+		name = j.ExecutableName
+	}
+
+	if boot && j.ExtractionPath != "" {
+		if err := ExtractJAR(jar, j.ExtractionPath); err != nil {
+			return []string{}, "", fmt.Errorf("unable to extract Spring Boot JAR\n%w", err)
+		}
+
+		return ExplodedJarArguments{
+			ApplicationPath: j.ExtractionPath,
+			Manifest:        manifest,
+			MainClass:       j.MainClass,
+			ExecutableName:  name,
+			IgnoreClassPath: true,
+			Logger:          j.Logger,
+		}.Configure(inputArgs)
+	}
+
+	if boot && j.MainClass != "" {
+		warn(j.Logger, fmt.Sprintf("Main class %s is ignored, as the Spring Boot JAR %s is built with -jar", j.MainClass, jarFileName))
+	}
+
+	// a thin JAR is built from its class path, so that entries of its Class-Path that do not exist can be omitted, as
+	// is a JAR whose main class is overridden, since -jar always starts the Main-Class of its manifest
+	mainClass := manifest.GetString("Main-Class", "")
+	if j.MainClass != "" {
+		mainClass = j.MainClass
+	}
+
+	if _, ok := manifest.Get("Class-Path"); (ok || j.MainClass != "") && !boot && mainClass != "" {
+		entries, err := ManifestClassPath(manifest, filepath.Dir(jar), j.Logger)
+		if err != nil {
+			return []string{}, "", fmt.Errorf("unable to read Class-Path of %s\n%w", jar, err)
+		}
+
+		inputArgs = append(inputArgs,
+			fmt.Sprintf("-H:Name=%s", name),
+			"-cp", strings.Join(append([]string{jar}, entries...), string(filepath.ListSeparator)),
+			mainClass,
+		)
+		return inputArgs, name, nil
+	}
+
+	inputArgs = append(inputArgs, "-jar", jar)
+
+	if j.ExecutableName != "" {
+		inputArgs = append(inputArgs, fmt.Sprintf("-H:Name=%s", name))
+	}
+
+	return inputArgs, name, nil
 }
 
 func replaceJarArguments(fileArgs []string) []string {
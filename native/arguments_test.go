@@ -17,6 +17,7 @@
 package native_test
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -26,6 +27,7 @@ import (
 	"github.com/magiconair/properties"
 	. "github.com/onsi/gomega"
 	"github.com/paketo-buildpacks/libpak"
+	"github.com/paketo-buildpacks/libpak/bard"
 	"github.com/paketo-buildpacks/native-image/v5/native"
 	"github.com/sclevine/spec"
 )
@@ -71,6 +73,51 @@ func testArguments(t *testing.T, context spec.G, it spec.S) {
 			Expect(args).To(HaveLen(1))
 			Expect(args).To(Equal([]string{"-H:+StaticExecutableWithDynamicLibC"}))
 		})
+
+		it("does not set static executable with dynamic libc for a target", func() {
+			args, _, err := native.BaselineArguments{Target: native.Target{OS: "linux", Arch: "amd64"}}.Configure(nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(args).To(BeEmpty())
+
+			args, _, err = native.BaselineArguments{
+				Target: native.Target{OS: "linux", Arch: "amd64", DistroName: "ubuntu", DistroVersion: "22.04"},
+			}.Configure(nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(args).To(BeEmpty())
+		})
+
+		it("sets static executable with dynamic libc for a target on a tiny stack", func() {
+			args, _, err := native.BaselineArguments{Target: native.Target{OS: "linux", Arch: "amd64"}, StackID: libpak.JammyTinyStackID}.Configure(nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(args).To(Equal([]string{"-H:+StaticExecutableWithDynamicLibC"}))
+		})
+
+		it("sets debug info", func() {
+			args, _, err := native.BaselineArguments{DebugInfo: true}.Configure(nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(args).To(Equal([]string{"-g"}))
+		})
+
+		it("sets the garbage collector", func() {
+			args, _, err := native.BaselineArguments{GC: "epsilon"}.Configure(nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(args).To(Equal([]string{"--gc=epsilon"}))
+		})
+
+		it("sets G1 as native-image documents it", func() {
+			args, _, err := native.BaselineArguments{GC: native.GCG1}.Configure(nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(args).To(Equal([]string{"--gc=G1"}))
+		})
+
+		it("lets the user override the garbage collector", func() {
+			args, _, err := native.BaselineArguments{GC: "epsilon"}.Configure(nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			args, _, err = native.UserArguments{Arguments: "--gc=serial"}.Configure(args)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(args).To(Equal([]string{"--gc=serial"}))
+		})
 	})
 
 	context("user arguments", func() {
@@ -186,6 +233,7 @@ func testArguments(t *testing.T, context spec.G, it spec.S) {
 			Expect(err).NotTo(HaveOccurred())
 			_, _, err = props.Set("Class-Path", "manifest-class-path")
 			Expect(err).NotTo(HaveOccurred())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "manifest-class-path"), []byte{}, 0644)).To(Succeed())
 		})
 
 		it("adds arguments, no CLASSPATH set", func() {
@@ -202,10 +250,27 @@ func testArguments(t *testing.T, context spec.G, it spec.S) {
 				"stuff",
 				fmt.Sprintf("-H:Name=%s/test-start-class", layer.Path),
 				"-cp",
-				fmt.Sprintf("%s:%s", ctx.Application.Path, "manifest-class-path"),
+				fmt.Sprintf("%s:%s", ctx.Application.Path, filepath.Join(ctx.Application.Path, "manifest-class-path")),
 				"test-start-class"}))
 		})
 
+		it("uses the main class and executable name of upstream buildpacks", func() {
+			args, name, err := native.ExplodedJarArguments{
+				ApplicationPath: ctx.Application.Path,
+				LayerPath:       layer.Path,
+				Manifest:        props,
+				MainClass:       "org.example.Main",
+				ExecutableName:  "example",
+			}.Configure(nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(name).To(Equal("example"))
+			Expect(args).To(Equal([]string{
+				fmt.Sprintf("-H:Name=%s/example", layer.Path),
+				"-cp",
+				fmt.Sprintf("%s:%s", ctx.Application.Path, filepath.Join(ctx.Application.Path, "manifest-class-path")),
+				"org.example.Main"}))
+		})
+
 		it("fails to find start or main class", func() {
 			inputArgs := []string{"stuff"}
 			_, _, err := native.ExplodedJarArguments{
@@ -216,6 +281,29 @@ func testArguments(t *testing.T, context spec.G, it spec.S) {
 			Expect(err).To(MatchError("unable to read Start-Class or Main-Class from MANIFEST.MF"))
 		})
 
+		it("builds the class path of the Spring Boot layout, no CLASSPATH set", func() {
+			_, _, err := props.Set("Spring-Boot-Classes", "BOOT-INF/classes/")
+			Expect(err).NotTo(HaveOccurred())
+			_, _, err = props.Set("Spring-Boot-Lib", "BOOT-INF/lib/")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.MkdirAll(filepath.Join(ctx.Application.Path, "BOOT-INF", "lib"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "BOOT-INF", "lib", "dependency.jar"), []byte{}, 0644)).To(Succeed())
+
+			args, _, err := native.ExplodedJarArguments{
+				ApplicationPath: ctx.Application.Path,
+				LayerPath:       layer.Path,
+				Manifest:        props,
+			}.Configure(nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(args).To(Equal([]string{
+				fmt.Sprintf("-H:Name=%s/test-start-class", layer.Path),
+				"-cp",
+				fmt.Sprintf("%s:%s",
+					filepath.Join(ctx.Application.Path, "BOOT-INF", "classes"),
+					filepath.Join(ctx.Application.Path, "BOOT-INF", "lib", "dependency.jar")),
+				"test-start-class"}))
+		})
+
 		context("CLASSPATH is set", func() {
 			it.Before(func() {
 				Expect(os.Setenv("CLASSPATH", "some-classpath")).To(Succeed())
@@ -245,14 +333,50 @@ func testArguments(t *testing.T, context spec.G, it spec.S) {
 		})
 	})
 
+	context("manifest Class-Path", func() {
+		it("decodes, resolves and omits missing entries with a warning", func() {
+			Expect(os.MkdirAll(filepath.Join(ctx.Application.Path, "lib dir"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "lib dir", "a.jar"), []byte{}, 0644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(ctx.Layers.Path, "b.jar"), []byte{}, 0644)).To(Succeed())
+
+			manifest := properties.NewProperties()
+			_, _, err := manifest.Set("Class-Path", fmt.Sprintf("lib%%20dir/a.jar  file:%s missing.jar https://example.com/c.jar",
+				filepath.Join(ctx.Layers.Path, "b.jar")))
+			Expect(err).NotTo(HaveOccurred())
+
+			out := &bytes.Buffer{}
+			entries, err := native.ManifestClassPath(manifest, ctx.Application.Path, bard.NewLogger(out))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entries).To(Equal([]string{
+				filepath.Join(ctx.Application.Path, "lib dir", "a.jar"),
+				filepath.Join(ctx.Layers.Path, "b.jar"),
+			}))
+			Expect(out.String()).To(ContainSubstring("Class-Path entry missing.jar does not exist and is ignored"))
+			Expect(out.String()).To(ContainSubstring("Class-Path entry https://example.com/c.jar is not a file and is ignored"))
+		})
+	})
+
 	context("jar file", func() {
 		it.Before(func() {
 			Expect(os.MkdirAll(filepath.Join(ctx.Application.Path, "target"), 0755)).To(Succeed())
-			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "target", "found.jar"), []byte{}, 0644)).To(Succeed())
+			writeJAR(t, filepath.Join(ctx.Application.Path, "target", "found.jar"), map[string]string{
+				"META-INF/MANIFEST.MF": "Main-Class: test-main-class\n",
+			})
 			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "target", "a.two"), []byte{}, 0644)).To(Succeed())
 			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "target", "b.two"), []byte{}, 0644)).To(Succeed())
 		})
 
+		it("uses the executable name of upstream buildpacks", func() {
+			args, name, err := native.JarArguments{
+				ApplicationPath: ctx.Application.Path,
+				JarFilePattern:  "target/*.jar",
+				ExecutableName:  "example",
+			}.Configure(nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(name).To(Equal("example"))
+			Expect(args).To(Equal([]string{"-jar", filepath.Join(ctx.Application.Path, "target", "found.jar"), "-H:Name=example"}))
+		})
+
 		it("adds arguments", func() {
 			inputArgs := []string{"stuff"}
 			args, startClass, err := native.JarArguments{
@@ -285,6 +409,90 @@ func testArguments(t *testing.T, context spec.G, it spec.S) {
 			}))
 		})
 
+		it("extracts a Spring Boot JAR and builds it exploded", func() {
+			writeJAR(t, filepath.Join(ctx.Application.Path, "target", "found.jar"), map[string]string{
+				"META-INF/MANIFEST.MF":        "Start-Class: test-start-class\nSpring-Boot-Classes: BOOT-INF/classes/\nSpring-Boot-Lib: BOOT-INF/lib/\n",
+				"BOOT-INF/lib/dependency.jar": "",
+			})
+			Expect(os.Setenv("CLASSPATH", "some-classpath")).To(Succeed())
+			defer os.Unsetenv("CLASSPATH")
+
+			extracted := filepath.Join(ctx.Layers.Path, "extracted")
+			args, name, err := native.JarArguments{
+				ApplicationPath: ctx.Application.Path,
+				JarFilePattern:  "target/*.jar",
+				ExtractionPath:  extracted,
+			}.Configure([]string{"stuff", "-jar", "no-where"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(name).To(Equal("found"))
+			Expect(args).To(Equal([]string{
+				"stuff",
+				"-H:Name=found",
+				"-cp",
+				fmt.Sprintf("%s:%s",
+					filepath.Join(extracted, "BOOT-INF", "classes"),
+					filepath.Join(extracted, "BOOT-INF", "lib", "dependency.jar")),
+				"test-start-class",
+			}))
+		})
+
+		it("builds a thin JAR from its class path", func() {
+			writeJAR(t, filepath.Join(ctx.Application.Path, "target", "found.jar"), map[string]string{
+				"META-INF/MANIFEST.MF": "Main-Class: test-main-class\nClass-Path: lib/dependency.jar lib/missing.jar\n",
+			})
+			Expect(os.MkdirAll(filepath.Join(ctx.Application.Path, "target", "lib"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "target", "lib", "dependency.jar"), []byte{}, 0644)).To(Succeed())
+
+			args, name, err := native.JarArguments{
+				ApplicationPath: ctx.Application.Path,
+				JarFilePattern:  "target/*.jar",
+			}.Configure([]string{"stuff"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(name).To(Equal("found"))
+			Expect(args).To(Equal([]string{
+				"stuff",
+				"-H:Name=found",
+				"-cp",
+				fmt.Sprintf("%s:%s",
+					filepath.Join(ctx.Application.Path, "target", "found.jar"),
+					filepath.Join(ctx.Application.Path, "target", "lib", "dependency.jar")),
+				"test-main-class",
+			}))
+		})
+
+		it("builds a JAR from its class path if the main class is overridden", func() {
+			args, name, err := native.JarArguments{
+				ApplicationPath: ctx.Application.Path,
+				JarFilePattern:  "target/*.jar",
+				MainClass:       "org.example.Main",
+			}.Configure([]string{"stuff"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(name).To(Equal("found"))
+			Expect(args).To(Equal([]string{
+				"stuff",
+				"-H:Name=found",
+				"-cp", filepath.Join(ctx.Application.Path, "target", "found.jar"),
+				"org.example.Main",
+			}))
+		})
+
+		it("warns that the main class of a Spring Boot JAR built with -jar is ignored", func() {
+			writeJAR(t, filepath.Join(ctx.Application.Path, "target", "found.jar"), map[string]string{
+				"META-INF/MANIFEST.MF": "Start-Class: test-start-class\nSpring-Boot-Classes: BOOT-INF/classes/\nSpring-Boot-Lib: BOOT-INF/lib/\n",
+			})
+
+			out := &bytes.Buffer{}
+			args, _, err := native.JarArguments{
+				ApplicationPath: ctx.Application.Path,
+				JarFilePattern:  "target/*.jar",
+				MainClass:       "org.example.Main",
+				Logger:          bard.NewLogger(out),
+			}.Configure(nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(args).To(Equal([]string{"-jar", filepath.Join(ctx.Application.Path, "target", "found.jar")}))
+			Expect(out.String()).To(ContainSubstring("Main class org.example.Main is ignored"))
+		})
+
 		it("pattern doesn't match", func() {
 			inputArgs := []string{"stuff"}
 			_, _, err := native.JarArguments{
@@ -0,0 +1,193 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package native
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/buildpacks/libcnb"
+	"github.com/mattn/go-shellwords"
+	"github.com/paketo-buildpacks/libpak"
+	"github.com/paketo-buildpacks/libpak/bard"
+	"github.com/paketo-buildpacks/libpak/sherpa"
+)
+
+const (
+	// BindingType is the type of the service binding that supplies inputs to native-image, such as those shared across
+	// an organization
+	BindingType = "native-image"
+
+	// BindingArgs holds arguments, BindingArgfile an argument file, BindingMetadata a directory of reachability
+	// metadata and BindingPGO a directory of profiles for profile-guided optimization
+	BindingArgs     = "args"
+	BindingArgfile  = "argfile"
+	BindingMetadata = "metadata"
+	BindingPGO      = "pgo"
+
+	// BindingPolicy is a Policy that the arguments of native-image are checked against
+	BindingPolicy = "policy"
+)
+
+// BindingKeys are the keys of a native-image binding that are copied into the NativeImageBinding layer
+var BindingKeys = []string{BindingArgs, BindingArgfile, BindingMetadata, BindingPGO, BindingPolicy}
+
+// NativeImageBinding copies the inputs of a native-image binding into a layer, so that the native image is built from
+// a snapshot of them at a stable path. The layer is keyed by the contents of the bound files.
+type NativeImageBinding struct {
+	Binding libcnb.Binding
+	Logger  bard.Logger
+}
+
+// NewNativeImageBinding creates a new instance copying the inputs of binding
+func NewNativeImageBinding(binding libcnb.Binding) NativeImageBinding {
+	return NativeImageBinding{Binding: binding}
+}
+
+func (b NativeImageBinding) Contribute(layer libcnb.Layer) (libcnb.Layer, error) {
+	paths := b.paths()
+
+	// the unresolved paths are digested, as the targets of the links change whenever Kubernetes updates the binding
+	var roots []string
+	for _, key := range BindingKeys {
+		if _, ok := paths[key]; ok {
+			roots = append(roots, filepath.Join(b.Binding.Path, key))
+		}
+	}
+
+	digest, err := NewInputDigest(roots...)
+	if err != nil {
+		return libcnb.Layer{}, fmt.Errorf("unable to compute digest of %s binding %s\n%w", BindingType, b.Binding.Name, err)
+	}
+
+	contributor := libpak.NewLayerContributor("Native Image Binding", map[string]interface{}{
+		"binding": b.Binding.Name,
+		"files":   digest.Root,
+	}, libcnb.LayerTypes{
+		Cache: true,
+	})
+	contributor.Logger = b.Logger
+
+	layer, err = contributor.Contribute(layer, func() (libcnb.Layer, error) {
+		b.Logger.Bodyf("Copying %s binding %s to %s", BindingType, b.Binding.Name, layer.Path)
+
+		for _, key := range BindingKeys {
+			src, ok := paths[key]
+			if !ok {
+				continue
+			}
+			dst := filepath.Join(layer.Path, key)
+
+			if key == BindingMetadata || key == BindingPGO {
+				if err := sherpa.CopyDir(src, dst); err != nil {
+					return libcnb.Layer{}, fmt.Errorf("unable to copy %s to %s\n%w", src, dst, err)
+				}
+			} else if err := copyFile(src, dst); err != nil {
+				return libcnb.Layer{}, fmt.Errorf("unable to copy %s to %s\n%w", src, dst, err)
+			}
+		}
+
+		return layer, nil
+	})
+	if err != nil {
+		return libcnb.Layer{}, fmt.Errorf("unable to contribute native-image-binding layer\n%w", err)
+	}
+
+	return layer, nil
+}
+
+func (NativeImageBinding) Name() string {
+	return "native-image-binding"
+}
+
+// paths returns the paths of the keys of the binding that exist. Files and directories are resolved through the
+// symbolic links that Kubernetes uses to project bindings.
+func (b NativeImageBinding) paths() map[string]string {
+	paths := map[string]string{}
+
+	for _, key := range BindingKeys {
+		p, err := filepath.EvalSymlinks(filepath.Join(b.Binding.Path, key))
+		if err != nil {
+			continue
+		}
+
+		info, err := os.Stat(p)
+		if err != nil {
+			continue
+		}
+
+		// metadata and pgo are directories, the other keys files
+		if info.IsDir() == (key == BindingMetadata || key == BindingPGO) {
+			paths[key] = p
+		}
+	}
+
+	return paths
+}
+
+// BindingArguments adds the inputs of a native-image binding copied into the layer at LayerPath: its arguments, its
+// argument file, its reachability metadata as a configuration directory and its profiles with --pgo. They are
+// configured after those of upstream buildpacks and before the user arguments, which therefore take precedence.
+type BindingArguments struct {
+	LayerPath string
+}
+
+// Configure appends the arguments of the binding to inputArgs
+func (b BindingArguments) Configure(inputArgs []string) ([]string, string, error) {
+	if b.LayerPath == "" {
+		return inputArgs, "", nil
+	}
+
+	file := filepath.Join(b.LayerPath, BindingArgs)
+	if raw, err := os.ReadFile(file); err != nil && !os.IsNotExist(err) {
+		return []string{}, "", fmt.Errorf("unable to read %s\n%w", file, err)
+	} else if err == nil {
+		args, err := shellwords.Parse(strings.TrimSpace(string(raw)))
+		if err != nil {
+			return []string{}, "", fmt.Errorf("unable to parse arguments from %s\n%w", file, err)
+		}
+		inputArgs = append(inputArgs, args...)
+	}
+
+	file = filepath.Join(b.LayerPath, BindingArgfile)
+	if ok, err := sherpa.FileExists(file); err != nil {
+		return []string{}, "", fmt.Errorf("unable to check for %s\n%w", file, err)
+	} else if ok {
+		inputArgs = append(inputArgs, fmt.Sprintf("@%s", file))
+	}
+
+	dir := filepath.Join(b.LayerPath, BindingMetadata)
+	if ok, err := sherpa.DirExists(dir); err != nil {
+		return []string{}, "", fmt.Errorf("unable to check for %s\n%w", dir, err)
+	} else if ok {
+		inputArgs = append(inputArgs, fmt.Sprintf("-H:ConfigurationFileDirectories=%s", dir))
+	}
+
+	profiles, err := filepath.Glob(filepath.Join(b.LayerPath, BindingPGO, "*.iprof"))
+	if err != nil {
+		return []string{}, "", fmt.Errorf("unable to find profiles in %s\n%w", filepath.Join(b.LayerPath, BindingPGO), err)
+	}
+	if len(profiles) > 0 {
+		sort.Strings(profiles)
+		inputArgs = append(inputArgs, fmt.Sprintf("--pgo=%s", strings.Join(profiles, ",")))
+	}
+
+	return inputArgs, "", nil
+}
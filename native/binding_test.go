@@ -0,0 +1,146 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package native_test
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/buildpacks/libcnb"
+	. "github.com/onsi/gomega"
+	"github.com/paketo-buildpacks/libpak/bard"
+	"github.com/sclevine/spec"
+
+	"github.com/paketo-buildpacks/native-image/v5/native"
+)
+
+func testBinding(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		binding libcnb.Binding
+		ctx     libcnb.BuildContext
+	)
+
+	it.Before(func() {
+		ctx.Layers.Path = t.TempDir()
+
+		binding = libcnb.Binding{Name: "org-defaults", Type: "native-image", Path: t.TempDir()}
+		Expect(os.WriteFile(filepath.Join(binding.Path, "type"), []byte("native-image"), 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(binding.Path, "args"), []byte("--no-fallback -Dgreeting='hello world'\n"), 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(binding.Path, "argfile"), []byte("-H:+ReportExceptionStackTraces\n"), 0644)).To(Succeed())
+		Expect(os.MkdirAll(filepath.Join(binding.Path, "metadata", "org.example"), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(binding.Path, "metadata", "org.example", "reachability-metadata.json"), []byte("{}"), 0644)).To(Succeed())
+		Expect(os.MkdirAll(filepath.Join(binding.Path, "pgo"), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(binding.Path, "pgo", "b.iprof"), []byte{}, 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(binding.Path, "pgo", "a.iprof"), []byte{}, 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(binding.Path, "pgo", "README"), []byte{}, 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(binding.Path, "policy"), []byte("[[deny]]\npattern = \"-march=native\"\n"), 0644)).To(Succeed())
+	})
+
+	it("copies the inputs of the binding into a cached layer", func() {
+		b := native.NewNativeImageBinding(binding)
+		b.Logger = bard.NewLogger(io.Discard)
+
+		layer, err := ctx.Layers.Layer(b.Name())
+		Expect(err).NotTo(HaveOccurred())
+
+		layer, err = b.Contribute(layer)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(layer.Cache).To(BeTrue())
+		Expect(layer.Launch).To(BeFalse())
+		Expect(filepath.Join(layer.Path, "args")).To(BeARegularFile())
+		Expect(filepath.Join(layer.Path, "argfile")).To(BeARegularFile())
+		Expect(filepath.Join(layer.Path, "metadata", "org.example", "reachability-metadata.json")).To(BeARegularFile())
+		Expect(filepath.Join(layer.Path, "pgo", "a.iprof")).To(BeARegularFile())
+		Expect(filepath.Join(layer.Path, "policy")).To(BeARegularFile())
+		Expect(filepath.Join(layer.Path, "type")).NotTo(BeAnExistingFile())
+	})
+
+	it("changes the layer when the bound files change", func() {
+		b := native.NewNativeImageBinding(binding)
+		b.Logger = bard.NewLogger(io.Discard)
+
+		layer, err := ctx.Layers.Layer(b.Name())
+		Expect(err).NotTo(HaveOccurred())
+		layer, err = b.Contribute(layer)
+		Expect(err).NotTo(HaveOccurred())
+		before := layer.Metadata["files"]
+
+		Expect(os.WriteFile(filepath.Join(binding.Path, "args"), []byte("--verbose"), 0644)).To(Succeed())
+
+		layer, err = ctx.Layers.Layer(b.Name())
+		Expect(err).NotTo(HaveOccurred())
+		layer, err = b.Contribute(layer)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(layer.Metadata["files"]).NotTo(Equal(before))
+		Expect(os.ReadFile(filepath.Join(layer.Path, "args"))).To(Equal([]byte("--verbose")))
+	})
+
+	it("changes the layer when the policy changes", func() {
+		b := native.NewNativeImageBinding(binding)
+		b.Logger = bard.NewLogger(io.Discard)
+
+		layer, err := ctx.Layers.Layer(b.Name())
+		Expect(err).NotTo(HaveOccurred())
+		layer, err = b.Contribute(layer)
+		Expect(err).NotTo(HaveOccurred())
+		before := layer.Metadata["files"]
+
+		Expect(os.WriteFile(filepath.Join(binding.Path, "policy"), []byte("[[require]]\npattern = \"--no-fallback\"\n"), 0644)).To(Succeed())
+
+		layer, err = ctx.Layers.Layer(b.Name())
+		Expect(err).NotTo(HaveOccurred())
+		layer, err = b.Contribute(layer)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(layer.Metadata["files"]).NotTo(Equal(before))
+		Expect(os.ReadFile(filepath.Join(layer.Path, "policy"))).To(ContainSubstring("--no-fallback"))
+	})
+
+	it("configures the arguments of the binding", func() {
+		layerPath := t.TempDir()
+		Expect(os.WriteFile(filepath.Join(layerPath, "args"), []byte("--no-fallback -Dgreeting='hello world'\n"), 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(layerPath, "argfile"), []byte{}, 0644)).To(Succeed())
+		Expect(os.MkdirAll(filepath.Join(layerPath, "metadata"), 0755)).To(Succeed())
+		Expect(os.MkdirAll(filepath.Join(layerPath, "pgo"), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(layerPath, "pgo", "b.iprof"), []byte{}, 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(layerPath, "pgo", "a.iprof"), []byte{}, 0644)).To(Succeed())
+
+		args, _, err := native.BindingArguments{LayerPath: layerPath}.Configure([]string{"-g"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(args).To(Equal([]string{
+			"-g",
+			"--no-fallback",
+			"-Dgreeting=hello world",
+			fmt.Sprintf("@%s", filepath.Join(layerPath, "argfile")),
+			fmt.Sprintf("-H:ConfigurationFileDirectories=%s", filepath.Join(layerPath, "metadata")),
+			fmt.Sprintf("--pgo=%s,%s", filepath.Join(layerPath, "pgo", "a.iprof"), filepath.Join(layerPath, "pgo", "b.iprof")),
+		}))
+	})
+
+	it("configures nothing without a binding", func() {
+		args, _, err := native.BindingArguments{}.Configure([]string{"-g"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(args).To(Equal([]string{"-g"}))
+	})
+}
@@ -0,0 +1,145 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package native
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/magiconair/properties"
+	"github.com/paketo-buildpacks/libjvm"
+	"github.com/paketo-buildpacks/libpak/crush"
+)
+
+// DefaultSpringBootClassPathIndex is the class path index of Spring Boot JARs that do not name one in their manifest
+const DefaultSpringBootClassPathIndex = "BOOT-INF/classpath.idx"
+
+// IsSpringBoot returns whether manifest describes a Spring Boot JAR, whose classes and libraries are nested in it
+func IsSpringBoot(manifest *properties.Properties) bool {
+	_, classes := manifest.Get("Spring-Boot-Classes")
+	_, lib := manifest.Get("Spring-Boot-Lib")
+	return classes && lib
+}
+
+// SpringBootClassPath returns the class path of the Spring Boot application exploded in appPath: the application
+// classes followed by the libraries in the order of the class path index. Libraries missing from the index are
+// appended in name order.
+func SpringBootClassPath(appPath string, manifest *properties.Properties) (string, error) {
+	classes := filepath.Join(appPath, manifest.GetString("Spring-Boot-Classes", ""))
+	lib := filepath.Join(appPath, manifest.GetString("Spring-Boot-Lib", ""))
+
+	cp := []string{classes}
+	seen := map[string]bool{}
+
+	index := filepath.Join(appPath, manifest.GetString("Spring-Boot-Classpath-Index", DefaultSpringBootClassPathIndex))
+	entries, err := readClassPathIndex(index)
+	if err != nil {
+		return "", fmt.Errorf("unable to read class path index %s\n%w", index, err)
+	}
+
+	for _, entry := range entries {
+		// Spring Boot 2.3 lists the names of the libraries, later versions their path in the JAR
+		jar := filepath.Join(appPath, entry)
+		if !strings.Contains(entry, "/") {
+			jar = filepath.Join(lib, entry)
+		}
+
+		if !seen[jar] {
+			seen[jar] = true
+			cp = append(cp, jar)
+		}
+	}
+
+	jars, err := filepath.Glob(filepath.Join(lib, "*.jar"))
+	if err != nil {
+		return "", fmt.Errorf("unable to list libraries in %s\n%w", lib, err)
+	}
+	sort.Strings(jars)
+
+	for _, jar := range jars {
+		if !seen[jar] {
+			seen[jar] = true
+			cp = append(cp, jar)
+		}
+	}
+
+	return strings.Join(cp, string(filepath.ListSeparator)), nil
+}
+
+// readClassPathIndex returns the entries of a Spring Boot class path index, which is a YAML list of quoted paths. A
+// missing index has no entries.
+func readClassPathIndex(path string) ([]string, error) {
+	in, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("unable to open %s\n%w", path, err)
+	}
+	defer in.Close()
+
+	var entries []string
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "-") {
+			continue
+		}
+
+		entry := strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "-")), `"'`)
+		if entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read %s\n%w", path, err)
+	}
+
+	return entries, nil
+}
+
+// SpringBootJAR returns the manifest of jar and whether it is a Spring Boot JAR
+func SpringBootJAR(jar string) (*properties.Properties, bool, error) {
+	manifest, err := libjvm.NewManifestFromJAR(jar)
+	if err != nil {
+		return nil, false, fmt.Errorf("unable to read manifest of %s\n%w", jar, err)
+	}
+
+	return manifest, IsSpringBoot(manifest), nil
+}
+
+// ExtractJAR extracts jar into destination, replacing any previous contents
+func ExtractJAR(jar string, destination string) error {
+	if err := os.RemoveAll(destination); err != nil {
+		return fmt.Errorf("unable to remove %s\n%w", destination, err)
+	}
+
+	in, err := os.Open(jar)
+	if err != nil {
+		return fmt.Errorf("unable to open %s\n%w", jar, err)
+	}
+	defer in.Close()
+
+	if err := crush.ExtractZip(in, destination, 0); err != nil {
+		return fmt.Errorf("unable to extract %s\n%w", jar, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,156 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package native_test
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/magiconair/properties"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+
+	"github.com/paketo-buildpacks/native-image/v5/native"
+)
+
+func testBoot(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		appPath  string
+		manifest *properties.Properties
+	)
+
+	it.Before(func() {
+		appPath = t.TempDir()
+
+		manifest = properties.NewProperties()
+		_, _, err := manifest.Set("Spring-Boot-Classes", "BOOT-INF/classes/")
+		Expect(err).NotTo(HaveOccurred())
+		_, _, err = manifest.Set("Spring-Boot-Lib", "BOOT-INF/lib/")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(os.MkdirAll(filepath.Join(appPath, "BOOT-INF", "lib"), 0755)).To(Succeed())
+		for _, jar := range []string{"a.jar", "b.jar", "c.jar"} {
+			Expect(os.WriteFile(filepath.Join(appPath, "BOOT-INF", "lib", jar), []byte{}, 0644)).To(Succeed())
+		}
+	})
+
+	it("recognizes Spring Boot manifests", func() {
+		Expect(native.IsSpringBoot(manifest)).To(BeTrue())
+		Expect(native.IsSpringBoot(properties.NewProperties())).To(BeFalse())
+	})
+
+	context("SpringBootClassPath", func() {
+		it("orders the libraries as the class path index", func() {
+			Expect(os.WriteFile(filepath.Join(appPath, "BOOT-INF", "classpath.idx"),
+				[]byte("- \"BOOT-INF/lib/c.jar\"\n- \"BOOT-INF/lib/a.jar\"\n"), 0644)).To(Succeed())
+
+			cp, err := native.SpringBootClassPath(appPath, manifest)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(filepath.SplitList(cp)).To(Equal([]string{
+				filepath.Join(appPath, "BOOT-INF", "classes"),
+				filepath.Join(appPath, "BOOT-INF", "lib", "c.jar"),
+				filepath.Join(appPath, "BOOT-INF", "lib", "a.jar"),
+				filepath.Join(appPath, "BOOT-INF", "lib", "b.jar"),
+			}))
+		})
+
+		it("reads the index named in the manifest, which may only list the names of the libraries", func() {
+			_, _, err := manifest.Set("Spring-Boot-Classpath-Index", "BOOT-INF/index.idx")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.WriteFile(filepath.Join(appPath, "BOOT-INF", "index.idx"), []byte("- \"b.jar\"\n"), 0644)).To(Succeed())
+
+			cp, err := native.SpringBootClassPath(appPath, manifest)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(filepath.SplitList(cp)).To(Equal([]string{
+				filepath.Join(appPath, "BOOT-INF", "classes"),
+				filepath.Join(appPath, "BOOT-INF", "lib", "b.jar"),
+				filepath.Join(appPath, "BOOT-INF", "lib", "a.jar"),
+				filepath.Join(appPath, "BOOT-INF", "lib", "c.jar"),
+			}))
+		})
+
+		it("orders the libraries by name without an index", func() {
+			cp, err := native.SpringBootClassPath(appPath, manifest)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(filepath.SplitList(cp)).To(Equal([]string{
+				filepath.Join(appPath, "BOOT-INF", "classes"),
+				filepath.Join(appPath, "BOOT-INF", "lib", "a.jar"),
+				filepath.Join(appPath, "BOOT-INF", "lib", "b.jar"),
+				filepath.Join(appPath, "BOOT-INF", "lib", "c.jar"),
+			}))
+		})
+	})
+
+	context("Spring Boot JAR", func() {
+		var jar string
+
+		it.Before(func() {
+			jar = filepath.Join(t.TempDir(), "app.jar")
+			writeJAR(t, jar, map[string]string{
+				"META-INF/MANIFEST.MF":        "Manifest-Version: 1.0\nStart-Class: org.example.Main\nSpring-Boot-Classes: BOOT-INF/classes/\nSpring-Boot-Lib: BOOT-INF/lib/\n",
+				"BOOT-INF/classes/Main.class": "",
+				"BOOT-INF/lib/dependency.jar": "",
+				"BOOT-INF/classpath.idx":      "- \"BOOT-INF/lib/dependency.jar\"\n",
+			})
+		})
+
+		it("reads the manifest", func() {
+			m, boot, err := native.SpringBootJAR(jar)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(boot).To(BeTrue())
+			Expect(m.GetString("Start-Class", "")).To(Equal("org.example.Main"))
+		})
+
+		it("extracts the JAR, replacing previous contents", func() {
+			destination := t.TempDir()
+			Expect(os.WriteFile(filepath.Join(destination, "stale"), []byte{}, 0644)).To(Succeed())
+
+			Expect(native.ExtractJAR(jar, destination)).To(Succeed())
+			Expect(filepath.Join(destination, "BOOT-INF", "lib", "dependency.jar")).To(BeARegularFile())
+			Expect(filepath.Join(destination, "stale")).NotTo(BeAnExistingFile())
+		})
+	})
+}
+
+func writeJAR(t *testing.T, path string, entries map[string]string) {
+	t.Helper()
+
+	out, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+
+	z := zip.NewWriter(out)
+	for name, content := range entries {
+		w, err := z.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := z.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
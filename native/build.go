@@ -21,9 +21,17 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
 
 	"github.com/paketo-buildpacks/libpak/sherpa"
 
+	"github.com/paketo-buildpacks/native-image/v5/native/march"
+
 	"github.com/paketo-buildpacks/libpak/effect"
 	"github.com/paketo-buildpacks/libpak/sbom"
 
@@ -33,14 +41,37 @@ import (
 	"github.com/paketo-buildpacks/libjvm"
 	"github.com/paketo-buildpacks/libpak"
 	"github.com/paketo-buildpacks/libpak/bard"
+	"github.com/paketo-buildpacks/libpak/bindings"
 )
 
 const (
-	ConfigNativeImageArgs           = "BP_NATIVE_IMAGE_BUILD_ARGUMENTS"
-	DeprecatedConfigNativeImageArgs = "BP_BOOT_NATIVE_IMAGE_BUILD_ARGUMENTS"
-	CompressorUpx                   = "upx"
-	CompressorGzexe                 = "gzexe"
-	CompressorNone                  = "none"
+	ConfigNativeImageArgs             = "BP_NATIVE_IMAGE_BUILD_ARGUMENTS"
+	DeprecatedConfigNativeImageArgs   = "BP_BOOT_NATIVE_IMAGE_BUILD_ARGUMENTS"
+	ConfigNativeImageDebugInfo        = "BP_NATIVE_IMAGE_DEBUG_INFO"
+	ConfigNativeImageDebugInfoLaunch  = "BP_NATIVE_IMAGE_DEBUG_INFO_LAUNCH"
+	ConfigNativeImageCacheDir         = "BP_NATIVE_IMAGE_CACHE_DIR"
+	ConfigNativeImageCacheSize        = "BP_NATIVE_IMAGE_CACHE_SIZE"
+	ConfigNativeImageLayered          = "BP_NATIVE_IMAGE_LAYERED"
+	ConfigNativeImageMaxSize          = "BP_NATIVE_IMAGE_MAX_SIZE"
+	ConfigNativeImageWarnSize         = "BP_NATIVE_IMAGE_WARN_SIZE"
+	ConfigNativeImageSmokeTest        = "BP_NATIVE_IMAGE_SMOKE_TEST"
+	ConfigNativeImageVerifyLibraries  = "BP_NATIVE_IMAGE_VERIFY_LIBRARIES"
+	ConfigNativeImageAllowedLibraries = "BP_NATIVE_IMAGE_ALLOWED_LIBRARIES"
+	ConfigNativeImageGLIBCVersion     = "BP_NATIVE_IMAGE_GLIBC_VERSION"
+	ConfigNativeImageMarch            = "BP_NATIVE_IMAGE_MARCH"
+	ConfigNativeImageGC               = "BP_NATIVE_IMAGE_GC"
+	ConfigNativeImageGCArgs           = "BPL_NATIVE_IMAGE_GC_ARGS"
+	ConfigNativeImageLauncher         = "BP_NATIVE_IMAGE_LAUNCHER"
+	ConfigNativeImageMainModule       = "BP_NATIVE_IMAGE_MAIN_MODULE"
+	ConfigNativeImageJARExclusions    = "BP_NATIVE_IMAGE_BUILT_ARTIFACT_EXCLUSIONS"
+	ConfigNativeImageArgsFile         = "BP_NATIVE_IMAGE_BUILD_ARGUMENTS_FILE"
+	ConfigNativeImageBuiltArtifact    = "BP_NATIVE_IMAGE_BUILT_ARTIFACT"
+	ConfigNativeImageProfile          = "BP_NATIVE_IMAGE_PROFILE"
+	ConfigNativeImagePolicy           = "BP_NATIVE_IMAGE_POLICY"
+	ConfigNativeImageSmokeTestExit    = "BP_NATIVE_IMAGE_SMOKE_TEST_EXIT_CODE"
+	ConfigNativeImageSmokeTestOutput  = "BP_NATIVE_IMAGE_SMOKE_TEST_OUTPUT"
+	ConfigNativeImageSmokeTestTime    = "BP_NATIVE_IMAGE_SMOKE_TEST_TIMEOUT"
+	DefaultNativeImageCacheSize       = "5G"
 )
 
 type Build struct {
@@ -49,14 +80,14 @@ type Build struct {
 }
 
 func (b Build) Build(context libcnb.BuildContext) (libcnb.BuildResult, error) {
-	cr, err := libpak.NewConfigurationResolver(context.Buildpack, nil) // so it doesn't print the configuration
+	pr, err := libpak.NewConfigurationResolver(context.Buildpack, nil) // so it doesn't print the configuration
 	if err != nil {
 		return libcnb.BuildResult{}, fmt.Errorf("unable to create configuration resolver\n%w", err)
 	}
 
 	// Check if BP_NATIVE_IMAGE or BP_BOOT_NATIVE_IMAGE are specifically set to false then we skip the build
-	_, runNativeImageSet := cr.Resolve(ConfigNativeImage)
-	_, runDeprecatedNativeImageSet := cr.Resolve(DeprecatedConfigNativeImage)
+	_, runNativeImageSet := pr.Resolve(ConfigNativeImage)
+	_, runDeprecatedNativeImageSet := pr.Resolve(DeprecatedConfigNativeImage)
 	if runNativeImageSet || runDeprecatedNativeImageSet {
 		runNativeImageBuild := sherpa.ResolveBool(ConfigNativeImage) || sherpa.ResolveBool(DeprecatedConfigNativeImage)
 		if !runNativeImageBuild {
@@ -72,11 +103,19 @@ func (b Build) Build(context libcnb.BuildContext) (libcnb.BuildResult, error) {
 		return libcnb.BuildResult{}, fmt.Errorf("unable to read manifest in %s\n%w", context.Application.Path, err)
 	}
 
-	cr, err = libpak.NewConfigurationResolver(context.Buildpack, &b.Logger)
+	pr, err = libpak.NewConfigurationResolver(context.Buildpack, &b.Logger)
 	if err != nil {
 		return libcnb.BuildResult{}, fmt.Errorf("unable to create configuration resolver\n%w", err)
 	}
 
+	profile, _ := pr.Resolve(ConfigNativeImageProfile)
+	descriptor, descriptorFile, err := ReadDescriptor(context.Application.Path, profile)
+	if err != nil {
+		return libcnb.BuildResult{}, fmt.Errorf("unable to read configuration of application\n%w", err)
+	}
+	cr := ConfigurationResolver{ConfigurationResolver: pr, Descriptor: descriptor.Configuration(), DescriptorFile: descriptorFile}
+	b.logDescriptor(descriptorFile, profile, cr)
+
 	if _, ok := cr.Resolve(DeprecatedConfigNativeImage); ok {
 		warn(b.Logger, fmt.Sprintf("$%s has been deprecated. Please use $%s instead.",
 			DeprecatedConfigNativeImage,
@@ -94,8 +133,13 @@ func (b Build) Build(context libcnb.BuildContext) (libcnb.BuildResult, error) {
 		}
 	}
 
-	jarFilePattern, _ := cr.Resolve("BP_NATIVE_IMAGE_BUILT_ARTIFACT")
-	argsFile, _ := cr.Resolve("BP_NATIVE_IMAGE_BUILD_ARGUMENTS_FILE")
+	jarFilePattern, _ := cr.Resolve(ConfigNativeImageBuiltArtifact)
+	mainModule, _ := cr.Resolve(ConfigNativeImageMainModule)
+	rawExclusions, _ := cr.Resolve(ConfigNativeImageJARExclusions)
+	jarExclusions := strings.FieldsFunc(rawExclusions, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+	argsFile, _ := cr.Resolve(ConfigNativeImageArgsFile)
 
 	if argsFile != "" {
 		argsFile, err = filepath.Abs(argsFile)
@@ -110,14 +154,67 @@ func (b Build) Build(context libcnb.BuildContext) (libcnb.BuildResult, error) {
 		}
 	}
 
+	plan, err := NewPlanMetadata(context.Plan, context.Application.Path)
+	if err != nil {
+		return libcnb.BuildResult{}, fmt.Errorf("unable to read %s plan entries\n%w", PlanEntryNativeImage, err)
+	}
+	b.logPlanMetadata(plan)
+
+	// the executable named by the application takes precedence over that named by upstream buildpacks
+	if descriptor.Executable != "" {
+		plan.ExecutableName = descriptor.Executable
+	}
+
 	compressor, ok := cr.Resolve(BinaryCompressionMethod)
 	if !ok {
 		compressor = CompressorNone
-	} else if ok {
-		if compressor != CompressorUpx && compressor != CompressorGzexe && compressor != CompressorNone {
-			warn(b.Logger, fmt.Sprintf("Requested compression method [%s] is unknown, no compression will be performed", compressor))
-			compressor = CompressorNone
-		}
+	} else if _, ok := NewCompressor(compressor, CompressorOptions{}); !ok {
+		warn(b.Logger, fmt.Sprintf("Requested compression method [%s] is unknown, no compression will be performed", compressor))
+		compressor = CompressorNone
+	}
+
+	target := NewTarget()
+	if target.IsSet() && target.OS != "linux" {
+		return libcnb.BuildResult{}, fmt.Errorf("native images can only be built for linux, not %s", target.OS)
+	}
+
+	runImage := NewRunImageForTarget(target, context.StackID)
+	b.Logger.Bodyf("Building for the %s", runImage)
+	if runImage.LinksLibCOnly() {
+		b.Logger.Bodyf("Linking statically except for glibc, as the %s run image only provides glibc", runImage.Flavor)
+	}
+
+	linking, err := linkingMode(target, context.StackID, plan, args, argsFile)
+	if err != nil {
+		return libcnb.BuildResult{}, fmt.Errorf("unable to determine linking mode\n%w", err)
+	}
+
+	warnings, err := CheckCompressorCompatibility(compressor, runImage, linking)
+	if err != nil {
+		return libcnb.BuildResult{}, err
+	}
+	for _, w := range warnings {
+		warn(b.Logger, w)
+	}
+
+	compressionLevel, _ := cr.Resolve(BinaryCompressionLevel)
+	if !IsValidCompressionLevel(compressionLevel) {
+		warn(b.Logger, fmt.Sprintf("Requested compression level [%s] is unknown, the default level will be used", compressionLevel))
+		compressionLevel = ""
+	}
+
+	retain, ok := cr.Resolve(ConfigRetainJVMArtifacts)
+	if !ok {
+		retain = RetainJVMArtifactsNone
+	} else if retain != RetainJVMArtifactsNone && retain != RetainJVMArtifactsBuild && retain != RetainJVMArtifactsLaunch {
+		warn(b.Logger, fmt.Sprintf("Requested JVM artifact retention [%s] is unknown, JVM artifacts will not be retained", retain))
+		retain = RetainJVMArtifactsNone
+	}
+
+	if retain != RetainJVMArtifactsNone {
+		j := NewJVMArtifacts(context.Application.Path, retain == RetainJVMArtifactsLaunch)
+		j.Logger = b.Logger
+		result.Layers = append(result.Layers, j)
 	}
 
 	n, err := NewNativeImage(context.Application.Path, args, argsFile, compressor, jarFilePattern, manifest, context.StackID)
@@ -125,19 +222,195 @@ func (b Build) Build(context libcnb.BuildContext) (libcnb.BuildResult, error) {
 		return libcnb.BuildResult{}, fmt.Errorf("unable to create native image layer\n%w", err)
 	}
 	n.Logger = b.Logger
+	n.Target = target
+	n.PlanMetadata = plan
+	n.MainModule = mainModule
+	n.JarExclusions = jarExclusions
+	n.Keep = descriptor.Keep
+	n.Profile = profile
+	n.ArgumentsSource = cr.Source(ConfigNativeImageArgs)
+	if n.ArgumentsSource == "" {
+		n.ArgumentsSource = cr.Source(DeprecatedConfigNativeImageArgs)
+	}
+	n.ArgumentsFileSource = cr.Source(ConfigNativeImageArgsFile)
+
+	if file, ok := cr.Resolve(ConfigNativeImagePolicy); ok && file != "" {
+		p, err := ReadPolicy(resolvePath(context.Application.Path, file))
+		if err != nil {
+			return libcnb.BuildResult{}, fmt.Errorf("unable to read policy\n%w", err)
+		}
+		n.Policies = append(n.Policies, p)
+	}
+	if jarFilePattern != "" {
+		// a layer directory without metadata is neither cached nor exported, so the extracted JAR is discarded
+		n.ExtractionPath = filepath.Join(context.Layers.Path, "native-image-extracted-jar")
+	}
+	n.CompressionLevel = compressionLevel
+	n.CompressionLZMA = cr.ResolveBool(BinaryCompressionLZMA)
+
+	if binding, ok, err := bindings.ResolveOne(context.Platform.Bindings, bindings.OfType(BindingType)); err != nil {
+		return libcnb.BuildResult{}, fmt.Errorf("unable to resolve %s binding\n%w", BindingType, err)
+	} else if ok {
+		nb := NewNativeImageBinding(binding)
+		nb.Logger = b.Logger
+		result.Layers = append(result.Layers, nb)
+		n.BindingPath = filepath.Join(context.Layers.Path, nb.Name())
+	}
+
+	if cacheDir, ok := cr.Resolve(ConfigNativeImageCacheDir); ok && cacheDir != "" {
+		n.BuildCache, err = b.buildCache(cr, cacheDir)
+		if err != nil {
+			return libcnb.BuildResult{}, fmt.Errorf("unable to configure native image cache\n%w", err)
+		}
+	}
+
+	if n.MaxSize, err = resolveSize(cr, ConfigNativeImageMaxSize); err != nil {
+		return libcnb.BuildResult{}, err
+	}
+	if n.WarnSize, err = resolveSize(cr, ConfigNativeImageWarnSize); err != nil {
+		return libcnb.BuildResult{}, err
+	}
+
+	verify, ok := cr.Resolve(ConfigNativeImageVerifyLibraries)
+	if !ok {
+		verify = VerifyLibrariesWarn
+	} else if verify != VerifyLibrariesNone && verify != VerifyLibrariesWarn && verify != VerifyLibrariesFail {
+		warn(b.Logger, fmt.Sprintf("Requested library verification [%s] is unknown, missing libraries will only be warned about", verify))
+		verify = VerifyLibrariesWarn
+	}
+	verifiedImage := runImage
+	if v, ok := cr.Resolve(ConfigNativeImageGLIBCVersion); ok && v != "" {
+		verifiedImage.GLIBCVersion = v
+	}
+	allowed, _ := cr.Resolve(ConfigNativeImageAllowedLibraries)
+	n.LibraryVerifier = NewLibraryVerifier(verify, verifiedImage, strings.FieldsFunc(allowed, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	}))
+
+	if command, ok := cr.Resolve(ConfigNativeImageSmokeTest); ok && command != "" {
+		if n.SmokeTest, err = smokeTest(cr, command); err != nil {
+			return libcnb.BuildResult{}, fmt.Errorf("unable to configure smoke test\n%w", err)
+		}
+	}
+
+	if raw, ok := cr.Resolve(ConfigNativeImageMarch); ok && raw != "" {
+		if n.Variants, err = b.variants(raw, runImage.Arch); err != nil {
+			return libcnb.BuildResult{}, fmt.Errorf("unable to configure microarchitecture\n%w", err)
+		}
+	}
+	multiVariant := len(n.Variants) > 1
+
+	if gc, ok := cr.Resolve(ConfigNativeImageGC); ok && gc != "" {
+		if g, ok := ParseGC(gc); ok {
+			n.GC = g
+		} else {
+			warn(b.Logger, fmt.Sprintf("Requested garbage collector [%s] is unknown, the default garbage collector will be used", gc))
+		}
+	}
+
+	debugInfo, ok := cr.Resolve(ConfigNativeImageDebugInfo)
+	if !ok {
+		debugInfo = DebugInfoNone
+	} else if debugInfo != DebugInfoNone && debugInfo != DebugInfoSplit && debugInfo != DebugInfoEmbedded {
+		warn(b.Logger, fmt.Sprintf("Requested debug info [%s] is unknown, no debug info will be generated", debugInfo))
+		debugInfo = DebugInfoNone
+	}
+	if debugInfo == DebugInfoSplit && multiVariant {
+		warn(b.Logger, "Split debug info is not supported for multiple variants, debug info will be embedded instead")
+		debugInfo = DebugInfoEmbedded
+	}
+	n.DebugInfo = debugInfo
+
+	if cr.ResolveBool(ConfigNativeImageLayered) && multiVariant {
+		warn(b.Logger, "Layered native images are not supported for multiple variants, a single native image will be built for each variant instead")
+	} else if cr.ResolveBool(ConfigNativeImageLayered) {
+		if d, ok, err := b.dependencies(context, target, args, linking, manifest, mainModule); err != nil {
+			return libcnb.BuildResult{}, fmt.Errorf("unable to configure layered native image\n%w", err)
+		} else if ok {
+			if len(n.Variants) == 1 {
+				d.March = n.Variants[0]
+			}
+			d.GC = n.GC
+			d.DebugInfo = n.DebugInfo
+			d.ArgumentsFile = argsFile
+			d.PlanMetadata = plan
+			d.BindingPath = n.BindingPath
+			result.Layers = append(result.Layers, d)
+			n.DependenciesLayerPath = filepath.Join(context.Layers.Path, d.Name())
+		}
+	}
+
 	result.Layers = append(result.Layers, n)
 
-	startClass, err := findStartOrMainClass(manifest, context.Application.Path, jarFilePattern)
+	if debugInfo == DebugInfoSplit {
+		d := NewDebugInfo(filepath.Join(context.Layers.Path, n.Name()), cr.ResolveBool(ConfigNativeImageDebugInfoLaunch))
+		d.Logger = b.Logger
+		result.Layers = append(result.Layers, d)
+	}
+
+	reportPath := filepath.Join(context.Layers.Path, n.Name())
+	if multiVariant {
+		reportPath = filepath.Join(reportPath, n.Variants[0])
+	}
+	r := NewNativeImageReport(reportPath)
+	r.Logger = b.Logger
+	result.Layers = append(result.Layers, r)
+
+	startClass, err := findStartOrMainClass(manifest, context.Application.Path, jarFilePattern, jarExclusions, mainModule, plan)
 	if err != nil {
 		return libcnb.BuildResult{}, fmt.Errorf("unable to find required manifest property\n%w", err)
 	}
 
 	command := fmt.Sprintf("%c%c%s", '.', os.PathSeparator, startClass)
-	result.Processes = append(result.Processes,
-		libcnb.Process{Type: "native-image", Command: command, Direct: true},
-		libcnb.Process{Type: "task", Command: command, Direct: true},
-		libcnb.Process{Type: "web", Command: command, Direct: true, Default: true},
-	)
+	processes := []libcnb.Process{
+		{Type: "native-image", Command: command, Direct: true},
+		{Type: "task", Command: command, Direct: true},
+		{Type: "web", Command: command, Direct: true, Default: true},
+	}
+	processes = descriptorProcesses(processes, descriptor.Processes)
+
+	// the launcher selects the variant and passes $BPL_NATIVE_IMAGE_GC_ARGS, which a direct process cannot expand
+	processCommand := filepath.Join(context.Application.Path, startClass)
+	if multiVariant || n.GC != "" || cr.ResolveBool(ConfigNativeImageLauncher) {
+		l := NewLauncher(context.Buildpack.Path)
+		l.Logger = b.Logger
+		result.Layers = append(result.Layers, l)
+
+		var variants []string
+		if multiVariant {
+			variants = n.Variants
+		}
+		layerPath := filepath.Join(context.Layers.Path, l.Name())
+		for i, p := range processes {
+			processes[i] = l.Process(layerPath, context.Application.Path, startClass, variants, p)
+		}
+		processCommand = l.Path(layerPath)
+	}
+	result.Processes = append(result.Processes, processes...)
+
+	executables := []string{filepath.Join(context.Application.Path, startClass)}
+	if multiVariant {
+		executables = nil
+		for _, v := range n.Variants {
+			executables = append(executables, filepath.Join(context.Application.Path, v, startClass))
+		}
+	}
+	o := NewNativeImageOutput(filepath.Join(context.Layers.Path, n.Name()), executables, processCommand, compressor)
+	o.Logger = b.Logger
+	result.Layers = append(result.Layers, o)
+
+	if retain == RetainJVMArtifactsLaunch {
+		pr := libpak.PlanEntryResolver{Plan: context.Plan}
+		if _, ok, err := pr.Resolve(PlanEntryJRE); err != nil {
+			return libcnb.BuildResult{}, fmt.Errorf("unable to resolve %s plan entry\n%w", PlanEntryJRE, err)
+		} else if ok {
+			p, err := JVMProcess(context.Application.Path, filepath.Join(context.Layers.Path, JVMArtifacts{}.Name()), manifest, jarFilePattern, jarExclusions)
+			if err != nil {
+				return libcnb.BuildResult{}, fmt.Errorf("unable to create jvm process\n%w", err)
+			}
+			result.Processes = append(result.Processes, p)
+		}
+	}
 
 	if b.SBOMScanner == nil {
 		b.SBOMScanner = sbom.NewSyftCLISBOMScanner(context.Layers, effect.CommandExecutor{}, b.Logger)
@@ -158,17 +431,310 @@ func warn(l bard.Logger, msg string) {
 	)
 }
 
-func findStartOrMainClass(manifest *properties.Properties, appPath, jarFilePattern string) (string, error) {
-	_, startClass, err := ExplodedJarArguments{Manifest: manifest}.Configure(nil)
-	if err != nil && !errors.Is(err, NoStartOrMainClass{}) {
-		return "", fmt.Errorf("unable to find startClass\n%w", err)
+func (b Build) buildCache(cr ConfigurationResolver, cacheDir string) (BuildCache, error) {
+	if exists, err := sherpa.DirExists(cacheDir); err != nil {
+		return BuildCache{}, fmt.Errorf("unable to check for %s\n%w", cacheDir, err)
+	} else if !exists {
+		warn(b.Logger, fmt.Sprintf("Native image cache directory %s does not exist, no native images will be shared between builds", cacheDir))
+		return BuildCache{}, nil
 	}
 
-	if startClass != "" {
-		return startClass, nil
+	rawSize, _ := cr.Resolve(ConfigNativeImageCacheSize)
+	if rawSize == "" {
+		rawSize = DefaultNativeImageCacheSize
+	}
+	size, err := ParseSize(rawSize)
+	if err != nil {
+		return BuildCache{}, fmt.Errorf("unable to parse $%s\n%w", ConfigNativeImageCacheSize, err)
+	}
+
+	return BuildCache{Logger: b.Logger, MaxSize: size, Path: cacheDir}, nil
+}
+
+// dependencies creates the layer holding the native image built from the library JARs, if the application can be
+// built as a layered native image
+func (b Build) dependencies(context libcnb.BuildContext, target Target, args string, linking string, manifest *properties.Properties, mainModule string) (NativeImageDependencies, bool, error) {
+	if linking == LinkingStatic {
+		warn(b.Logger, "Layered native images cannot be statically linked, a single native image will be built instead")
+		return NativeImageDependencies{}, false, nil
+	}
+
+	if modular, err := IsModular(context.Application.Path, mainModule); err != nil {
+		return NativeImageDependencies{}, false, fmt.Errorf("unable to check for module\n%w", err)
+	} else if modular {
+		warn(b.Logger, "Layered native images require a class path, a single native image will be built from the module path instead")
+		return NativeImageDependencies{}, false, nil
+	}
+
+	if exists, err := sherpa.FileExists(filepath.Join(context.Application.Path, "META-INF", "MANIFEST.MF")); err != nil {
+		return NativeImageDependencies{}, false, fmt.Errorf("unable to check for manifest\n%w", err)
+	} else if !exists {
+		warn(b.Logger, "Layered native images require an exploded JAR, a single native image will be built instead")
+		return NativeImageDependencies{}, false, nil
+	}
+
+	cp, err := ExplodedJarArguments{ApplicationPath: context.Application.Path, Manifest: manifest}.ClassPath()
+	if err != nil {
+		return NativeImageDependencies{}, false, fmt.Errorf("unable to determine class path\n%w", err)
+	}
+
+	d := NewNativeImageDependencies(args, cp, context.StackID)
+	d.Logger = b.Logger
+	d.Target = target
+	return d, true, nil
+}
+
+// variants parses the microarchitectures configured with $BP_NATIVE_IMAGE_MARCH, from the most to the least preferred.
+// A single microarchitecture is passed to native-image as is, while each of several has to be known so that the
+// launcher can select it.
+func (b Build) variants(raw string, arch string) ([]string, error) {
+	var variants []string
+	seen := map[string]bool{}
+	for _, v := range strings.FieldsFunc(raw, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	}) {
+		if !seen[v] {
+			seen[v] = true
+			variants = append(variants, v)
+		}
+	}
+
+	if len(variants) == 1 {
+		if variants[0] == march.Native {
+			warn(b.Logger, fmt.Sprintf("The native image is built for the CPU of the build host with -march=%s and may not start on other CPUs", march.Native))
+		}
+		return variants, nil
+	}
+
+	for _, v := range variants {
+		if !march.Known(v, arch) {
+			return nil, fmt.Errorf("unable to select %s at launch, supported microarchitectures on %s are %s",
+				v, arch, strings.Join(march.Names(arch), ", "))
+		}
+	}
+
+	if !march.Supported(variants[len(variants)-1], nil) {
+		warn(b.Logger, fmt.Sprintf("None of the variants %s runs on every %s CPU, add %s as the last one to start on any CPU",
+			strings.Join(variants, ", "), arch, march.Compatibility))
+	}
+
+	b.Logger.Bodyf("Building variants %s, selected at launch in this order", strings.Join(variants, ", "))
+	return variants, nil
+}
+
+// logPlanMetadata logs the configuration contributed by upstream buildpacks
+func (b Build) logPlanMetadata(plan PlanMetadata) {
+	if plan.IsEmpty() {
+		return
+	}
+
+	b.Logger.Bodyf("Configuration contributed by upstream buildpacks through %s plan entries:", PlanEntryNativeImage)
+	if len(plan.Arguments) > 0 {
+		b.Logger.Bodyf("  Arguments: %s", strings.Join(plan.Arguments, " "))
+	}
+	if len(plan.ArgumentFiles) > 0 {
+		b.Logger.Bodyf("  Argument files: %s", strings.Join(plan.ArgumentFiles, ", "))
+	}
+	if len(plan.ConfigDirectories) > 0 {
+		b.Logger.Bodyf("  Configuration directories: %s", strings.Join(plan.ConfigDirectories, ", "))
+	}
+	if plan.MainClass != "" {
+		b.Logger.Bodyf("  Main class: %s", plan.MainClass)
+	}
+	if plan.ExecutableName != "" {
+		b.Logger.Bodyf("  Executable name: %s", plan.ExecutableName)
+	}
+}
+
+// descriptorProcesses adds the processes of the descriptor to processes, replacing those of the same type. A default
+// process of the descriptor replaces the default process.
+func descriptorProcesses(processes []libcnb.Process, descriptor []DescriptorProcess) []libcnb.Process {
+	for _, d := range descriptor {
+		if d.Default {
+			for i := range processes {
+				processes[i].Default = false
+			}
+		}
+
+		p := libcnb.Process{
+			Type:      d.Type,
+			Command:   processes[0].Command,
+			Arguments: d.Arguments,
+			Direct:    true,
+			Default:   d.Default,
+		}
+
+		replaced := false
+		for i := range processes {
+			if processes[i].Type == d.Type {
+				p.Default = p.Default || processes[i].Default
+				processes[i], replaced = p, true
+			}
+		}
+		if !replaced {
+			processes = append(processes, p)
+		}
+	}
+
+	return processes
+}
+
+// logDescriptor logs the configuration read from the descriptor of the application, noting values overridden by the
+// environment
+func (b Build) logDescriptor(file string, profile string, cr ConfigurationResolver) {
+	if file == "" {
+		return
+	}
+
+	if profile != "" {
+		b.Logger.Bodyf("Configuration read from %s with profile %s:", file, profile)
+	} else {
+		b.Logger.Bodyf("Configuration read from %s:", file)
+	}
+
+	var names []string
+	for name := range cr.Descriptor {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if _, ok := os.LookupEnv(name); ok {
+			b.Logger.Bodyf("  $%s: %s (overridden by the environment)", name, cr.Descriptor[name])
+		} else {
+			b.Logger.Bodyf("  $%s: %s", name, cr.Descriptor[name])
+		}
+	}
+}
+
+// resolveSize resolves a size configured by name, returning zero if it is not set
+func resolveSize(cr ConfigurationResolver, name string) (int64, error) {
+	raw, ok := cr.Resolve(name)
+	if !ok || raw == "" {
+		return 0, nil
+	}
+
+	size, err := ParseSize(raw)
+	if err != nil {
+		return 0, fmt.Errorf("unable to parse $%s\n%w", name, err)
+	}
+	return size, nil
+}
+
+// smokeTest configures the smoke test running command. The exit code is only checked if it is set or no output is
+// expected.
+func smokeTest(cr ConfigurationResolver, command string) (SmokeTest, error) {
+	s := SmokeTest{Command: command, ExitCode: 0}
+
+	if raw, ok := cr.Resolve(ConfigNativeImageSmokeTestOutput); ok && raw != "" {
+		output, err := regexp.Compile(raw)
+		if err != nil {
+			return SmokeTest{}, fmt.Errorf("unable to parse $%s\n%w", ConfigNativeImageSmokeTestOutput, err)
+		}
+		s.Output = output
+		s.ExitCode = AnyExitCode
+	}
+
+	if raw, ok := cr.Resolve(ConfigNativeImageSmokeTestExit); ok && raw != "" {
+		exitCode, err := strconv.Atoi(raw)
+		if err != nil {
+			return SmokeTest{}, fmt.Errorf("unable to parse $%s\n%w", ConfigNativeImageSmokeTestExit, err)
+		}
+		s.ExitCode = exitCode
+	}
+
+	raw, _ := cr.Resolve(ConfigNativeImageSmokeTestTime)
+	if raw == "" {
+		raw = DefaultSmokeTestTimeout
+	}
+	timeout, err := time.ParseDuration(raw)
+	if err != nil {
+		return SmokeTest{}, fmt.Errorf("unable to parse $%s\n%w", ConfigNativeImageSmokeTestTime, err)
+	}
+	s.Timeout = timeout
+
+	return s, nil
+}
+
+// linkingMode determines the linking mode from the baseline, plan and user provided arguments
+func linkingMode(target Target, stackID string, plan PlanMetadata, args string, argsFile string) (string, error) {
+	arguments, _, err := BaselineArguments{StackID: stackID, Target: target}.Configure(nil)
+	if err != nil {
+		return "", fmt.Errorf("unable to set baseline arguments\n%w", err)
+	}
+
+	arguments = append(arguments, plan.Arguments...)
+
+	arguments, _, err = UserArguments{Arguments: args}.Configure(arguments)
+	if err != nil {
+		return "", fmt.Errorf("unable to create user arguments\n%w", err)
+	}
+
+	files := append([]string{}, plan.ArgumentFiles...)
+	if argsFile != "" {
+		files = append(files, argsFile)
+	}
+	for _, f := range files {
+		rawArgs, err := os.ReadFile(f)
+		if err != nil {
+			return "", fmt.Errorf("unable to read arguments from %s\n%w", f, err)
+		}
+		arguments = append(arguments, strings.Fields(string(rawArgs))...)
+	}
+
+	return LinkingMode(arguments), nil
+}
+
+func findStartOrMainClass(manifest *properties.Properties, appPath, jarFilePattern string, jarExclusions []string, mainModule string, plan PlanMetadata) (string, error) {
+	if dir, ok, err := FindQuarkusNativeSources(appPath); err != nil {
+		return "", fmt.Errorf("unable to find Quarkus native sources\n%w", err)
+	} else if ok {
+		return QuarkusArguments{Directory: dir, ExecutableName: plan.ExecutableName}.ImageName()
+	}
+
+	if modular, err := IsModular(appPath, mainModule); err != nil {
+		return "", fmt.Errorf("unable to check for module\n%w", err)
+	} else if modular {
+		_, name, err := ModuleArguments{
+			ApplicationPath: appPath,
+			MainModule:      mainModule,
+			MainClass:       plan.MainClass,
+			ExecutableName:  plan.ExecutableName,
+		}.Configure(nil)
+		if err != nil {
+			return "", fmt.Errorf("unable to find main module\n%w", err)
+		}
+		return name, nil
+	}
+
+	// a JAR is built, and named after the JAR, unless the application is exploded
+	exploded, err := sherpa.FileExists(filepath.Join(appPath, "META-INF", "MANIFEST.MF"))
+	if err != nil {
+		return "", fmt.Errorf("unable to check for manifest\n%w", err)
+	}
+
+	if exploded || jarFilePattern == "" {
+		_, startClass, err := ExplodedJarArguments{
+			ApplicationPath: appPath,
+			Manifest:        manifest,
+			MainClass:       plan.MainClass,
+			ExecutableName:  plan.ExecutableName,
+		}.Configure(nil)
+		if err != nil && !errors.Is(err, NoStartOrMainClass{}) {
+			return "", fmt.Errorf("unable to find startClass\n%w", err)
+		}
+
+		if startClass != "" {
+			return startClass, nil
+		}
 	}
 
-	_, startClass, err = JarArguments{JarFilePattern: jarFilePattern, ApplicationPath: appPath}.Configure(nil)
+	_, startClass, err := JarArguments{
+		JarFilePattern:  jarFilePattern,
+		Exclusions:      jarExclusions,
+		ApplicationPath: appPath,
+		ExecutableName:  plan.ExecutableName,
+	}.Configure(nil)
 	if err != nil {
 		return "", fmt.Errorf("unable to find startClass from JAR\n%w", err)
 	}
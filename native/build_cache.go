@@ -0,0 +1,208 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package native
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/paketo-buildpacks/libpak/bard"
+	"github.com/paketo-buildpacks/libpak/sherpa"
+)
+
+const (
+	buildCacheTempPrefix  = ".tmp-"
+	buildCacheEvictPrefix = ".evict-"
+	buildCacheStaleAge    = 24 * time.Hour
+)
+
+// BuildCache is a directory shared between builds and applications that holds native images keyed by the digest of
+// their build inputs. Entries are published and evicted by renaming, so that concurrent builders only ever see
+// complete entries, and the least recently used entries are evicted once MaxSize is exceeded.
+type BuildCache struct {
+	Logger  bard.Logger
+	MaxSize int64
+	Path    string
+}
+
+// Enabled returns true if a build cache directory has been configured
+func (b BuildCache) Enabled() bool {
+	return b.Path != ""
+}
+
+// Key returns the key of the native image built with metadata, which must include the digest of all build inputs
+func (b BuildCache) Key(metadata map[string]interface{}) (string, error) {
+	raw, err := json.Marshal(metadata)
+	if err != nil {
+		return "", fmt.Errorf("unable to encode metadata\n%w", err)
+	}
+
+	return fmt.Sprintf("%x", sha256.Sum256(raw)), nil
+}
+
+// Restore copies the entry for key to destination, returning false if there is no such entry
+func (b BuildCache) Restore(key string, destination string) (bool, error) {
+	entry := filepath.Join(b.Path, key)
+
+	if exists, err := sherpa.DirExists(entry); err != nil {
+		return false, fmt.Errorf("unable to check for %s\n%w", entry, err)
+	} else if !exists {
+		return false, nil
+	}
+
+	if err := sherpa.CopyDir(entry, destination); err != nil {
+		return false, fmt.Errorf("unable to copy %s to %s\n%w", entry, destination, err)
+	}
+
+	now := time.Now()
+	if err := os.Chtimes(entry, now, now); err != nil && !os.IsNotExist(err) {
+		return false, fmt.Errorf("unable to mark %s as used\n%w", entry, err)
+	}
+
+	return true, nil
+}
+
+// Store copies source to the entry for key. If another builder publishes the same entry first, its entry is kept.
+func (b BuildCache) Store(key string, source string) error {
+	temp, err := os.MkdirTemp(b.Path, buildCacheTempPrefix)
+	if err != nil {
+		return fmt.Errorf("unable to create temporary directory in %s\n%w", b.Path, err)
+	}
+
+	if err := sherpa.CopyDir(source, temp); err != nil {
+		_ = os.RemoveAll(temp)
+		return fmt.Errorf("unable to copy %s to %s\n%w", source, temp, err)
+	}
+
+	// MkdirTemp creates the directory as 0700
+	if err := os.Chmod(temp, 0755); err != nil {
+		_ = os.RemoveAll(temp)
+		return fmt.Errorf("unable to change permissions of %s\n%w", temp, err)
+	}
+
+	entry := filepath.Join(b.Path, key)
+	if err := os.Rename(temp, entry); err != nil {
+		_ = os.RemoveAll(temp)
+
+		if exists, _ := sherpa.DirExists(entry); exists {
+			return nil
+		}
+		return fmt.Errorf("unable to rename %s to %s\n%w", temp, entry, err)
+	}
+
+	return nil
+}
+
+// Evict removes the least recently used entries until the cache is no larger than MaxSize, along with any stale
+// temporary directories left behind by failed builds
+func (b BuildCache) Evict() error {
+	children, err := os.ReadDir(b.Path)
+	if err != nil {
+		return fmt.Errorf("unable to list %s\n%w", b.Path, err)
+	}
+
+	type entry struct {
+		name    string
+		size    int64
+		modTime time.Time
+	}
+
+	var entries []entry
+	var total int64
+	for _, c := range children {
+		if !c.IsDir() {
+			continue
+		}
+
+		info, err := c.Info()
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return fmt.Errorf("unable to stat %s\n%w", c.Name(), err)
+		}
+
+		if strings.HasPrefix(c.Name(), ".") {
+			if (strings.HasPrefix(c.Name(), buildCacheTempPrefix) || strings.HasPrefix(c.Name(), buildCacheEvictPrefix)) &&
+				time.Since(info.ModTime()) > buildCacheStaleAge {
+				_ = os.RemoveAll(filepath.Join(b.Path, c.Name()))
+			}
+			continue
+		}
+
+		size, err := dirSize(filepath.Join(b.Path, c.Name()))
+		if err != nil {
+			return fmt.Errorf("unable to compute size of %s\n%w", c.Name(), err)
+		}
+
+		entries = append(entries, entry{name: c.Name(), size: size, modTime: info.ModTime()})
+		total += size
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].modTime.Before(entries[j].modTime)
+	})
+
+	for _, e := range entries {
+		if total <= b.MaxSize {
+			break
+		}
+
+		b.Logger.Bodyf("Evicting %s (%s) from native image cache", e.name, formatSize(e.size))
+
+		// renaming first means that no other builder can restore a partially removed entry
+		evicted := filepath.Join(b.Path, fmt.Sprintf("%s%s", buildCacheEvictPrefix, randomSuffix()))
+		if err := os.Rename(filepath.Join(b.Path, e.name), evicted); err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return fmt.Errorf("unable to evict %s\n%w", e.name, err)
+		}
+
+		if err := os.RemoveAll(evicted); err != nil {
+			return fmt.Errorf("unable to remove %s\n%w", evicted, err)
+		}
+		total -= e.size
+	}
+
+	return nil
+}
+
+func dirSize(path string) (int64, error) {
+	var size int64
+	err := filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
+
+func randomSuffix() string {
+	h := sha256.Sum256([]byte(fmt.Sprintf("%d-%d", os.Getpid(), time.Now().UnixNano())))
+	return hex.EncodeToString(h[:8])
+}
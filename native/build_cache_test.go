@@ -0,0 +1,146 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package native_test
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/paketo-buildpacks/libpak/bard"
+	"github.com/sclevine/spec"
+
+	"github.com/paketo-buildpacks/native-image/v5/native"
+)
+
+func testBuildCache(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		cache  native.BuildCache
+		source string
+	)
+
+	it.Before(func() {
+		cache = native.BuildCache{
+			Logger:  bard.NewLogger(io.Discard),
+			MaxSize: 1024,
+			Path:    t.TempDir(),
+		}
+
+		source = t.TempDir()
+		Expect(os.WriteFile(filepath.Join(source, "test-start-class"), []byte("native"), 0755)).To(Succeed())
+		Expect(os.MkdirAll(filepath.Join(source, "sources"), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(source, "sources", "Test.java"), []byte("source"), 0644)).To(Succeed())
+	})
+
+	it("computes a stable key from metadata", func() {
+		k1, err := cache.Key(map[string]interface{}{"inputs": "1", "arguments": []string{"a"}})
+		Expect(err).NotTo(HaveOccurred())
+
+		k2, err := cache.Key(map[string]interface{}{"arguments": []string{"a"}, "inputs": "1"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(k2).To(Equal(k1))
+
+		k3, err := cache.Key(map[string]interface{}{"arguments": []string{"a"}, "inputs": "2"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(k3).NotTo(Equal(k1))
+	})
+
+	it("does not restore a missing entry", func() {
+		ok, err := cache.Restore("missing", t.TempDir())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeFalse())
+	})
+
+	it("stores and restores an entry", func() {
+		Expect(cache.Store("test-key", source)).To(Succeed())
+
+		destination := filepath.Join(t.TempDir(), "layer")
+		ok, err := cache.Restore("test-key", destination)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+
+		info, err := os.Stat(filepath.Join(destination, "test-start-class"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Mode().Perm()).To(Equal(os.FileMode(0755)))
+		Expect(filepath.Join(destination, "sources", "Test.java")).To(BeARegularFile())
+
+		entries, err := os.ReadDir(cache.Path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(HaveLen(1))
+	})
+
+	it("keeps a single entry when stored concurrently", func() {
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				Expect(cache.Store("test-key", source)).To(Succeed())
+			}()
+		}
+		wg.Wait()
+
+		entries, err := os.ReadDir(cache.Path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(HaveLen(1))
+		Expect(entries[0].Name()).To(Equal("test-key"))
+	})
+
+	it("evicts the least recently used entries", func() {
+		Expect(os.WriteFile(filepath.Join(source, "test-start-class"), make([]byte, 400), 0755)).To(Succeed())
+
+		for i := 0; i < 3; i++ {
+			key := fmt.Sprintf("key-%d", i)
+			Expect(cache.Store(key, source)).To(Succeed())
+
+			used := time.Now().Add(time.Duration(i-10) * time.Minute)
+			Expect(os.Chtimes(filepath.Join(cache.Path, key), used, used)).To(Succeed())
+		}
+
+		ok, err := cache.Restore("key-0", t.TempDir()+"/layer")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+
+		Expect(cache.Evict()).To(Succeed())
+
+		Expect(filepath.Join(cache.Path, "key-0")).To(BeADirectory())
+		Expect(filepath.Join(cache.Path, "key-1")).NotTo(BeAnExistingFile())
+		Expect(filepath.Join(cache.Path, "key-2")).To(BeADirectory())
+	})
+
+	it("removes stale temporary directories", func() {
+		stale := filepath.Join(cache.Path, ".tmp-stale")
+		Expect(os.MkdirAll(stale, 0755)).To(Succeed())
+		old := time.Now().Add(-48 * time.Hour)
+		Expect(os.Chtimes(stale, old, old)).To(Succeed())
+
+		recent := filepath.Join(cache.Path, ".tmp-recent")
+		Expect(os.MkdirAll(recent, 0755)).To(Succeed())
+
+		Expect(cache.Evict()).To(Succeed())
+
+		Expect(stale).NotTo(BeAnExistingFile())
+		Expect(recent).To(BeADirectory())
+	})
+}
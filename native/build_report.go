@@ -0,0 +1,256 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package native
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/buildpacks/libcnb"
+	"github.com/paketo-buildpacks/libpak"
+	"github.com/paketo-buildpacks/libpak/bard"
+	"github.com/paketo-buildpacks/libpak/sherpa"
+)
+
+const (
+	// BuildOutputJSONFile is the build output written by native-image with -H:BuildOutputJSONFile
+	BuildOutputJSONFile = "build-output.json"
+
+	// BuildOutputLogFile is the console output of native-image, from which the duration of each stage is read
+	BuildOutputLogFile = "build-output.log"
+
+	// BuildReportHTMLFile is the build report written by native-image with --emit build-report
+	BuildReportHTMLFile = "build-report.html"
+
+	// BuildReportFile is the summary of the build written to the native-image-report layer
+	BuildReportFile = "native-image-report.json"
+
+	// MinimumBuildReportVersion is the first Java version whose native-image supports both build outputs
+	MinimumBuildReportVersion = 21
+)
+
+var (
+	ansiEscape = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+	buildStage = regexp.MustCompile(`^\[\d+/\d+\]\s+(.+?)\.\.\..*\(([\d.]+)s\b`)
+)
+
+// BuildOutputCount is the number of elements of a kind found by the analysis
+type BuildOutputCount struct {
+	Total     int64 `json:"total"`
+	Reachable int64 `json:"reachable"`
+}
+
+// BuildOutputBytes is the size of an area of the native image
+type BuildOutputBytes struct {
+	Bytes int64 `json:"bytes"`
+}
+
+// BuildStage is a stage of a native-image build and its duration in seconds
+type BuildStage struct {
+	Name    string  `json:"name"`
+	Seconds float64 `json:"seconds"`
+}
+
+// BuildReport is the subset of the native-image build output that is reported, along with the duration of each stage
+type BuildReport struct {
+	AnalysisResults struct {
+		Types   BuildOutputCount `json:"types"`
+		Methods BuildOutputCount `json:"methods"`
+		Fields  BuildOutputCount `json:"fields"`
+	} `json:"analysis_results"`
+
+	ImageDetails struct {
+		TotalBytes int64            `json:"total_bytes"`
+		CodeArea   BuildOutputBytes `json:"code_area"`
+		ImageHeap  BuildOutputBytes `json:"image_heap"`
+		DebugInfo  BuildOutputBytes `json:"debug_info"`
+	} `json:"image_details"`
+
+	ResourceUsage struct {
+		Memory struct {
+			PeakRSSBytes int64 `json:"peak_rss_bytes"`
+		} `json:"memory"`
+		TotalSecs float64 `json:"total_secs"`
+	} `json:"resource_usage"`
+
+	Stages []BuildStage `json:"stages"`
+}
+
+// SupportsBuildReport returns whether the native-image that printed version supports -H:BuildOutputJSONFile and
+// --emit build-report
+func SupportsBuildReport(version string) bool {
+	major, ok := nativeImageMajorVersion(version)
+	return ok && major >= MinimumBuildReportVersion
+}
+
+// BuildReportArguments returns the arguments requesting the build outputs be written to layerPath
+func BuildReportArguments(layerPath string) []string {
+	return []string{
+		fmt.Sprintf("-H:BuildOutputJSONFile=%s", filepath.Join(layerPath, BuildOutputJSONFile)),
+		"--emit", fmt.Sprintf("build-report=%s", filepath.Join(layerPath, BuildReportHTMLFile)),
+	}
+}
+
+// ReadBuildReport reads the build output and the stages logged by native-image to layerPath. It returns false if
+// native-image did not write a build output.
+func ReadBuildReport(layerPath string) (BuildReport, bool, error) {
+	var report BuildReport
+
+	file := filepath.Join(layerPath, BuildOutputJSONFile)
+	b, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return BuildReport{}, false, nil
+	} else if err != nil {
+		return BuildReport{}, false, fmt.Errorf("unable to read %s\n%w", file, err)
+	}
+
+	if err := json.Unmarshal(b, &report); err != nil {
+		return BuildReport{}, false, fmt.Errorf("unable to decode %s\n%w", file, err)
+	}
+
+	report.Stages, err = readBuildStages(filepath.Join(layerPath, BuildOutputLogFile))
+	if err != nil {
+		return BuildReport{}, false, fmt.Errorf("unable to read build stages\n%w", err)
+	}
+
+	return report, true, nil
+}
+
+// readBuildStages parses the `[1/8] Initializing... (3.2s @ 0.11GB)` progress lines of the native-image output
+func readBuildStages(file string) ([]BuildStage, error) {
+	in, err := os.Open(file)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("unable to open %s\n%w", file, err)
+	}
+	defer in.Close()
+
+	var stages []BuildStage
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		m := buildStage.FindStringSubmatch(ansiEscape.ReplaceAllString(strings.TrimSpace(scanner.Text()), ""))
+		if m == nil {
+			continue
+		}
+
+		seconds, err := strconv.ParseFloat(m[2], 64)
+		if err != nil {
+			continue
+		}
+		stages = append(stages, BuildStage{Name: m[1], Seconds: seconds})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read %s\n%w", file, err)
+	}
+
+	return stages, nil
+}
+
+// NativeImageReport publishes the build report of the native image in a build layer and logs a summary of it
+type NativeImageReport struct {
+	Logger               bard.Logger
+	NativeImageLayerPath string
+}
+
+func NewNativeImageReport(nativeImageLayerPath string) NativeImageReport {
+	return NativeImageReport{NativeImageLayerPath: nativeImageLayerPath}
+}
+
+func (r NativeImageReport) Contribute(layer libcnb.Layer) (libcnb.Layer, error) {
+	report, ok, err := ReadBuildReport(r.NativeImageLayerPath)
+	if err != nil {
+		return libcnb.Layer{}, fmt.Errorf("unable to read build report\n%w", err)
+	} else if !ok {
+		r.Logger.Body("No build report was written by native-image")
+		return layer, nil
+	}
+
+	b, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return libcnb.Layer{}, fmt.Errorf("unable to encode build report\n%w", err)
+	}
+
+	r.logSummary(report)
+
+	contributor := libpak.NewLayerContributor("Native Image Report", map[string]interface{}{
+		"report": fmt.Sprintf("%x", sha256.Sum256(b)),
+	}, libcnb.LayerTypes{
+		Build: true,
+	})
+	contributor.Logger = r.Logger
+
+	layer, err = contributor.Contribute(layer, func() (libcnb.Layer, error) {
+		file := filepath.Join(layer.Path, BuildReportFile)
+		if err := os.WriteFile(file, b, 0644); err != nil {
+			return libcnb.Layer{}, fmt.Errorf("unable to write %s\n%w", file, err)
+		}
+
+		for _, name := range []string{BuildOutputJSONFile, BuildReportHTMLFile} {
+			src := filepath.Join(r.NativeImageLayerPath, name)
+			if exists, err := sherpa.FileExists(src); err != nil {
+				return libcnb.Layer{}, fmt.Errorf("unable to check for %s\n%w", src, err)
+			} else if !exists {
+				continue
+			}
+
+			if err := copyFile(src, filepath.Join(layer.Path, name)); err != nil {
+				return libcnb.Layer{}, fmt.Errorf("unable to copy %s to %s\n%w", src, layer.Path, err)
+			}
+		}
+
+		return layer, nil
+	})
+	if err != nil {
+		return libcnb.Layer{}, fmt.Errorf("unable to contribute native-image-report layer\n%w", err)
+	}
+
+	return layer, nil
+}
+
+func (NativeImageReport) Name() string {
+	return "native-image-report"
+}
+
+func (r NativeImageReport) logSummary(report BuildReport) {
+	a := report.AnalysisResults
+	r.Logger.Body("Native image build report:")
+	r.Logger.Bodyf("  Analysis: %d of %d types, %d of %d methods and %d of %d fields reachable",
+		a.Types.Reachable, a.Types.Total, a.Methods.Reachable, a.Methods.Total, a.Fields.Reachable, a.Fields.Total)
+
+	d := report.ImageDetails
+	r.Logger.Bodyf("  Image size: %s, of which %s code area, %s image heap and %s debug info",
+		formatSize(d.TotalBytes), formatSize(d.CodeArea.Bytes), formatSize(d.ImageHeap.Bytes), formatSize(d.DebugInfo.Bytes))
+	r.Logger.Bodyf("  Peak RSS: %s", formatSize(report.ResourceUsage.Memory.PeakRSSBytes))
+
+	var stages []string
+	for _, s := range report.Stages {
+		stages = append(stages, fmt.Sprintf("%s %.1fs", s.Name, s.Seconds))
+	}
+	if len(stages) > 0 {
+		r.Logger.Bodyf("  Stages: %s", strings.Join(stages, ", "))
+	}
+	r.Logger.Bodyf("  Total: %.1fs", report.ResourceUsage.TotalSecs)
+}
@@ -0,0 +1,149 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package native_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/buildpacks/libcnb"
+	. "github.com/onsi/gomega"
+	"github.com/paketo-buildpacks/libpak/bard"
+	"github.com/sclevine/spec"
+
+	"github.com/paketo-buildpacks/native-image/v5/native"
+)
+
+func testBuildReport(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		ctx             libcnb.BuildContext
+		nativeImagePath string
+	)
+
+	it.Before(func() {
+		ctx.Layers.Path = t.TempDir()
+		nativeImagePath = t.TempDir()
+
+		Expect(os.WriteFile(filepath.Join(nativeImagePath, "build-output.json"), []byte(`{
+  "general_info": {"name": "test-start-class"},
+  "analysis_results": {
+    "types": {"total": 300, "reachable": 200, "reflection": 10, "jni": 5},
+    "methods": {"total": 3000, "reachable": 2000},
+    "fields": {"total": 600, "reachable": 400}
+  },
+  "image_details": {
+    "total_bytes": 41943040,
+    "code_area": {"bytes": 20971520, "compilation_units": 1000},
+    "image_heap": {"bytes": 18874368, "objects": {"count": 100}},
+    "debug_info": {"bytes": 2097152}
+  },
+  "resource_usage": {
+    "memory": {"system_total": 17179869184, "peak_rss_bytes": 3221225472},
+    "total_secs": 95.25
+  }
+}`), 0644)).To(Succeed())
+
+		Expect(os.WriteFile(filepath.Join(nativeImagePath, "build-output.log"), []byte(
+			"========================================\n"+
+				"[1/8] Initializing...                    (3.9s @ 0.14GB)\n"+
+				" Java version: 21.0.2+13\n"+
+				"\x1b[1m[2/8] Performing analysis...\x1b[0m  [******]   (40.1s @ 1.20GB)\n"+
+				"[8/8] Creating image...                  (2.0s @ 1.50GB)\n",
+		), 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(nativeImagePath, "build-report.html"), []byte("<html/>"), 0644)).To(Succeed())
+	})
+
+	it("reads the build output and stages", func() {
+		report, ok, err := native.ReadBuildReport(nativeImagePath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+
+		Expect(report.AnalysisResults.Types).To(Equal(native.BuildOutputCount{Total: 300, Reachable: 200}))
+		Expect(report.ImageDetails.TotalBytes).To(Equal(int64(41943040)))
+		Expect(report.ImageDetails.DebugInfo.Bytes).To(Equal(int64(2097152)))
+		Expect(report.ResourceUsage.Memory.PeakRSSBytes).To(Equal(int64(3221225472)))
+		Expect(report.Stages).To(Equal([]native.BuildStage{
+			{Name: "Initializing", Seconds: 3.9},
+			{Name: "Performing analysis", Seconds: 40.1},
+			{Name: "Creating image", Seconds: 2.0},
+		}))
+	})
+
+	it("does not read a report that was not written", func() {
+		_, ok, err := native.ReadBuildReport(t.TempDir())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeFalse())
+	})
+
+	it("supports native-image 21 and later", func() {
+		Expect(native.SupportsBuildReport("native-image 21.0.2 2024-01-16")).To(BeTrue())
+		Expect(native.SupportsBuildReport("native-image 17.0.9 2023-10-17")).To(BeFalse())
+		Expect(native.SupportsBuildReport("GraalVM 22.3.0 Java 17 CE")).To(BeFalse())
+	})
+
+	context("NativeImageReport", func() {
+		var (
+			layer  libcnb.Layer
+			out    *bytes.Buffer
+			report native.NativeImageReport
+		)
+
+		it.Before(func() {
+			out = &bytes.Buffer{}
+			report = native.NewNativeImageReport(nativeImagePath)
+			report.Logger = bard.NewLogger(out)
+
+			var err error
+			layer, err = ctx.Layers.Layer("native-image-report")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		it("publishes the report in a build layer", func() {
+			layer, err := report.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(layer.Build).To(BeTrue())
+			Expect(layer.Launch).To(BeFalse())
+			Expect(filepath.Join(layer.Path, "native-image-report.json")).To(BeARegularFile())
+			Expect(filepath.Join(layer.Path, "build-output.json")).To(BeARegularFile())
+			Expect(filepath.Join(layer.Path, "build-report.html")).To(BeARegularFile())
+		})
+
+		it("logs a summary", func() {
+			_, err := report.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(out.String()).To(ContainSubstring("Analysis: 200 of 300 types, 2000 of 3000 methods and 400 of 600 fields reachable"))
+			Expect(out.String()).To(ContainSubstring("Image size: 40.0 MiB, of which 20.0 MiB code area, 18.0 MiB image heap and 2.0 MiB debug info"))
+			Expect(out.String()).To(ContainSubstring("Peak RSS: 3.0 GiB"))
+			Expect(out.String()).To(ContainSubstring("Stages: Initializing 3.9s, Performing analysis 40.1s, Creating image 2.0s"))
+		})
+
+		it("does nothing without a report", func() {
+			report.NativeImageLayerPath = t.TempDir()
+
+			layer, err := report.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(layer.Build).To(BeFalse())
+		})
+	})
+}
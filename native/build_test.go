@@ -21,7 +21,9 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/paketo-buildpacks/libpak"
 	"github.com/paketo-buildpacks/libpak/sbom/mocks"
 	"github.com/paketo-buildpacks/libpak/sherpa"
 
@@ -85,7 +87,7 @@ Start-Class: test-start-class
 		result, err := build.Build(ctx)
 		Expect(err).NotTo(HaveOccurred())
 
-		Expect(result.Layers).To(HaveLen(1))
+		Expect(result.Layers).To(HaveLen(3))
 		Expect(result.Layers[0].(native.NativeImage).Arguments).To(BeEmpty())
 		Expect(result.Processes).To(ContainElements(
 			libcnb.Process{Type: "native-image", Command: "./test-start-class", Direct: true},
@@ -113,7 +115,7 @@ Start-Class: test-start-class
 				result, err := build.Build(ctx)
 				Expect(err).NotTo(HaveOccurred())
 
-				Expect(result.Layers).To(HaveLen(1))
+				Expect(result.Layers).To(HaveLen(3))
 				Expect(result.Layers[0].(native.NativeImage).Arguments).To(BeEmpty())
 				Expect(result.Processes).To(ContainElements(
 					libcnb.Process{Type: "native-image", Command: "./test-start-class", Direct: true},
@@ -171,7 +173,7 @@ Start-Class: test-start-class
 			result, err := build.Build(ctx)
 			Expect(err).NotTo(HaveOccurred())
 
-			Expect(result.Layers).To(HaveLen(1))
+			Expect(result.Layers).To(HaveLen(3))
 			Expect(result.Layers[0].(native.NativeImage).Arguments).To(BeEmpty())
 			Expect(result.Processes).To(ContainElements(
 				libcnb.Process{Type: "native-image", Command: "./test-start-class", Direct: true},
@@ -256,6 +258,21 @@ Start-Class: test-start-class
 			Expect(err).NotTo(HaveOccurred())
 
 			Expect(result.Layers[0].(native.NativeImage).JarFilePattern).To(Equal("target/*.jar"))
+			Expect(result.Layers[0].(native.NativeImage).ExtractionPath).To(Equal(filepath.Join(ctx.Layers.Path, "native-image-extracted-jar")))
+		})
+
+		it("configures the JARs that are not built", func() {
+			t.Setenv("BP_NATIVE_IMAGE_BUILT_ARTIFACT_EXCLUSIONS", "*-tool.jar, other.jar")
+			Expect(os.MkdirAll(filepath.Join(ctx.Application.Path, "target"), 0755)).To(Succeed())
+
+			fp, err := os.Open("testdata/test-fixture.jar")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sherpa.CopyFile(fp, filepath.Join(ctx.Application.Path, "target", "test-fixture.jar"))).To(Succeed())
+
+			result, err := build.Build(ctx)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Layers[0].(native.NativeImage).JarExclusions).To(Equal([]string{"*-tool.jar", "other.jar"}))
 			Expect(result.Processes).To(ContainElements(
 				libcnb.Process{Type: "native-image", Command: "./test-fixture", Direct: true},
 				libcnb.Process{Type: "task", Command: "./test-fixture", Direct: true},
@@ -263,4 +280,866 @@ Start-Class: test-start-class
 			))
 		})
 	})
+
+	context("BP_NATIVE_IMAGE_RETAIN_JVM_ARTIFACTS", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "META-INF", "MANIFEST.MF"), []byte(`
+Main-Class: test-main-class
+Start-Class: test-start-class
+`), 0644)).To(Succeed())
+		})
+
+		it("contributes a build jvm-artifacts layer", func() {
+			t.Setenv("BP_NATIVE_IMAGE_RETAIN_JVM_ARTIFACTS", "build")
+
+			result, err := build.Build(ctx)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Layers).To(HaveLen(4))
+			Expect(result.Layers[0].Name()).To(Equal("jvm-artifacts"))
+			Expect(result.Layers[0].(native.JVMArtifacts).Launch).To(BeFalse())
+			Expect(result.Layers[1].Name()).To(Equal("native-image"))
+			for _, p := range result.Processes {
+				Expect(p.Type).NotTo(Equal("jvm"))
+			}
+		})
+
+		it("contributes a launch jvm-artifacts layer and jvm process", func() {
+			t.Setenv("BP_NATIVE_IMAGE_RETAIN_JVM_ARTIFACTS", "launch")
+			ctx.Plan.Entries = append(ctx.Plan.Entries, libcnb.BuildpackPlanEntry{Name: "jre", Metadata: map[string]interface{}{"launch": true}})
+
+			result, err := build.Build(ctx)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Layers).To(HaveLen(4))
+			Expect(result.Layers[0].(native.JVMArtifacts).Launch).To(BeTrue())
+
+			layerPath := filepath.Join(ctx.Layers.Path, "jvm-artifacts")
+			Expect(result.Processes).To(ContainElement(libcnb.Process{
+				Type:             "jvm",
+				Command:          "java",
+				Arguments:        []string{"-cp", layerPath, "test-start-class"},
+				Direct:           true,
+				WorkingDirectory: layerPath,
+			}))
+		})
+
+		it("does not contribute a jvm process without a jre", func() {
+			t.Setenv("BP_NATIVE_IMAGE_RETAIN_JVM_ARTIFACTS", "launch")
+
+			result, err := build.Build(ctx)
+			Expect(err).NotTo(HaveOccurred())
+
+			for _, p := range result.Processes {
+				Expect(p.Type).NotTo(Equal("jvm"))
+			}
+		})
+	})
+
+	context("BP_NATIVE_IMAGE_DEBUG_INFO", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "META-INF", "MANIFEST.MF"), []byte(`
+Start-Class: test-start-class
+`), 0644)).To(Succeed())
+		})
+
+		it("contributes a cached debug-info layer when split", func() {
+			t.Setenv("BP_NATIVE_IMAGE_DEBUG_INFO", "split")
+
+			result, err := build.Build(ctx)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Layers).To(HaveLen(4))
+			Expect(result.Layers[0].(native.NativeImage).DebugInfo).To(Equal("split"))
+			Expect(result.Layers[1].(native.DebugInfo).NativeImageLayerPath).To(Equal(filepath.Join(ctx.Layers.Path, "native-image")))
+			Expect(result.Layers[1].(native.DebugInfo).Launch).To(BeFalse())
+		})
+
+		it("contributes a launch debug-info layer when requested", func() {
+			t.Setenv("BP_NATIVE_IMAGE_DEBUG_INFO", "split")
+			t.Setenv("BP_NATIVE_IMAGE_DEBUG_INFO_LAUNCH", "true")
+
+			result, err := build.Build(ctx)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Layers[1].(native.DebugInfo).Launch).To(BeTrue())
+		})
+
+		it("does not contribute a debug-info layer when embedded", func() {
+			t.Setenv("BP_NATIVE_IMAGE_DEBUG_INFO", "embedded")
+
+			result, err := build.Build(ctx)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Layers).To(HaveLen(3))
+			Expect(result.Layers[0].(native.NativeImage).DebugInfo).To(Equal("embedded"))
+		})
+
+		it("ignores unknown values", func() {
+			t.Setenv("BP_NATIVE_IMAGE_DEBUG_INFO", "unknown")
+
+			result, err := build.Build(ctx)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Layers).To(HaveLen(3))
+			Expect(result.Layers[0].(native.NativeImage).DebugInfo).To(Equal("none"))
+		})
+	})
+
+	context("BP_BINARY_COMPRESSION_LEVEL", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "META-INF", "MANIFEST.MF"), []byte(`
+Start-Class: test-start-class
+`), 0644)).To(Succeed())
+			t.Setenv("BP_BINARY_COMPRESSION_METHOD", "upx")
+		})
+
+		it("configures the compression level and lzma", func() {
+			t.Setenv("BP_BINARY_COMPRESSION_LEVEL", "best")
+			t.Setenv("BP_BINARY_COMPRESSION_LZMA", "true")
+
+			result, err := build.Build(ctx)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Layers[0].(native.NativeImage).Compressor).To(Equal("upx"))
+			Expect(result.Layers[0].(native.NativeImage).CompressionLevel).To(Equal("best"))
+			Expect(result.Layers[0].(native.NativeImage).CompressionLZMA).To(BeTrue())
+		})
+
+		it("ignores an invalid compression level", func() {
+			t.Setenv("BP_BINARY_COMPRESSION_LEVEL", "11")
+
+			result, err := build.Build(ctx)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Layers[0].(native.NativeImage).CompressionLevel).To(BeEmpty())
+			Expect(out.String()).To(ContainSubstring("Requested compression level [11] is unknown"))
+		})
+	})
+
+	context("compression compatibility", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "META-INF", "MANIFEST.MF"), []byte(`
+Start-Class: test-start-class
+`), 0644)).To(Succeed())
+		})
+
+		it("rejects gzexe on a tiny stack", func() {
+			t.Setenv("BP_BINARY_COMPRESSION_METHOD", "gzexe")
+			ctx.StackID = libpak.JammyTinyStackID
+
+			_, err := build.Build(ctx)
+			Expect(err).To(MatchError(ContainSubstring("compression method gzexe is not compatible with the tiny run image")))
+		})
+
+		it("does not warn about upx on amd64", func() {
+			t.Setenv("BP_BINARY_COMPRESSION_METHOD", "upx")
+			t.Setenv("CNB_TARGET_ARCH", "amd64")
+
+			_, err := build.Build(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(out.String()).NotTo(ContainSubstring("upx"))
+		})
+	})
+
+	context("BP_NATIVE_IMAGE_CACHE_DIR", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "META-INF", "MANIFEST.MF"), []byte(`
+Start-Class: test-start-class
+`), 0644)).To(Succeed())
+		})
+
+		it("configures the build cache", func() {
+			cacheDir := t.TempDir()
+			t.Setenv("BP_NATIVE_IMAGE_CACHE_DIR", cacheDir)
+			t.Setenv("BP_NATIVE_IMAGE_CACHE_SIZE", "1G")
+
+			result, err := build.Build(ctx)
+			Expect(err).NotTo(HaveOccurred())
+
+			buildCache := result.Layers[0].(native.NativeImage).BuildCache
+			Expect(buildCache.Path).To(Equal(cacheDir))
+			Expect(buildCache.MaxSize).To(Equal(int64(1024 * 1024 * 1024)))
+		})
+
+		it("defaults the cache size", func() {
+			t.Setenv("BP_NATIVE_IMAGE_CACHE_DIR", t.TempDir())
+
+			result, err := build.Build(ctx)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Layers[0].(native.NativeImage).BuildCache.MaxSize).To(Equal(int64(5 * 1024 * 1024 * 1024)))
+		})
+
+		it("warns if the directory does not exist", func() {
+			t.Setenv("BP_NATIVE_IMAGE_CACHE_DIR", filepath.Join(t.TempDir(), "missing"))
+
+			result, err := build.Build(ctx)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Layers[0].(native.NativeImage).BuildCache.Enabled()).To(BeFalse())
+			Expect(out.String()).To(ContainSubstring("does not exist, no native images will be shared between builds"))
+		})
+	})
+
+	context("BP_NATIVE_IMAGE_LAYERED", func() {
+		it.Before(func() {
+			t.Setenv("BP_NATIVE_IMAGE_LAYERED", "true")
+		})
+
+		it("contributes a native-image-dependencies layer", func() {
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "META-INF", "MANIFEST.MF"), []byte(`
+Start-Class: test-start-class
+Class-Path: lib/dependency.jar
+`), 0644)).To(Succeed())
+			Expect(os.MkdirAll(filepath.Join(ctx.Application.Path, "lib"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "lib", "dependency.jar"), []byte{}, 0644)).To(Succeed())
+
+			result, err := build.Build(ctx)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Layers).To(HaveLen(4))
+			Expect(result.Layers[0].(native.NativeImageDependencies).ClassPath).
+				To(Equal(ctx.Application.Path + string(filepath.ListSeparator) + filepath.Join(ctx.Application.Path, "lib", "dependency.jar")))
+			Expect(result.Layers[1].(native.NativeImage).DependenciesLayerPath).
+				To(Equal(filepath.Join(ctx.Layers.Path, "native-image-dependencies")))
+		})
+
+		it("configures the dependencies layer with the arguments file", func() {
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "META-INF", "MANIFEST.MF"), []byte(`
+Start-Class: test-start-class
+Class-Path: lib/dependency.jar
+`), 0644)).To(Succeed())
+			Expect(os.MkdirAll(filepath.Join(ctx.Application.Path, "lib"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "lib", "dependency.jar"), []byte{}, 0644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "native-image.args"), []byte("--enable-preview"), 0644)).To(Succeed())
+			t.Setenv("BP_NATIVE_IMAGE_BUILD_ARGUMENTS_FILE", filepath.Join(ctx.Application.Path, "native-image.args"))
+
+			result, err := build.Build(ctx)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Layers[0].(native.NativeImageDependencies).ArgumentsFile).
+				To(Equal(filepath.Join(ctx.Application.Path, "native-image.args")))
+		})
+
+		it("builds a single native image from a JAR", func() {
+			Expect(os.RemoveAll(filepath.Join(ctx.Application.Path, "META-INF"))).To(Succeed())
+			fp, err := os.Open("testdata/test-fixture.jar")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sherpa.CopyFile(fp, filepath.Join(ctx.Application.Path, "test-fixture.jar"))).To(Succeed())
+			t.Setenv("BP_NATIVE_IMAGE_BUILT_ARTIFACT", "*.jar")
+
+			result, err := build.Build(ctx)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Layers).To(HaveLen(3))
+			Expect(result.Layers[0].(native.NativeImage).DependenciesLayerPath).To(BeEmpty())
+			Expect(out.String()).To(ContainSubstring("Layered native images require an exploded JAR"))
+		})
+
+		it("builds a single native image when statically linked", func() {
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "META-INF", "MANIFEST.MF"), []byte(`
+Start-Class: test-start-class
+`), 0644)).To(Succeed())
+			t.Setenv("BP_NATIVE_IMAGE_BUILD_ARGUMENTS", "--static")
+
+			result, err := build.Build(ctx)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Layers).To(HaveLen(3))
+			Expect(out.String()).To(ContainSubstring("Layered native images cannot be statically linked"))
+		})
+	})
+
+	context("build report", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "META-INF", "MANIFEST.MF"), []byte(`
+Start-Class: test-start-class
+`), 0644)).To(Succeed())
+		})
+
+		it("contributes a native-image-report layer", func() {
+			result, err := build.Build(ctx)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Layers[1].Name()).To(Equal("native-image-report"))
+			report := result.Layers[1].(native.NativeImageReport)
+			Expect(report.NativeImageLayerPath).To(Equal(filepath.Join(ctx.Layers.Path, "native-image")))
+		})
+	})
+
+	context("BP_NATIVE_IMAGE_MAX_SIZE", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "META-INF", "MANIFEST.MF"), []byte(`
+Start-Class: test-start-class
+`), 0644)).To(Succeed())
+		})
+
+		it("configures the size budget", func() {
+			t.Setenv("BP_NATIVE_IMAGE_MAX_SIZE", "100M")
+			t.Setenv("BP_NATIVE_IMAGE_WARN_SIZE", "80M")
+
+			result, err := build.Build(ctx)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Layers[0].(native.NativeImage).MaxSize).To(Equal(int64(100 * 1024 * 1024)))
+			Expect(result.Layers[0].(native.NativeImage).WarnSize).To(Equal(int64(80 * 1024 * 1024)))
+		})
+
+		it("rejects an invalid size", func() {
+			t.Setenv("BP_NATIVE_IMAGE_MAX_SIZE", "large")
+
+			_, err := build.Build(ctx)
+			Expect(err).To(MatchError(ContainSubstring("unable to parse $BP_NATIVE_IMAGE_MAX_SIZE")))
+		})
+	})
+
+	context("BP_NATIVE_IMAGE_SMOKE_TEST", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "META-INF", "MANIFEST.MF"), []byte(`
+Start-Class: test-start-class
+`), 0644)).To(Succeed())
+			t.Setenv("BP_NATIVE_IMAGE_SMOKE_TEST", "{{.Executable}} --version")
+		})
+
+		it("configures the smoke test", func() {
+			result, err := build.Build(ctx)
+			Expect(err).NotTo(HaveOccurred())
+
+			smokeTest := result.Layers[0].(native.NativeImage).SmokeTest
+			Expect(smokeTest.Command).To(Equal("{{.Executable}} --version"))
+			Expect(smokeTest.ExitCode).To(Equal(0))
+			Expect(smokeTest.Output).To(BeNil())
+			Expect(smokeTest.Timeout).To(Equal(30 * time.Second))
+		})
+
+		it("only checks the output if no exit code is set", func() {
+			t.Setenv("BP_NATIVE_IMAGE_SMOKE_TEST_OUTPUT", "^1\\.")
+			t.Setenv("BP_NATIVE_IMAGE_SMOKE_TEST_TIMEOUT", "2m")
+
+			result, err := build.Build(ctx)
+			Expect(err).NotTo(HaveOccurred())
+
+			smokeTest := result.Layers[0].(native.NativeImage).SmokeTest
+			Expect(smokeTest.ExitCode).To(Equal(native.AnyExitCode))
+			Expect(smokeTest.Output.String()).To(Equal("^1\\."))
+			Expect(smokeTest.Timeout).To(Equal(2 * time.Minute))
+		})
+
+		it("checks the exit code if it is set", func() {
+			t.Setenv("BP_NATIVE_IMAGE_SMOKE_TEST_OUTPUT", "Started")
+			t.Setenv("BP_NATIVE_IMAGE_SMOKE_TEST_EXIT_CODE", "3")
+
+			result, err := build.Build(ctx)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Layers[0].(native.NativeImage).SmokeTest.ExitCode).To(Equal(3))
+		})
+
+		it("rejects an invalid timeout", func() {
+			t.Setenv("BP_NATIVE_IMAGE_SMOKE_TEST_TIMEOUT", "soon")
+
+			_, err := build.Build(ctx)
+			Expect(err).To(MatchError(ContainSubstring("unable to parse $BP_NATIVE_IMAGE_SMOKE_TEST_TIMEOUT")))
+		})
+	})
+
+	context("BP_NATIVE_IMAGE_VERIFY_LIBRARIES", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "META-INF", "MANIFEST.MF"), []byte(`
+Start-Class: test-start-class
+`), 0644)).To(Succeed())
+		})
+
+		it("warns about missing libraries by default", func() {
+			ctx.StackID = libpak.JammyTinyStackID
+
+			result, err := build.Build(ctx)
+			Expect(err).NotTo(HaveOccurred())
+
+			verifier := result.Layers[0].(native.NativeImage).LibraryVerifier
+			Expect(verifier.Mode).To(Equal("warn"))
+			Expect(verifier.Allowed).To(Equal(native.DefaultLibraryAllowlist["tiny"]))
+			Expect(verifier.GLIBCVersion).To(Equal("2.35"))
+		})
+
+		it("configures the allowlist and glibc version", func() {
+			t.Setenv("BP_NATIVE_IMAGE_VERIFY_LIBRARIES", "fail")
+			t.Setenv("BP_NATIVE_IMAGE_ALLOWED_LIBRARIES", "libz.so.1, libfreetype.so.*")
+			t.Setenv("BP_NATIVE_IMAGE_GLIBC_VERSION", "2.31")
+
+			result, err := build.Build(ctx)
+			Expect(err).NotTo(HaveOccurred())
+
+			verifier := result.Layers[0].(native.NativeImage).LibraryVerifier
+			Expect(verifier.Mode).To(Equal("fail"))
+			Expect(verifier.Allowed).To(ContainElements("libc.so.6", "libz.so.1", "libfreetype.so.*"))
+			Expect(verifier.GLIBCVersion).To(Equal("2.31"))
+		})
+	})
+
+	context("CNB targets", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "META-INF", "MANIFEST.MF"), []byte(`
+Start-Class: test-start-class
+`), 0644)).To(Succeed())
+		})
+
+		it("builds for the target", func() {
+			t.Setenv("CNB_TARGET_OS", "linux")
+			t.Setenv("CNB_TARGET_ARCH", "arm64")
+
+			result, err := build.Build(ctx)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Layers[0].(native.NativeImage).Target).To(Equal(native.Target{OS: "linux", Arch: "arm64"}))
+			Expect(out.String()).To(ContainSubstring("Building for the base run image on arm64, from target linux/arm64"))
+		})
+
+		it("falls back to the stack", func() {
+			ctx.StackID = libpak.JammyTinyStackID
+
+			_, err := build.Build(ctx)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(out.String()).To(ContainSubstring("from stack io.buildpacks.stacks.jammy.tiny"))
+		})
+
+		it("rejects other operating systems", func() {
+			t.Setenv("CNB_TARGET_OS", "windows")
+
+			_, err := build.Build(ctx)
+			Expect(err).To(MatchError("native images can only be built for linux, not windows"))
+		})
+	})
+
+	context("BP_NATIVE_IMAGE_MARCH", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "META-INF", "MANIFEST.MF"), []byte(`
+Start-Class: test-start-class
+`), 0644)).To(Succeed())
+			ctx.Buildpack.Path = "/buildpack"
+			t.Setenv("CNB_TARGET_OS", "linux")
+			t.Setenv("CNB_TARGET_ARCH", "amd64")
+		})
+
+		it("builds a single variant", func() {
+			t.Setenv("BP_NATIVE_IMAGE_MARCH", "x86-64-v3")
+
+			result, err := build.Build(ctx)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Layers).To(HaveLen(3))
+			Expect(result.Layers[0].(native.NativeImage).Variants).To(Equal([]string{"x86-64-v3"}))
+			Expect(result.Processes).To(ContainElement(
+				libcnb.Process{Type: "web", Command: "./test-start-class", Direct: true, Default: true},
+			))
+		})
+
+		it("warns about native", func() {
+			t.Setenv("BP_NATIVE_IMAGE_MARCH", "native")
+
+			_, err := build.Build(ctx)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(out.String()).To(ContainSubstring("may not start on other CPUs"))
+		})
+
+		context("multiple variants", func() {
+			it.Before(func() {
+				t.Setenv("BP_NATIVE_IMAGE_MARCH", "x86-64-v3, compatibility")
+			})
+
+			it("starts the variants through the launcher", func() {
+				result, err := build.Build(ctx)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Layers).To(HaveLen(4))
+				Expect(result.Layers[0].(native.NativeImage).Variants).To(Equal([]string{"x86-64-v3", "compatibility"}))
+				Expect(result.Layers[1].(native.NativeImageReport).NativeImageLayerPath).To(Equal(filepath.Join(ctx.Layers.Path, "native-image", "x86-64-v3")))
+				Expect(result.Layers[2].Name()).To(Equal("native-image-launcher"))
+				Expect(result.Layers[2].(native.Launcher).BuildpackPath).To(Equal("/buildpack"))
+
+				launcher := filepath.Join(ctx.Layers.Path, "native-image-launcher", "bin", "launcher")
+				arguments := []string{ctx.Application.Path, "test-start-class", "x86-64-v3", "compatibility", "--"}
+				Expect(result.Processes).To(ContainElements(
+					libcnb.Process{Type: "native-image", Command: launcher, Arguments: arguments, Direct: true},
+					libcnb.Process{Type: "task", Command: launcher, Arguments: arguments, Direct: true},
+					libcnb.Process{Type: "web", Command: launcher, Arguments: arguments, Direct: true, Default: true},
+				))
+			})
+
+			it("embeds split debug info", func() {
+				t.Setenv("BP_NATIVE_IMAGE_DEBUG_INFO", "split")
+
+				result, err := build.Build(ctx)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Layers[0].(native.NativeImage).DebugInfo).To(Equal(native.DebugInfoEmbedded))
+				Expect(out.String()).To(ContainSubstring("Split debug info is not supported for multiple variants"))
+			})
+
+			it("warns if no variant runs on every CPU", func() {
+				t.Setenv("BP_NATIVE_IMAGE_MARCH", "x86-64-v4,x86-64-v3")
+
+				_, err := build.Build(ctx)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(out.String()).To(ContainSubstring("add compatibility as the last one to start on any CPU"))
+			})
+
+			it("rejects microarchitectures of other architectures", func() {
+				t.Setenv("BP_NATIVE_IMAGE_MARCH", "armv8.1-a,compatibility")
+
+				_, err := build.Build(ctx)
+				Expect(err).To(MatchError(ContainSubstring("unable to select armv8.1-a at launch, supported microarchitectures on amd64 are compatibility, x86-64, x86-64-v1, x86-64-v2, x86-64-v3, x86-64-v4")))
+			})
+		})
+	})
+
+	context("BP_NATIVE_IMAGE_GC", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "META-INF", "MANIFEST.MF"), []byte(`
+Start-Class: test-start-class
+`), 0644)).To(Succeed())
+			ctx.Buildpack.Path = "/buildpack"
+		})
+
+		it("starts the native image through the launcher", func() {
+			t.Setenv("BP_NATIVE_IMAGE_GC", "g1")
+
+			result, err := build.Build(ctx)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Layers).To(HaveLen(4))
+			Expect(result.Layers[0].(native.NativeImage).GC).To(Equal("G1"))
+			Expect(result.Layers[2].Name()).To(Equal("native-image-launcher"))
+			Expect(result.Processes).To(ContainElement(libcnb.Process{
+				Type:      "web",
+				Command:   filepath.Join(ctx.Layers.Path, "native-image-launcher", "bin", "launcher"),
+				Arguments: []string{ctx.Application.Path, "test-start-class", "--"},
+				Direct:    true,
+				Default:   true,
+			}))
+		})
+
+		it("starts the native image directly with the default garbage collector", func() {
+			result, err := build.Build(ctx)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Layers).To(HaveLen(3))
+			Expect(result.Processes).To(ContainElement(
+				libcnb.Process{Type: "web", Command: "./test-start-class", Direct: true, Default: true},
+			))
+		})
+
+		it("starts the native image through the launcher if requested", func() {
+			t.Setenv("BP_NATIVE_IMAGE_LAUNCHER", "true")
+
+			result, err := build.Build(ctx)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Layers).To(HaveLen(4))
+			Expect(result.Layers[2].Name()).To(Equal("native-image-launcher"))
+			Expect(result.Processes).To(ContainElement(libcnb.Process{
+				Type:      "web",
+				Command:   filepath.Join(ctx.Layers.Path, "native-image-launcher", "bin", "launcher"),
+				Arguments: []string{ctx.Application.Path, "test-start-class", "--"},
+				Direct:    true,
+				Default:   true,
+			}))
+		})
+
+		it("starts a single variant from the application", func() {
+			t.Setenv("CNB_TARGET_OS", "linux")
+			t.Setenv("CNB_TARGET_ARCH", "amd64")
+			t.Setenv("BP_NATIVE_IMAGE_MARCH", "x86-64-v3")
+			t.Setenv("BP_NATIVE_IMAGE_GC", "G1")
+
+			result, err := build.Build(ctx)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Processes).To(ContainElement(libcnb.Process{
+				Type:      "web",
+				Command:   filepath.Join(ctx.Layers.Path, "native-image-launcher", "bin", "launcher"),
+				Arguments: []string{ctx.Application.Path, "test-start-class", "--"},
+				Direct:    true,
+				Default:   true,
+			}))
+		})
+
+		it("ignores an unknown garbage collector", func() {
+			t.Setenv("BP_NATIVE_IMAGE_GC", "parallel")
+
+			result, err := build.Build(ctx)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Layers).To(HaveLen(3))
+			Expect(result.Layers[0].(native.NativeImage).GC).To(BeEmpty())
+			Expect(out.String()).To(ContainSubstring("Requested garbage collector [parallel] is unknown"))
+		})
+	})
+
+	context("native-image-application plan entries", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "META-INF", "MANIFEST.MF"), []byte(`
+Start-Class: test-start-class
+`), 0644)).To(Succeed())
+			ctx.Plan.Entries = append(ctx.Plan.Entries, libcnb.BuildpackPlanEntry{
+				Name: "native-image-application",
+				Metadata: map[string]interface{}{
+					"args":               []interface{}{"--static"},
+					"config-directories": "META-INF/native-image",
+					"main-class":         "org.example.Main",
+					"executable-name":    "example",
+				},
+			})
+		})
+
+		it.After(func() {
+			ctx.Plan.Entries = nil
+		})
+
+		it("configures the native image with the metadata", func() {
+			result, err := build.Build(ctx)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Layers[0].(native.NativeImage).PlanMetadata).To(Equal(native.PlanMetadata{
+				Arguments:         []string{"--static"},
+				ConfigDirectories: []string{filepath.Join(ctx.Application.Path, "META-INF", "native-image")},
+				MainClass:         "org.example.Main",
+				ExecutableName:    "example",
+			}))
+			Expect(result.Processes).To(ContainElement(
+				libcnb.Process{Type: "web", Command: "./example", Direct: true, Default: true},
+			))
+			Expect(out.String()).To(ContainSubstring("Configuration contributed by upstream buildpacks"))
+		})
+
+		it("determines the linking mode from the metadata", func() {
+			t.Setenv("BP_NATIVE_IMAGE_LAYERED", "true")
+
+			_, err := build.Build(ctx)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(out.String()).To(ContainSubstring("Layered native images cannot be statically linked"))
+		})
+
+		it("fails on invalid metadata", func() {
+			ctx.Plan.Entries[len(ctx.Plan.Entries)-1].Metadata["main-class"] = true
+
+			_, err := build.Build(ctx)
+			Expect(err).To(MatchError(ContainSubstring("main-class of native-image-application plan entry must be a string, not bool")))
+		})
+	})
+
+	context("native image output", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "META-INF", "MANIFEST.MF"), []byte(`
+Start-Class: test-start-class
+`), 0644)).To(Succeed())
+		})
+
+		it("describes the native image", func() {
+			t.Setenv("BP_BINARY_COMPRESSION_METHOD", "gzexe")
+
+			result, err := build.Build(ctx)
+			Expect(err).NotTo(HaveOccurred())
+
+			o := result.Layers[len(result.Layers)-1].(native.NativeImageOutput)
+			Expect(o.NativeImageLayerPath).To(Equal(filepath.Join(ctx.Layers.Path, "native-image")))
+			Expect(o.Executables).To(Equal([]string{filepath.Join(ctx.Application.Path, "test-start-class")}))
+			Expect(o.Command).To(Equal(filepath.Join(ctx.Application.Path, "test-start-class")))
+			Expect(o.Compressor).To(Equal("gzexe"))
+		})
+
+		it("describes the launcher and variants", func() {
+			t.Setenv("CNB_TARGET_OS", "linux")
+			t.Setenv("CNB_TARGET_ARCH", "amd64")
+			t.Setenv("BP_NATIVE_IMAGE_MARCH", "x86-64-v3,compatibility")
+
+			result, err := build.Build(ctx)
+			Expect(err).NotTo(HaveOccurred())
+
+			o := result.Layers[len(result.Layers)-1].(native.NativeImageOutput)
+			Expect(o.Executables).To(Equal([]string{
+				filepath.Join(ctx.Application.Path, "x86-64-v3", "test-start-class"),
+				filepath.Join(ctx.Application.Path, "compatibility", "test-start-class"),
+			}))
+			Expect(o.Command).To(Equal(filepath.Join(ctx.Layers.Path, "native-image-launcher", "bin", "launcher")))
+		})
+	})
+
+	context("Quarkus native sources", func() {
+		it.Before(func() {
+			dir := filepath.Join(ctx.Application.Path, "target", "native-sources")
+			Expect(os.MkdirAll(dir, 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(dir, "app-1.0-runner.jar"), []byte{}, 0644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(dir, "native-image.args"), []byte("-H:Name=app-1.0-runner\n-jar\napp-1.0-runner.jar\n"), 0644)).To(Succeed())
+		})
+
+		it("starts the executable named by Quarkus", func() {
+			result, err := build.Build(ctx)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Processes).To(ContainElement(
+				libcnb.Process{Type: "web", Command: "./app-1.0-runner", Direct: true, Default: true},
+			))
+		})
+	})
+
+	context("BP_NATIVE_IMAGE_MAIN_MODULE", func() {
+		it.Before(func() {
+			t.Setenv("BP_NATIVE_IMAGE_MAIN_MODULE", "org.example.app/org.example.Main")
+		})
+
+		it("builds from the module path", func() {
+			result, err := build.Build(ctx)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Layers[0].(native.NativeImage).MainModule).To(Equal("org.example.app/org.example.Main"))
+			Expect(result.Processes).To(ContainElement(
+				libcnb.Process{Type: "web", Command: "./org.example.Main", Direct: true, Default: true},
+			))
+		})
+
+		it("builds a single native image when layered", func() {
+			t.Setenv("BP_NATIVE_IMAGE_LAYERED", "true")
+
+			result, err := build.Build(ctx)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Layers[0]).To(BeAssignableToTypeOf(native.NativeImage{}))
+			Expect(out.String()).To(ContainSubstring("Layered native images require a class path"))
+		})
+	})
+
+	context("native-image binding", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "META-INF", "MANIFEST.MF"), []byte(`
+Main-Class: test-main-class
+`), 0644)).To(Succeed())
+
+			ctx.Platform.Bindings = libcnb.Bindings{
+				{Name: "org-defaults", Type: "native-image", Path: t.TempDir()},
+			}
+		})
+
+		it.After(func() {
+			ctx.Platform.Bindings = nil
+		})
+
+		it("copies the binding into a layer the native image is built with", func() {
+			result, err := build.Build(ctx)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Layers[0]).To(BeAssignableToTypeOf(native.NativeImageBinding{}))
+			Expect(result.Layers[0].(native.NativeImageBinding).Binding.Name).To(Equal("org-defaults"))
+			Expect(result.Layers[1].(native.NativeImage).BindingPath).To(Equal(filepath.Join(ctx.Layers.Path, "native-image-binding")))
+		})
+
+		it("rejects several bindings", func() {
+			ctx.Platform.Bindings = append(ctx.Platform.Bindings,
+				libcnb.Binding{Name: "team-defaults", Type: "native-image", Path: t.TempDir()})
+
+			_, err := build.Build(ctx)
+			Expect(err).To(MatchError(ContainSubstring("unable to resolve native-image binding")))
+		})
+	})
+
+	context("BP_NATIVE_IMAGE_POLICY", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "META-INF", "MANIFEST.MF"), []byte(`
+Main-Class: test-main-class
+`), 0644)).To(Succeed())
+			t.Setenv("BP_NATIVE_IMAGE_POLICY", "policy.toml")
+		})
+
+		it("reads the policy relative to the application", func() {
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "policy.toml"), []byte(`
+[[require]]
+pattern = "--no-fallback"
+`), 0644)).To(Succeed())
+			result, err := build.Build(ctx)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Layers[0].(native.NativeImage).Policies).To(Equal([]native.Policy{{
+				File:    filepath.Join(ctx.Application.Path, "policy.toml"),
+				Require: []native.PolicyRule{{Pattern: "--no-fallback"}},
+			}}))
+		})
+
+		it("fails if the policy does not exist", func() {
+			_, err := build.Build(ctx)
+			Expect(err).To(MatchError(ContainSubstring("unable to read policy")))
+		})
+	})
+
+	context("native-image.toml", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "META-INF", "MANIFEST.MF"), []byte(`
+Main-Class: test-main-class
+`), 0644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "native-image.toml"), []byte(`
+arguments  = ["--no-fallback", "-Dgreeting=hello world"]
+executable = "app"
+keep       = ["static/**"]
+
+[[processes]]
+type      = "worker"
+arguments = ["--worker"]
+
+[[processes]]
+type      = "web"
+arguments = ["--port", "8080"]
+
+[profiles.debug]
+arguments = ["-g"]
+`), 0644)).To(Succeed())
+		})
+
+		it("configures the native image", func() {
+			result, err := build.Build(ctx)
+			Expect(err).NotTo(HaveOccurred())
+
+			n := result.Layers[0].(native.NativeImage)
+			Expect(n.Arguments).To(Equal("--no-fallback '-Dgreeting=hello world'"))
+			Expect(n.Keep).To(Equal([]string{"static/**"}))
+			Expect(result.Processes).To(ConsistOf(
+				libcnb.Process{Type: "native-image", Command: "./app", Direct: true},
+				libcnb.Process{Type: "task", Command: "./app", Direct: true},
+				libcnb.Process{Type: "web", Command: "./app", Arguments: []string{"--port", "8080"}, Direct: true, Default: true},
+				libcnb.Process{Type: "worker", Command: "./app", Arguments: []string{"--worker"}, Direct: true},
+			))
+			Expect(out.String()).To(ContainSubstring("Configuration read from"))
+		})
+
+		it("gives precedence to the environment", func() {
+			t.Setenv("BP_NATIVE_IMAGE_BUILD_ARGUMENTS", "--verbose")
+
+			result, err := build.Build(ctx)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Layers[0].(native.NativeImage).Arguments).To(Equal("--verbose"))
+			Expect(out.String()).To(ContainSubstring("(overridden by the environment)"))
+		})
+
+		it("merges the profile", func() {
+			t.Setenv("BP_NATIVE_IMAGE_PROFILE", "debug")
+
+			result, err := build.Build(ctx)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Layers[0].(native.NativeImage).Arguments).To(Equal("--no-fallback '-Dgreeting=hello world' -g"))
+		})
+
+		it("fails on an invalid descriptor", func() {
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "native-image.toml"), []byte(`
+argument = "--no-fallback"
+`), 0644)).To(Succeed())
+
+			_, err := build.Build(ctx)
+			Expect(err).To(MatchError(ContainSubstring("argument: unknown key")))
+		})
+	})
 }
@@ -0,0 +1,141 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package native
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// ExplainCacheMiss describes why the native-image layer with previous metadata cannot be reused for a build with
+// expected metadata. The input files are compared separately, as only their digest is recorded in the metadata.
+func ExplainCacheMiss(previous map[string]interface{}, expected map[string]interface{}, previousInputs InputDigest, inputs InputDigest, base string) []string {
+	if len(previous) == 0 {
+		return []string{"no native image was cached by a previous build"}
+	}
+
+	var reasons []string
+
+	added, removed, reordered := argumentChanges(stringSlice(previous["arguments"]), stringSlice(expected["arguments"]))
+	for _, a := range added {
+		reasons = append(reasons, fmt.Sprintf("argument `%s` added", a))
+	}
+	for _, r := range removed {
+		reasons = append(reasons, fmt.Sprintf("argument `%s` removed", r))
+	}
+	if reordered {
+		reasons = append(reasons, "arguments reordered")
+	}
+
+	if !equalValues(previous["version-hash"], expected["version-hash"]) {
+		if previous["version"] != nil && !equalValues(previous["version"], expected["version"]) {
+			reasons = append(reasons, fmt.Sprintf("native-image version changed from %v to %v", previous["version"], expected["version"]))
+		} else {
+			reasons = append(reasons, "native-image version changed")
+		}
+	}
+
+	var keys []string
+	for k := range expected {
+		if k != "arguments" && k != "inputs" && k != "version" && k != "version-hash" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if !equalValues(previous[k], expected[k]) {
+			reasons = append(reasons, fmt.Sprintf("%s changed from %s to %s", k, describeValue(previous[k]), describeValue(expected[k])))
+		}
+	}
+
+	if !equalValues(previous["inputs"], expected["inputs"]) {
+		if changes := inputs.Changes(previousInputs, base); len(changes) > 0 {
+			reasons = append(reasons, changes...)
+		} else {
+			reasons = append(reasons, "input files changed")
+		}
+	}
+
+	if len(reasons) == 0 {
+		reasons = append(reasons, "the cached native image was not restored")
+	}
+
+	return reasons
+}
+
+// argumentChanges returns the arguments added and removed between previous and expected, and whether the common
+// arguments were reordered
+func argumentChanges(previous []string, expected []string) ([]string, []string, bool) {
+	count := func(s []string) map[string]int {
+		m := map[string]int{}
+		for _, v := range s {
+			m[v]++
+		}
+		return m
+	}
+
+	p, e := count(previous), count(expected)
+
+	var added, removed, commonPrevious, commonExpected []string
+	for _, v := range expected {
+		if p[v] > 0 {
+			p[v]--
+			commonExpected = append(commonExpected, v)
+		} else {
+			added = append(added, v)
+		}
+	}
+	for _, v := range previous {
+		if e[v] > 0 {
+			e[v]--
+			commonPrevious = append(commonPrevious, v)
+		} else {
+			removed = append(removed, v)
+		}
+	}
+
+	return added, removed, !reflect.DeepEqual(commonPrevious, commonExpected)
+}
+
+// stringSlice converts a metadata value, which has been through a TOML round trip if read from a layer, to a slice
+func stringSlice(v interface{}) []string {
+	switch s := v.(type) {
+	case []string:
+		return s
+	case []interface{}:
+		var r []string
+		for _, i := range s {
+			r = append(r, fmt.Sprint(i))
+		}
+		return r
+	default:
+		return nil
+	}
+}
+
+func equalValues(a interface{}, b interface{}) bool {
+	return fmt.Sprint(a) == fmt.Sprint(b)
+}
+
+func describeValue(v interface{}) string {
+	if v == nil || v == "" {
+		return "unset"
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,105 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package native_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+
+	"github.com/paketo-buildpacks/native-image/v5/native"
+)
+
+func testCacheExplanation(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		previous map[string]interface{}
+		expected map[string]interface{}
+	)
+
+	it.Before(func() {
+		previous = map[string]interface{}{
+			"arguments":    []interface{}{"--no-fallback", "-cp", "/workspace", "test-start-class"},
+			"compression":  "none",
+			"inputs":       "inputs-1",
+			"version":      "GraalVM 21.0.1",
+			"version-hash": "hash-1",
+		}
+		expected = map[string]interface{}{
+			"arguments":    []string{"--no-fallback", "-cp", "/workspace", "test-start-class"},
+			"compression":  "none",
+			"inputs":       "inputs-1",
+			"version":      "GraalVM 21.0.1",
+			"version-hash": "hash-1",
+		}
+	})
+
+	it("explains a missing cache", func() {
+		Expect(native.ExplainCacheMiss(nil, expected, native.InputDigest{}, native.InputDigest{}, "/workspace")).
+			To(Equal([]string{"no native image was cached by a previous build"}))
+	})
+
+	it("explains an unrestored layer", func() {
+		Expect(native.ExplainCacheMiss(previous, expected, native.InputDigest{}, native.InputDigest{}, "/workspace")).
+			To(Equal([]string{"the cached native image was not restored"}))
+	})
+
+	it("explains argument changes", func() {
+		expected["arguments"] = []string{"--no-fallback", "-O3", "-cp", "/workspace", "test-start-class"}
+		Expect(native.ExplainCacheMiss(previous, expected, native.InputDigest{}, native.InputDigest{}, "/workspace")).
+			To(Equal([]string{"argument `-O3` added"}))
+
+		expected["arguments"] = []string{"-cp", "/workspace", "test-start-class"}
+		Expect(native.ExplainCacheMiss(previous, expected, native.InputDigest{}, native.InputDigest{}, "/workspace")).
+			To(Equal([]string{"argument `--no-fallback` removed"}))
+
+		expected["arguments"] = []string{"-cp", "/workspace", "--no-fallback", "test-start-class"}
+		Expect(native.ExplainCacheMiss(previous, expected, native.InputDigest{}, native.InputDigest{}, "/workspace")).
+			To(Equal([]string{"arguments reordered"}))
+	})
+
+	it("explains version changes", func() {
+		expected["version"] = "GraalVM 21.0.2"
+		expected["version-hash"] = "hash-2"
+
+		Expect(native.ExplainCacheMiss(previous, expected, native.InputDigest{}, native.InputDigest{}, "/workspace")).
+			To(Equal([]string{"native-image version changed from GraalVM 21.0.1 to GraalVM 21.0.2"}))
+	})
+
+	it("explains configuration changes", func() {
+		expected["compression"] = "upx"
+		expected["debug-info"] = "split"
+
+		Expect(native.ExplainCacheMiss(previous, expected, native.InputDigest{}, native.InputDigest{}, "/workspace")).
+			To(Equal([]string{
+				"compression changed from none to upx",
+				"debug-info changed from unset to split",
+			}))
+	})
+
+	it("explains input file changes", func() {
+		expected["inputs"] = "inputs-2"
+
+		Expect(native.ExplainCacheMiss(previous, expected,
+			native.InputDigest{Files: map[string]string{"/workspace/BOOT-INF/lib/a.jar": "1"}},
+			native.InputDigest{Files: map[string]string{"/workspace/BOOT-INF/lib/a.jar": "2"}},
+			"/workspace",
+		)).To(Equal([]string{"file `BOOT-INF/lib/a.jar` changed"}))
+	})
+}
@@ -0,0 +1,196 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package native
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+
+	"github.com/paketo-buildpacks/libpak"
+)
+
+const (
+	RunImageFlavorBase       = "base"
+	RunImageFlavorTiny       = "tiny"
+	RunImageFlavorStatic     = "static"
+	RunImageFlavorDistroless = "distroless"
+
+	LinkingDynamic      = "dynamic"
+	LinkingMostlyStatic = "mostly-static"
+	LinkingStatic       = "static"
+)
+
+// stackGLIBCVersions is the version of glibc provided by the run images of the stacks of each distribution
+var stackGLIBCVersions = map[string]string{
+	"bionic": "2.27",
+	"jammy":  "2.35",
+	"noble":  "2.39",
+}
+
+// RunImage describes the image that the native image executable will run on
+type RunImage struct {
+	Arch   string
+	Flavor string
+
+	// GLIBCVersion is the version of glibc provided by the run image, empty if it is unknown
+	GLIBCVersion string
+
+	// Source describes where the run image was derived from
+	Source string
+}
+
+// NewRunImage describes the run image of the target read from the environment, falling back to stackID
+func NewRunImage(stackID string) RunImage {
+	return NewRunImageForTarget(NewTarget(), stackID)
+}
+
+// NewRunImageForTarget describes the run image of target. Targets do not describe the flavor of the run image, so it is
+// taken from stackID. Platforms that do not provide a target are described by stackID alone.
+func NewRunImageForTarget(target Target, stackID string) RunImage {
+	r := RunImage{Arch: runtime.GOARCH, Flavor: RunImageFlavorBase, GLIBCVersion: target.GLIBCVersion()}
+
+	if target.Arch != "" {
+		r.Arch = target.Arch
+	}
+
+	if target.IsSet() {
+		r.Source = fmt.Sprintf("target %s", target)
+	} else {
+		r.Source = fmt.Sprintf("stack %s", stackID)
+	}
+
+	switch {
+	case libpak.IsStaticStack(stackID):
+		r.Flavor = RunImageFlavorStatic
+		r.Source = fmt.Sprintf("stack %s", stackID)
+	case libpak.IsTinyStack(stackID):
+		r.Flavor = RunImageFlavorTiny
+		r.Source = fmt.Sprintf("stack %s", stackID)
+	case strings.Contains(stackID, RunImageFlavorDistroless):
+		r.Flavor = RunImageFlavorDistroless
+		r.Source = fmt.Sprintf("stack %s", stackID)
+	}
+
+	if r.GLIBCVersion == "" {
+		for distro, version := range stackGLIBCVersions {
+			if strings.Contains(stackID, distro) {
+				r.GLIBCVersion = version
+			}
+		}
+	}
+
+	return r
+}
+
+// LinksLibCOnly returns whether glibc is the only shared library that the run image is known to provide, in which case
+// the native image is linked statically except for glibc
+func (r RunImage) LinksLibCOnly() bool {
+	return r.Flavor == RunImageFlavorTiny || r.Flavor == RunImageFlavorDistroless
+}
+
+func (r RunImage) String() string {
+	s := fmt.Sprintf("%s run image on %s", r.Flavor, r.Arch)
+	if r.GLIBCVersion != "" {
+		s = fmt.Sprintf("%s with glibc %s", s, r.GLIBCVersion)
+	}
+	return fmt.Sprintf("%s, from %s", s, r.Source)
+}
+
+// HasShell returns true if the run image provides /bin/sh
+func (r RunImage) HasShell() bool {
+	return r.Flavor == RunImageFlavorBase
+}
+
+// LinkingMode returns how an executable built with arguments is linked
+func LinkingMode(arguments []string) string {
+	switch {
+	case containsArg("--static", arguments) && !containsArg("--static-nolibc", arguments):
+		return LinkingStatic
+	case containsArg("--static-nolibc", arguments) || containsArg("-H:+StaticExecutableWithDynamicLibC", arguments):
+		return LinkingMostlyStatic
+	default:
+		return LinkingDynamic
+	}
+}
+
+// CompatibilityRule describes a known problem running an executable compressed with Compressor. Empty Arch, Flavor or
+// Linking fields match any value.
+type CompatibilityRule struct {
+	Compressor string
+	Arch       string
+	Flavor     string
+	Linking    string
+	Fatal      bool
+	Reason     string
+}
+
+func (c CompatibilityRule) matches(compressor string, runImage RunImage, linking string) bool {
+	return c.Compressor == compressor &&
+		(c.Arch == "" || c.Arch == runImage.Arch) &&
+		(c.Flavor == "" || c.Flavor == runImage.Flavor) &&
+		(c.Linking == "" || c.Linking == linking)
+}
+
+const gzexeShellReason = "gzexe produces a shell script that requires /bin/sh, which is not present on %s run images. Set $%s to `upx` or `none` instead."
+
+// CompressorCompatibility is the matrix of known problems with compression methods
+var CompressorCompatibility = []CompatibilityRule{
+	{
+		Compressor: CompressorGzexe,
+		Flavor:     RunImageFlavorTiny,
+		Fatal:      true,
+		Reason:     fmt.Sprintf(gzexeShellReason, RunImageFlavorTiny, BinaryCompressionMethod),
+	},
+	{
+		Compressor: CompressorGzexe,
+		Flavor:     RunImageFlavorStatic,
+		Fatal:      true,
+		Reason:     fmt.Sprintf(gzexeShellReason, RunImageFlavorStatic, BinaryCompressionMethod),
+	},
+	{
+		Compressor: CompressorGzexe,
+		Flavor:     RunImageFlavorDistroless,
+		Fatal:      true,
+		Reason:     fmt.Sprintf(gzexeShellReason, RunImageFlavorDistroless, BinaryCompressionMethod),
+	},
+	{
+		Compressor: CompressorGzexe,
+		Flavor:     RunImageFlavorBase,
+		Linking:    LinkingStatic,
+		Reason:     "gzexe produces a shell script that requires /bin/sh, so the statically linked executable cannot be moved to a run image without a shell.",
+	},
+}
+
+// CheckCompressorCompatibility checks compressor against the CompressorCompatibility matrix, returning the reasons for
+// any warnings and an error if the combination is known not to work
+func CheckCompressorCompatibility(compressor string, runImage RunImage, linking string) ([]string, error) {
+	var warnings []string
+
+	for _, rule := range CompressorCompatibility {
+		if !rule.matches(compressor, runImage, linking) {
+			continue
+		}
+
+		if rule.Fatal {
+			return nil, fmt.Errorf("compression method %s is not compatible with the %s run image\n%s", compressor, runImage.Flavor, rule.Reason)
+		}
+		warnings = append(warnings, rule.Reason)
+	}
+
+	return warnings, nil
+}
@@ -0,0 +1,116 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package native_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/paketo-buildpacks/libpak"
+	"github.com/sclevine/spec"
+
+	"github.com/paketo-buildpacks/native-image/v5/native"
+)
+
+func testCompatibility(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+	)
+
+	context("run image", func() {
+		it("describes the run image from the stack", func() {
+			t.Setenv("CNB_TARGET_ARCH", "arm64")
+
+			Expect(native.NewRunImage(libpak.JammyStackID)).To(Equal(native.RunImage{
+				Arch: "arm64", Flavor: "base", GLIBCVersion: "2.35", Source: "stack io.buildpacks.stacks.jammy",
+			}))
+			Expect(native.NewRunImage(libpak.JammyTinyStackID).Flavor).To(Equal("tiny"))
+			Expect(native.NewRunImage(libpak.NobleStaticStackID).Flavor).To(Equal("static"))
+			Expect(native.NewRunImage("io.example.stacks.distroless").Flavor).To(Equal("distroless"))
+			Expect(native.NewRunImage(libpak.NobleStaticStackID).GLIBCVersion).To(Equal("2.39"))
+			Expect(native.NewRunImage("io.example.stacks.distroless").GLIBCVersion).To(BeEmpty())
+		})
+	})
+
+	context("run image of a target", func() {
+		it("describes the run image from the target", func() {
+			r := native.NewRunImageForTarget(native.Target{OS: "linux", Arch: "arm64", DistroName: "ubuntu", DistroVersion: "24.04"}, "")
+
+			Expect(r).To(Equal(native.RunImage{
+				Arch: "arm64", Flavor: "base", GLIBCVersion: "2.39", Source: "target linux/arm64 ubuntu 24.04",
+			}))
+			Expect(r.LinksLibCOnly()).To(BeFalse())
+			Expect(r.String()).To(Equal("base run image on arm64 with glibc 2.39, from target linux/arm64 ubuntu 24.04"))
+		})
+
+		it("does not guess the flavor of a target without a distribution", func() {
+			r := native.NewRunImageForTarget(native.Target{OS: "linux", Arch: "amd64"}, "")
+
+			Expect(r.Flavor).To(Equal("base"))
+			Expect(r.LinksLibCOnly()).To(BeFalse())
+			Expect(r.Source).To(Equal("target linux/amd64"))
+		})
+
+		it("falls back to the stack for the flavor", func() {
+			r := native.NewRunImageForTarget(native.Target{OS: "linux", Arch: "amd64", DistroName: "ubuntu", DistroVersion: "22.04"}, libpak.JammyTinyStackID)
+
+			Expect(r.Flavor).To(Equal("tiny"))
+			Expect(r.GLIBCVersion).To(Equal("2.35"))
+			Expect(r.Source).To(Equal("stack io.buildpacks.stacks.jammy.tiny"))
+		})
+	})
+
+	context("linking mode", func() {
+		it("detects the linking mode from arguments", func() {
+			Expect(native.LinkingMode([]string{"-O3"})).To(Equal("dynamic"))
+			Expect(native.LinkingMode([]string{"-H:+StaticExecutableWithDynamicLibC"})).To(Equal("mostly-static"))
+			Expect(native.LinkingMode([]string{"--static-nolibc"})).To(Equal("mostly-static"))
+			Expect(native.LinkingMode([]string{"--static", "--libc=musl"})).To(Equal("static"))
+		})
+	})
+
+	context("compressor compatibility", func() {
+		for _, c := range []struct {
+			compressor string
+			runImage   native.RunImage
+			linking    string
+			warnings   int
+			fatal      bool
+		}{
+			{"none", native.RunImage{Arch: "amd64", Flavor: "tiny"}, "mostly-static", 0, false},
+			{"gzexe", native.RunImage{Arch: "arm64", Flavor: "base"}, "dynamic", 0, false},
+			{"gzexe", native.RunImage{Arch: "arm64", Flavor: "base"}, "static", 1, false},
+			{"gzexe", native.RunImage{Arch: "arm64", Flavor: "tiny"}, "mostly-static", 0, true},
+			{"gzexe", native.RunImage{Arch: "arm64", Flavor: "static"}, "static", 0, true},
+			{"gzexe", native.RunImage{Arch: "arm64", Flavor: "distroless"}, "dynamic", 0, true},
+			{"upx", native.RunImage{Arch: "arm64", Flavor: "tiny"}, "mostly-static", 0, false},
+			{"upx", native.RunImage{Arch: "amd64", Flavor: "tiny"}, "mostly-static", 0, false},
+		} {
+			c := c
+
+			it(c.compressor+" on "+c.runImage.Arch+" "+c.runImage.Flavor+" with "+c.linking+" linking", func() {
+				warnings, err := native.CheckCompressorCompatibility(c.compressor, c.runImage, c.linking)
+				if c.fatal {
+					Expect(err).To(MatchError(ContainSubstring("requires /bin/sh")))
+				} else {
+					Expect(err).NotTo(HaveOccurred())
+					Expect(warnings).To(HaveLen(c.warnings))
+				}
+			})
+		}
+	})
+}
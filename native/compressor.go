@@ -0,0 +1,188 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package native
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/paketo-buildpacks/libpak/bard"
+	"github.com/paketo-buildpacks/libpak/effect"
+)
+
+const (
+	CompressorUpx   = "upx"
+	CompressorGzexe = "gzexe"
+	CompressorNone  = "none"
+
+	CompressionLevelBest = "best"
+)
+
+// Compressor reduces the size of a native image executable
+type Compressor interface {
+	// Name returns the compression method, as configured by $BP_BINARY_COMPRESSION_METHOD
+	Name() string
+
+	// PlanEntry returns the build plan entry that provides the compression tool, or an empty string if none is required
+	PlanEntry() string
+
+	// Compress compresses the executable at path in place
+	Compress(path string) error
+
+	// Verify checks the integrity of the compressed executable at path
+	Verify(path string) error
+}
+
+// CompressorOptions are the options used to create a Compressor
+type CompressorOptions struct {
+	Executor effect.Executor
+	Level    string
+	Logger   bard.Logger
+	LZMA     bool
+}
+
+var compressors = map[string]func(CompressorOptions) Compressor{
+	CompressorNone:  func(CompressorOptions) Compressor { return NoneCompressor{} },
+	CompressorUpx:   func(o CompressorOptions) Compressor { return UpxCompressor{Options: o} },
+	CompressorGzexe: func(o CompressorOptions) Compressor { return GzexeCompressor{Options: o} },
+}
+
+// NewCompressor returns the Compressor registered for method, returning false if method is unknown
+func NewCompressor(method string, options CompressorOptions) (Compressor, bool) {
+	f, ok := compressors[method]
+	if !ok {
+		return nil, false
+	}
+	return f(options), true
+}
+
+// IsValidCompressionLevel returns true if level is empty, `best` or between 1 and 9
+func IsValidCompressionLevel(level string) bool {
+	if level == "" || level == CompressionLevelBest {
+		return true
+	}
+
+	l, err := strconv.Atoi(level)
+	return err == nil && l >= 1 && l <= 9
+}
+
+// NoneCompressor leaves the executable uncompressed
+type NoneCompressor struct{}
+
+func (NoneCompressor) Name() string {
+	return CompressorNone
+}
+
+func (NoneCompressor) PlanEntry() string {
+	return ""
+}
+
+func (NoneCompressor) Compress(string) error {
+	return nil
+}
+
+func (NoneCompressor) Verify(string) error {
+	return nil
+}
+
+// UpxCompressor compresses the executable with upx, defaulting to compression level 9
+type UpxCompressor struct {
+	Options CompressorOptions
+}
+
+func (UpxCompressor) Name() string {
+	return CompressorUpx
+}
+
+func (UpxCompressor) PlanEntry() string {
+	return PlanEntryUpx
+}
+
+func (u UpxCompressor) Compress(path string) error {
+	args := []string{"-q"}
+
+	switch u.Options.Level {
+	case "":
+		args = append(args, "-9")
+	case CompressionLevelBest:
+		args = append(args, "--best")
+	default:
+		args = append(args, fmt.Sprintf("-%s", u.Options.Level))
+	}
+
+	if u.Options.LZMA {
+		args = append(args, "--lzma")
+	}
+
+	return u.execute(append(args, path), filepath.Dir(path))
+}
+
+func (u UpxCompressor) Verify(path string) error {
+	return u.execute([]string{"-q", "-t", path}, filepath.Dir(path))
+}
+
+func (u UpxCompressor) execute(args []string, dir string) error {
+	if err := u.Options.Executor.Execute(effect.Execution{
+		Command: "upx",
+		Args:    args,
+		Dir:     dir,
+		Stdout:  u.Options.Logger.InfoWriter(),
+		Stderr:  u.Options.Logger.InfoWriter(),
+	}); err != nil {
+		return fmt.Errorf("error running upx\n%w", err)
+	}
+
+	return nil
+}
+
+// GzexeCompressor compresses the executable with gzexe, which produces a shell script that extracts the executable
+type GzexeCompressor struct {
+	Options CompressorOptions
+}
+
+func (GzexeCompressor) Name() string {
+	return CompressorGzexe
+}
+
+func (GzexeCompressor) PlanEntry() string {
+	return ""
+}
+
+func (g GzexeCompressor) Compress(path string) error {
+	if err := g.Options.Executor.Execute(effect.Execution{
+		Command: "gzexe",
+		Args:    []string{path},
+		Dir:     filepath.Dir(path),
+		Stdout:  g.Options.Logger.InfoWriter(),
+		Stderr:  g.Options.Logger.InfoWriter(),
+	}); err != nil {
+		return fmt.Errorf("error running gzexe\n%w", err)
+	}
+
+	// gzexe leaves a backup of the original executable behind
+	if err := os.Remove(fmt.Sprintf("%s~", path)); err != nil {
+		return fmt.Errorf("error removing\n%w", err)
+	}
+
+	return nil
+}
+
+func (GzexeCompressor) Verify(string) error {
+	return nil
+}
@@ -0,0 +1,143 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package native_test
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/paketo-buildpacks/libpak/bard"
+	"github.com/paketo-buildpacks/libpak/effect"
+	"github.com/paketo-buildpacks/libpak/effect/mocks"
+	"github.com/sclevine/spec"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/paketo-buildpacks/native-image/v5/native"
+)
+
+func testCompressor(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		executor *mocks.Executor
+		options  native.CompressorOptions
+		path     string
+	)
+
+	it.Before(func() {
+		executor = &mocks.Executor{}
+		executor.On("Execute", mock.Anything).Return(nil)
+
+		options = native.CompressorOptions{
+			Executor: executor,
+			Logger:   bard.NewLogger(io.Discard),
+		}
+
+		path = filepath.Join(t.TempDir(), "test-executable")
+		Expect(os.WriteFile(path, []byte{}, 0755)).To(Succeed())
+	})
+
+	context("registry", func() {
+		it("returns registered compressors", func() {
+			for _, method := range []string{"none", "upx", "gzexe"} {
+				c, ok := native.NewCompressor(method, options)
+				Expect(ok).To(BeTrue())
+				Expect(c.Name()).To(Equal(method))
+			}
+		})
+
+		it("does not return unknown compressors", func() {
+			_, ok := native.NewCompressor("unknown", options)
+			Expect(ok).To(BeFalse())
+		})
+
+		it("only requires a plan entry for upx", func() {
+			c, _ := native.NewCompressor("upx", options)
+			Expect(c.PlanEntry()).To(Equal("upx"))
+
+			c, _ = native.NewCompressor("gzexe", options)
+			Expect(c.PlanEntry()).To(BeEmpty())
+
+			c, _ = native.NewCompressor("none", options)
+			Expect(c.PlanEntry()).To(BeEmpty())
+		})
+	})
+
+	context("compression level", func() {
+		it("accepts valid levels", func() {
+			for _, level := range []string{"", "1", "9", "best"} {
+				Expect(native.IsValidCompressionLevel(level)).To(BeTrue(), level)
+			}
+		})
+
+		it("rejects invalid levels", func() {
+			for _, level := range []string{"0", "10", "fast", "-1"} {
+				Expect(native.IsValidCompressionLevel(level)).To(BeFalse(), level)
+			}
+		})
+	})
+
+	context("upx", func() {
+		it("compresses with level 9 by default", func() {
+			Expect(native.UpxCompressor{Options: options}.Compress(path)).To(Succeed())
+
+			execution := executor.Calls[0].Arguments[0].(effect.Execution)
+			Expect(execution.Command).To(Equal("upx"))
+			Expect(execution.Args).To(Equal([]string{"-q", "-9", path}))
+		})
+
+		it("compresses with the configured level", func() {
+			options.Level = "5"
+			Expect(native.UpxCompressor{Options: options}.Compress(path)).To(Succeed())
+
+			execution := executor.Calls[0].Arguments[0].(effect.Execution)
+			Expect(execution.Args).To(Equal([]string{"-q", "-5", path}))
+		})
+
+		it("compresses with best and lzma", func() {
+			options.Level = "best"
+			options.LZMA = true
+			Expect(native.UpxCompressor{Options: options}.Compress(path)).To(Succeed())
+
+			execution := executor.Calls[0].Arguments[0].(effect.Execution)
+			Expect(execution.Args).To(Equal([]string{"-q", "--best", "--lzma", path}))
+		})
+
+		it("verifies the compressed executable", func() {
+			Expect(native.UpxCompressor{Options: options}.Verify(path)).To(Succeed())
+
+			execution := executor.Calls[0].Arguments[0].(effect.Execution)
+			Expect(execution.Args).To(Equal([]string{"-q", "-t", path}))
+		})
+	})
+
+	context("gzexe", func() {
+		it("compresses and removes the backup", func() {
+			Expect(os.WriteFile(path+"~", []byte{}, 0755)).To(Succeed())
+
+			Expect(native.GzexeCompressor{Options: options}.Compress(path)).To(Succeed())
+
+			execution := executor.Calls[0].Arguments[0].(effect.Execution)
+			Expect(execution.Command).To(Equal("gzexe"))
+			Expect(execution.Args).To(Equal([]string{path}))
+			Expect(path + "~").NotTo(BeAnExistingFile())
+		})
+	})
+}
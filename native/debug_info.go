@@ -0,0 +1,117 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package native
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/buildpacks/libcnb"
+	"github.com/paketo-buildpacks/libpak"
+	"github.com/paketo-buildpacks/libpak/bard"
+	"github.com/paketo-buildpacks/libpak/sherpa"
+)
+
+const (
+	DebugInfoNone     = "none"
+	DebugInfoSplit    = "split"
+	DebugInfoEmbedded = "embedded"
+
+	debugSourcesDir = "sources"
+)
+
+// DebugInfo moves the debug files split from the native image into their own layer
+type DebugInfo struct {
+	Launch               bool
+	Logger               bard.Logger
+	NativeImageLayerPath string
+}
+
+// NewDebugInfo creates a new instance, exposing the layer at launch if requested and only caching it otherwise
+func NewDebugInfo(nativeImageLayerPath string, launch bool) DebugInfo {
+	return DebugInfo{
+		Launch:               launch,
+		NativeImageLayerPath: nativeImageLayerPath,
+	}
+}
+
+func (d DebugInfo) Contribute(layer libcnb.Layer) (libcnb.Layer, error) {
+	files, err := debugFiles(d.NativeImageLayerPath)
+	if err != nil {
+		return libcnb.Layer{}, fmt.Errorf("unable to find debug files\n%w", err)
+	}
+
+	hash, err := sherpa.NewFileListingHash(files...)
+	if err != nil {
+		return libcnb.Layer{}, fmt.Errorf("unable to create file listing for debug files\n%w", err)
+	}
+
+	contributor := libpak.NewLayerContributor("Debug Info", map[string]interface{}{
+		"files": hash,
+	}, libcnb.LayerTypes{
+		Cache:  !d.Launch,
+		Launch: d.Launch,
+	})
+	contributor.Logger = d.Logger
+
+	layer, err = contributor.Contribute(layer, func() (libcnb.Layer, error) {
+		for _, file := range files {
+			dst := filepath.Join(layer.Path, filepath.Base(file))
+			d.Logger.Bodyf("Copying %s to %s", filepath.Base(file), layer.Path)
+
+			if filepath.Base(file) == debugSourcesDir {
+				err = sherpa.CopyDir(file, dst)
+			} else {
+				err = copyFile(file, dst)
+			}
+			if err != nil {
+				return libcnb.Layer{}, fmt.Errorf("unable to copy %s to %s\n%w", file, dst, err)
+			}
+		}
+
+		return layer, nil
+	})
+	if err != nil {
+		return libcnb.Layer{}, fmt.Errorf("unable to contribute debug-info layer\n%w", err)
+	}
+
+	return layer, nil
+}
+
+func (DebugInfo) Name() string {
+	return "debug-info"
+}
+
+// debugFiles lists the `*.debug` files and the `sources/` cache written by native-image to path
+func debugFiles(path string) ([]string, error) {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list files in %s\n%w", path, err)
+	}
+
+	var files []string
+	for _, entry := range entries {
+		if (entry.Type().IsRegular() && strings.HasSuffix(entry.Name(), ".debug")) ||
+			(entry.IsDir() && entry.Name() == debugSourcesDir) {
+			files = append(files, filepath.Join(path, entry.Name()))
+		}
+	}
+
+	return files, nil
+}
@@ -0,0 +1,82 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package native_test
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/buildpacks/libcnb"
+	. "github.com/onsi/gomega"
+	"github.com/paketo-buildpacks/libpak/bard"
+	"github.com/sclevine/spec"
+
+	"github.com/paketo-buildpacks/native-image/v5/native"
+)
+
+func testDebugInfo(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		ctx                  libcnb.BuildContext
+		nativeImageLayerPath string
+	)
+
+	it.Before(func() {
+		ctx.Layers.Path = t.TempDir()
+		nativeImageLayerPath = filepath.Join(ctx.Layers.Path, "native-image")
+
+		Expect(os.MkdirAll(filepath.Join(nativeImageLayerPath, "sources", "test"), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(nativeImageLayerPath, "sources", "test", "Test.java"), []byte{}, 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(nativeImageLayerPath, "test-start-class"), []byte{}, 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(nativeImageLayerPath, "test-start-class.debug"), []byte{}, 0644)).To(Succeed())
+	})
+
+	it("contributes debug files to a cache layer", func() {
+		d := native.NewDebugInfo(nativeImageLayerPath, false)
+		d.Logger = bard.NewLogger(io.Discard)
+
+		layer, err := ctx.Layers.Layer("debug-info")
+		Expect(err).NotTo(HaveOccurred())
+
+		layer, err = d.Contribute(layer)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(layer.Cache).To(BeTrue())
+		Expect(layer.Launch).To(BeFalse())
+		Expect(filepath.Join(layer.Path, "test-start-class.debug")).To(BeARegularFile())
+		Expect(filepath.Join(layer.Path, "sources", "test", "Test.java")).To(BeARegularFile())
+		Expect(filepath.Join(layer.Path, "test-start-class")).NotTo(BeAnExistingFile())
+	})
+
+	it("contributes debug files to a launch layer", func() {
+		d := native.NewDebugInfo(nativeImageLayerPath, true)
+		d.Logger = bard.NewLogger(io.Discard)
+
+		layer, err := ctx.Layers.Layer("debug-info")
+		Expect(err).NotTo(HaveOccurred())
+
+		layer, err = d.Contribute(layer)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(layer.Cache).To(BeFalse())
+		Expect(layer.Launch).To(BeTrue())
+		Expect(filepath.Join(layer.Path, "test-start-class.debug")).To(BeARegularFile())
+	})
+}
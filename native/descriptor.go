@@ -0,0 +1,328 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package native
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/paketo-buildpacks/libpak"
+)
+
+const (
+	// DescriptorFile configures the buildpack from the application
+	DescriptorFile = "native-image.toml"
+
+	// ProjectDescriptorFile is the project descriptor, whose DescriptorTable configures the buildpack unless the
+	// application has a DescriptorFile
+	ProjectDescriptorFile = "project.toml"
+	DescriptorTable       = "io.buildpacks.native-image"
+)
+
+// Descriptor is the configuration of the buildpack in the application. Values of the environment take precedence.
+type Descriptor struct {
+	// Arguments, ArgumentsFile and BuiltArtifact are $BP_NATIVE_IMAGE_BUILD_ARGUMENTS,
+	// $BP_NATIVE_IMAGE_BUILD_ARGUMENTS_FILE and $BP_NATIVE_IMAGE_BUILT_ARTIFACT
+	Arguments     []string `toml:"arguments"`
+	ArgumentsFile string   `toml:"arguments-file"`
+	BuiltArtifact string   `toml:"built-artifact"`
+
+	// Executable is the name of the executable, which takes precedence over that of upstream buildpacks
+	Executable string `toml:"executable"`
+
+	// Processes are process types added to, or replacing, those starting the executable
+	Processes []DescriptorProcess `toml:"processes"`
+
+	Compression DescriptorCompression `toml:"compression"`
+
+	// Keep are patterns of files in the application that are kept when the bytecode is removed
+	Keep []string `toml:"keep"`
+
+	// Profiles are selected with $BP_NATIVE_IMAGE_PROFILE and merged into the descriptor
+	Profiles map[string]Descriptor `toml:"profiles"`
+}
+
+// DescriptorProcess is a process type starting the executable with Arguments
+type DescriptorProcess struct {
+	Type      string   `toml:"type"`
+	Arguments []string `toml:"arguments"`
+	Default   bool     `toml:"default"`
+}
+
+// DescriptorCompression is $BP_BINARY_COMPRESSION_METHOD, $BP_BINARY_COMPRESSION_LEVEL and $BP_BINARY_COMPRESSION_LZMA
+type DescriptorCompression struct {
+	Method string `toml:"method"`
+	Level  string `toml:"level"`
+	LZMA   *bool  `toml:"lzma"`
+}
+
+// ReadDescriptor reads the descriptor of the application at appPath from its DescriptorFile or the DescriptorTable
+// of its ProjectDescriptorFile, and merges the profile into it. It returns the file read, which is empty if the
+// application has no descriptor.
+func ReadDescriptor(appPath string, profile string) (Descriptor, string, error) {
+	var d Descriptor
+
+	file := filepath.Join(appPath, DescriptorFile)
+	md, err := toml.DecodeFile(file, &d)
+	if os.IsNotExist(err) {
+		file = filepath.Join(appPath, ProjectDescriptorFile)
+
+		var project struct {
+			IO struct {
+				Buildpacks struct {
+					NativeImage toml.Primitive `toml:"native-image"`
+				} `toml:"buildpacks"`
+			} `toml:"io"`
+		}
+		md, err = toml.DecodeFile(file, &project)
+		if os.IsNotExist(err) {
+			return Descriptor{}, "", nil
+		} else if err != nil {
+			return Descriptor{}, "", fmt.Errorf("unable to decode %s\n%w", file, err)
+		}
+
+		if !md.IsDefined("io", "buildpacks", "native-image") {
+			return Descriptor{}, "", nil
+		}
+		if err := md.PrimitiveDecode(project.IO.Buildpacks.NativeImage, &d); err != nil {
+			return Descriptor{}, "", fmt.Errorf("unable to decode [%s] of %s\n%w", DescriptorTable, file, err)
+		}
+	} else if err != nil {
+		return Descriptor{}, "", fmt.Errorf("unable to decode %s\n%w", file, err)
+	}
+
+	var problems []string
+	for _, key := range md.Undecoded() {
+		k := key.String()
+		if filepath.Base(file) == ProjectDescriptorFile {
+			if !strings.HasPrefix(k, DescriptorTable+".") {
+				continue
+			}
+			k = strings.TrimPrefix(k, DescriptorTable+".")
+		}
+		problems = append(problems, fmt.Sprintf("%s: unknown key", k))
+	}
+	problems = append(problems, d.validate("")...)
+
+	if len(problems) > 0 {
+		return Descriptor{}, "", fmt.Errorf("invalid %s\n%s", file, strings.Join(problems, "\n"))
+	}
+
+	if profile != "" {
+		p, ok := d.Profiles[profile]
+		if !ok {
+			var names []string
+			for name := range d.Profiles {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return Descriptor{}, "", fmt.Errorf("unable to find profile %s in %s, available profiles: %s", profile, file, names)
+		}
+		d = d.merge(p)
+	}
+	d.Profiles = nil
+
+	return d, file, nil
+}
+
+// validate returns the problems of the fields of the descriptor, prefixed with the key of its table
+func (d Descriptor) validate(table string) []string {
+	var problems []string
+
+	if d.Compression.Method != "" && d.Compression.Method != CompressorNone &&
+		d.Compression.Method != CompressorUpx && d.Compression.Method != CompressorGzexe {
+		problems = append(problems, fmt.Sprintf("%scompression.method: must be %s, %s or %s, not %s",
+			table, CompressorNone, CompressorUpx, CompressorGzexe, d.Compression.Method))
+	}
+
+	if l := d.Compression.Level; l != "" && l != "best" {
+		if i, err := strconv.Atoi(l); err != nil || i < 1 || i > 9 {
+			problems = append(problems, fmt.Sprintf("%scompression.level: must be 1 to 9 or best, not %s", table, l))
+		}
+	}
+
+	if strings.ContainsRune(d.Executable, filepath.Separator) {
+		problems = append(problems, fmt.Sprintf("%sexecutable: must be a file name, not %s", table, d.Executable))
+	}
+
+	types := map[string]bool{}
+	for i, p := range d.Processes {
+		if p.Type == "" {
+			problems = append(problems, fmt.Sprintf("%sprocesses[%d].type: must be set", table, i))
+		} else if types[p.Type] {
+			problems = append(problems, fmt.Sprintf("%sprocesses[%d].type: %s is declared more than once", table, i, p.Type))
+		}
+		types[p.Type] = true
+	}
+
+	for i, k := range d.Keep {
+		if _, err := filepath.Match(k, ""); err != nil {
+			problems = append(problems, fmt.Sprintf("%skeep[%d]: invalid pattern %s", table, i, k))
+		}
+	}
+
+	if table == "" {
+		var names []string
+		for name := range d.Profiles {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			p := d.Profiles[name]
+			if len(p.Profiles) > 0 {
+				problems = append(problems, fmt.Sprintf("profiles.%s.profiles: profiles cannot be nested", name))
+			}
+			problems = append(problems, p.validate(fmt.Sprintf("profiles.%s.", name))...)
+		}
+	}
+
+	return problems
+}
+
+// merge returns the descriptor with the fields set in profile. Arguments and patterns to keep are appended, and
+// processes replace those of the same type.
+func (d Descriptor) merge(profile Descriptor) Descriptor {
+	d.Arguments = append(append([]string{}, d.Arguments...), profile.Arguments...)
+	d.Keep = append(append([]string{}, d.Keep...), profile.Keep...)
+
+	if profile.ArgumentsFile != "" {
+		d.ArgumentsFile = profile.ArgumentsFile
+	}
+	if profile.BuiltArtifact != "" {
+		d.BuiltArtifact = profile.BuiltArtifact
+	}
+	if profile.Executable != "" {
+		d.Executable = profile.Executable
+	}
+	if profile.Compression.Method != "" {
+		d.Compression.Method = profile.Compression.Method
+	}
+	if profile.Compression.Level != "" {
+		d.Compression.Level = profile.Compression.Level
+	}
+	if profile.Compression.LZMA != nil {
+		d.Compression.LZMA = profile.Compression.LZMA
+	}
+
+	processes := append([]DescriptorProcess{}, d.Processes...)
+	for _, p := range profile.Processes {
+		replaced := false
+		for i := range processes {
+			if processes[i].Type == p.Type {
+				processes[i], replaced = p, true
+			}
+		}
+		if !replaced {
+			processes = append(processes, p)
+		}
+	}
+	d.Processes = processes
+
+	return d
+}
+
+// Configuration returns the descriptor as values of the configuration of the buildpack
+func (d Descriptor) Configuration() map[string]string {
+	values := map[string]string{}
+
+	if len(d.Arguments) > 0 {
+		var quoted []string
+		for _, a := range d.Arguments {
+			quoted = append(quoted, shellQuote(a))
+		}
+		values[ConfigNativeImageArgs] = strings.Join(quoted, " ")
+	}
+	if d.ArgumentsFile != "" {
+		values[ConfigNativeImageArgsFile] = d.ArgumentsFile
+	}
+	if d.BuiltArtifact != "" {
+		values[ConfigNativeImageBuiltArtifact] = d.BuiltArtifact
+	}
+	if d.Compression.Method != "" {
+		values[BinaryCompressionMethod] = d.Compression.Method
+	}
+	if d.Compression.Level != "" {
+		values[BinaryCompressionLevel] = d.Compression.Level
+	}
+	if d.Compression.LZMA != nil {
+		values[BinaryCompressionLZMA] = strconv.FormatBool(*d.Compression.LZMA)
+	}
+
+	return values
+}
+
+// shellQuote quotes s so that it is parsed as a single argument
+func shellQuote(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t\n'\"\\$`") {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// ConfigurationResolver resolves configuration from the environment, then from the descriptor of the application,
+// and then from the defaults of the buildpack
+type ConfigurationResolver struct {
+	libpak.ConfigurationResolver
+
+	// Descriptor are the values of the descriptor of the application read from DescriptorFile
+	Descriptor     map[string]string
+	DescriptorFile string
+}
+
+// Resolve returns the value of the configuration option name and whether it is set in the environment or the
+// descriptor
+func (c ConfigurationResolver) Resolve(name string) (string, bool) {
+	if v, ok := os.LookupEnv(name); ok {
+		return v, ok
+	}
+
+	if v, ok := c.Descriptor[name]; ok {
+		return v, ok
+	}
+
+	return c.ConfigurationResolver.Resolve(name)
+}
+
+// Source returns where the configuration option name is set: the environment, the descriptor or, if empty, neither
+func (c ConfigurationResolver) Source(name string) string {
+	if _, ok := os.LookupEnv(name); ok {
+		return fmt.Sprintf("$%s", name)
+	}
+
+	if _, ok := c.Descriptor[name]; ok {
+		return c.DescriptorFile
+	}
+
+	return ""
+}
+
+// ResolveBool resolves a boolean value for the configuration option name, false if it is unset or not a boolean
+func (c ConfigurationResolver) ResolveBool(name string) bool {
+	s, _ := c.Resolve(name)
+	t, err := strconv.ParseBool(s)
+	if err != nil {
+		return false
+	}
+
+	return t
+}
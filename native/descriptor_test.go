@@ -0,0 +1,291 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package native_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/paketo-buildpacks/libpak"
+	"github.com/sclevine/spec"
+
+	"github.com/paketo-buildpacks/native-image/v5/native"
+)
+
+func testDescriptor(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		appPath string
+	)
+
+	it.Before(func() {
+		appPath = t.TempDir()
+	})
+
+	write := func(name string, content string) {
+		Expect(os.WriteFile(filepath.Join(appPath, name), []byte(content), 0644)).To(Succeed())
+	}
+
+	it("returns an empty descriptor without a descriptor file", func() {
+		d, file, err := native.ReadDescriptor(appPath, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(d).To(Equal(native.Descriptor{}))
+		Expect(file).To(BeEmpty())
+	})
+
+	it("reads native-image.toml", func() {
+		write("native-image.toml", `
+arguments      = ["--no-fallback"]
+arguments-file = "native-image.args"
+built-artifact = "target/*.jar"
+executable     = "app"
+keep           = ["static/**"]
+
+[compression]
+method = "upx"
+level  = "best"
+lzma   = true
+
+[[processes]]
+type      = "worker"
+arguments = ["--worker"]
+default   = true
+`)
+
+		d, file, err := native.ReadDescriptor(appPath, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(file).To(Equal(filepath.Join(appPath, "native-image.toml")))
+
+		lzma := true
+		Expect(d).To(Equal(native.Descriptor{
+			Arguments:     []string{"--no-fallback"},
+			ArgumentsFile: "native-image.args",
+			BuiltArtifact: "target/*.jar",
+			Executable:    "app",
+			Processes:     []native.DescriptorProcess{{Type: "worker", Arguments: []string{"--worker"}, Default: true}},
+			Compression:   native.DescriptorCompression{Method: "upx", Level: "best", LZMA: &lzma},
+			Keep:          []string{"static/**"},
+		}))
+	})
+
+	it("reads the section of project.toml", func() {
+		write("project.toml", `
+[_]
+schema-version = "0.2"
+
+[io.buildpacks]
+builder = "example"
+
+[io.buildpacks.native-image]
+arguments = ["--no-fallback"]
+`)
+
+		d, file, err := native.ReadDescriptor(appPath, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(file).To(Equal(filepath.Join(appPath, "project.toml")))
+		Expect(d.Arguments).To(Equal([]string{"--no-fallback"}))
+	})
+
+	it("ignores project.toml without a section", func() {
+		write("project.toml", `
+[io.buildpacks]
+builder = "example"
+`)
+
+		_, file, err := native.ReadDescriptor(appPath, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(file).To(BeEmpty())
+	})
+
+	it("prefers native-image.toml to project.toml", func() {
+		write("native-image.toml", `executable = "native"`)
+		write("project.toml", `
+[io.buildpacks.native-image]
+executable = "project"
+`)
+
+		d, _, err := native.ReadDescriptor(appPath, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(d.Executable).To(Equal("native"))
+	})
+
+	it("rejects unknown keys", func() {
+		write("project.toml", `
+[io.buildpacks]
+builder = "example"
+
+[io.buildpacks.native-image]
+argument = "--no-fallback"
+
+[io.buildpacks.native-image.compression]
+methd = "upx"
+`)
+
+		_, _, err := native.ReadDescriptor(appPath, "")
+		Expect(err).To(MatchError(ContainSubstring("argument: unknown key")))
+		Expect(err).To(MatchError(ContainSubstring("compression.methd: unknown key")))
+		Expect(err).NotTo(MatchError(ContainSubstring("builder")))
+	})
+
+	it("reports the problems of each field", func() {
+		write("native-image.toml", `
+executable = "bin/app"
+keep       = ["[a-"]
+
+[compression]
+method = "zip"
+level  = "10"
+
+[[processes]]
+arguments = ["--worker"]
+
+[profiles.debug.compression]
+level = "0"
+`)
+
+		_, _, err := native.ReadDescriptor(appPath, "")
+		Expect(err).To(MatchError(ContainSubstring("invalid " + filepath.Join(appPath, "native-image.toml"))))
+		Expect(err).To(MatchError(ContainSubstring("compression.method: must be none, upx or gzexe, not zip")))
+		Expect(err).To(MatchError(ContainSubstring("compression.level: must be 1 to 9 or best, not 10")))
+		Expect(err).To(MatchError(ContainSubstring("executable: must be a file name, not bin/app")))
+		Expect(err).To(MatchError(ContainSubstring("processes[0].type: must be set")))
+		Expect(err).To(MatchError(ContainSubstring("keep[0]: invalid pattern [a-")))
+		Expect(err).To(MatchError(ContainSubstring("profiles.debug.compression.level: must be 1 to 9 or best, not 0")))
+	})
+
+	context("profiles", func() {
+		it.Before(func() {
+			write("native-image.toml", `
+arguments  = ["--no-fallback"]
+executable = "app"
+keep       = ["static/**"]
+
+[[processes]]
+type      = "web"
+arguments = ["--port", "8080"]
+
+[profiles.debug]
+arguments  = ["-g"]
+executable = "app-debug"
+keep       = ["*.properties"]
+
+[[profiles.debug.processes]]
+type      = "web"
+arguments = ["--debug"]
+
+[[profiles.debug.processes]]
+type = "worker"
+`)
+		})
+
+		it("merges the profile", func() {
+			d, _, err := native.ReadDescriptor(appPath, "debug")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(d).To(Equal(native.Descriptor{
+				Arguments:  []string{"--no-fallback", "-g"},
+				Executable: "app-debug",
+				Processes: []native.DescriptorProcess{
+					{Type: "web", Arguments: []string{"--debug"}},
+					{Type: "worker"},
+				},
+				Keep: []string{"static/**", "*.properties"},
+			}))
+		})
+
+		it("does not merge profiles unless selected", func() {
+			d, _, err := native.ReadDescriptor(appPath, "")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(d.Arguments).To(Equal([]string{"--no-fallback"}))
+			Expect(d.Profiles).To(BeNil())
+		})
+
+		it("rejects an unknown profile", func() {
+			_, _, err := native.ReadDescriptor(appPath, "release")
+			Expect(err).To(MatchError(ContainSubstring("unable to find profile release")))
+			Expect(err).To(MatchError(ContainSubstring("available profiles: [debug]")))
+		})
+	})
+
+	it("maps the descriptor to configuration", func() {
+		lzma := false
+		Expect(native.Descriptor{
+			Arguments:     []string{"--no-fallback", "-Dgreeting=hello world", "-Dquote='"},
+			ArgumentsFile: "native-image.args",
+			BuiltArtifact: "target/*.jar",
+			Compression:   native.DescriptorCompression{Method: "upx", Level: "9", LZMA: &lzma},
+		}.Configuration()).To(Equal(map[string]string{
+			"BP_NATIVE_IMAGE_BUILD_ARGUMENTS":      `--no-fallback '-Dgreeting=hello world' '-Dquote='\'''`,
+			"BP_NATIVE_IMAGE_BUILD_ARGUMENTS_FILE": "native-image.args",
+			"BP_NATIVE_IMAGE_BUILT_ARTIFACT":       "target/*.jar",
+			"BP_BINARY_COMPRESSION_METHOD":         "upx",
+			"BP_BINARY_COMPRESSION_LEVEL":          "9",
+			"BP_BINARY_COMPRESSION_LZMA":           "false",
+		}))
+	})
+
+	context("ConfigurationResolver", func() {
+		var cr native.ConfigurationResolver
+
+		it.Before(func() {
+			cr = native.ConfigurationResolver{
+				ConfigurationResolver: libpak.ConfigurationResolver{Configurations: []libpak.BuildpackConfiguration{
+					{Name: "BP_BINARY_COMPRESSION_METHOD", Default: "none"},
+					{Name: "BP_BINARY_COMPRESSION_LEVEL", Default: "best"},
+					{Name: "BP_BINARY_COMPRESSION_LZMA", Default: "false"},
+				}},
+				Descriptor: map[string]string{
+					"BP_BINARY_COMPRESSION_METHOD": "upx",
+					"BP_BINARY_COMPRESSION_LZMA":   "true",
+				},
+			}
+		})
+
+		it("resolves the descriptor before the defaults", func() {
+			v, ok := cr.Resolve("BP_BINARY_COMPRESSION_METHOD")
+			Expect(v).To(Equal("upx"))
+			Expect(ok).To(BeTrue())
+
+			v, ok = cr.Resolve("BP_BINARY_COMPRESSION_LEVEL")
+			Expect(v).To(Equal("best"))
+			Expect(ok).To(BeFalse())
+
+			Expect(cr.ResolveBool("BP_BINARY_COMPRESSION_LZMA")).To(BeTrue())
+		})
+
+		it("returns the source of the configuration", func() {
+			cr.DescriptorFile = "native-image.toml"
+			t.Setenv("BP_BINARY_COMPRESSION_LZMA", "false")
+
+			Expect(cr.Source("BP_BINARY_COMPRESSION_METHOD")).To(Equal("native-image.toml"))
+			Expect(cr.Source("BP_BINARY_COMPRESSION_LZMA")).To(Equal("$BP_BINARY_COMPRESSION_LZMA"))
+			Expect(cr.Source("BP_BINARY_COMPRESSION_LEVEL")).To(BeEmpty())
+		})
+
+		it("resolves the environment before the descriptor", func() {
+			t.Setenv("BP_BINARY_COMPRESSION_METHOD", "gzexe")
+
+			v, ok := cr.Resolve("BP_BINARY_COMPRESSION_METHOD")
+			Expect(v).To(Equal("gzexe"))
+			Expect(ok).To(BeTrue())
+		})
+	})
+}
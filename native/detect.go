@@ -29,6 +29,9 @@ const (
 	ConfigNativeImage           = "BP_NATIVE_IMAGE"
 	DeprecatedConfigNativeImage = "BP_BOOT_NATIVE_IMAGE"
 	BinaryCompressionMethod     = "BP_BINARY_COMPRESSION_METHOD"
+	BinaryCompressionLevel      = "BP_BINARY_COMPRESSION_LEVEL"
+	BinaryCompressionLZMA       = "BP_BINARY_COMPRESSION_LZMA"
+	ConfigRetainJVMArtifacts    = "BP_NATIVE_IMAGE_RETAIN_JVM_ARTIFACTS"
 
 	PlanEntryNativeImage        = "native-image-application"
 	PlanEntryNativeProcessed    = "native-processed"
@@ -36,6 +39,7 @@ const (
 	PlanEntryJVMApplication     = "jvm-application"
 	PlanEntrySpringBoot         = "spring-boot"
 	PlanEntryUpx                = "upx"
+	PlanEntryJRE                = "jre"
 )
 
 type Detect struct {
@@ -43,11 +47,18 @@ type Detect struct {
 }
 
 func (d Detect) Detect(context libcnb.DetectContext) (libcnb.DetectResult, error) {
-	cr, err := libpak.NewConfigurationResolver(context.Buildpack, nil)
+	pr, err := libpak.NewConfigurationResolver(context.Buildpack, nil)
 	if err != nil {
 		return libcnb.DetectResult{}, fmt.Errorf("unable to create configuration resolver\n%w", err)
 	}
 
+	profile, _ := pr.Resolve(ConfigNativeImageProfile)
+	descriptor, _, err := ReadDescriptor(context.Application.Path, profile)
+	if err != nil {
+		return libcnb.DetectResult{}, fmt.Errorf("unable to read descriptor\n%w", err)
+	}
+	cr := ConfigurationResolver{ConfigurationResolver: pr, Descriptor: descriptor.Configuration()}
+
 	result := libcnb.DetectResult{
 		Pass: true,
 		Plans: []libcnb.BuildPlan{
@@ -108,6 +119,30 @@ func (d Detect) Detect(context libcnb.DetectContext) (libcnb.DetectResult, error
 		},
 	}
 
+	// Quarkus native sources are built without an upstream buildpack, so the plan is preferred when they are found
+	if dir, ok, err := FindQuarkusNativeSources(context.Application.Path); err != nil {
+		return libcnb.DetectResult{}, fmt.Errorf("unable to find Quarkus native sources\n%w", err)
+	} else if ok {
+		d.Logger.Infof("Quarkus native sources found in %s", dir)
+		result.Plans = append([]libcnb.BuildPlan{
+			{
+				Provides: []libcnb.BuildPlanProvide{
+					{
+						Name: PlanEntryNativeImage,
+					},
+				},
+				Requires: []libcnb.BuildPlanRequire{
+					{
+						Name: PlanEntryNativeImageBuilder,
+					},
+					{
+						Name: PlanEntryNativeImage,
+					},
+				},
+			},
+		}, result.Plans...)
+	}
+
 	if ok, err := d.nativeImageEnabled(cr); err != nil {
 		d.Logger.Infof("SKIPPED: The BP_NATIVE_IMAGE environment variable was not set to true")
 		return libcnb.DetectResult{}, err
@@ -137,18 +172,42 @@ func (d Detect) Detect(context libcnb.DetectContext) (libcnb.DetectResult, error
 		}
 	}
 
+	// the jvm process is only contributed if a JRE is available at launch, so each plan is preferably resolved with
+	// one but also without
+	if d.jvmArtifactsRetainedAtLaunch(cr) {
+		var plans []libcnb.BuildPlan
+		for _, p := range result.Plans {
+			jre := p
+			jre.Requires = append(append([]libcnb.BuildPlanRequire{}, p.Requires...), libcnb.BuildPlanRequire{
+				Name:     PlanEntryJRE,
+				Metadata: map[string]interface{}{"launch": true},
+			})
+			plans = append(plans, jre, p)
+		}
+		result.Plans = plans
+	}
+
 	// still participates if a downstream buildpack requires native-image-applications or upx
 	return result, nil
 }
 
-func (d Detect) upxCompressionEnabled(cr libpak.ConfigurationResolver) bool {
+func (d Detect) upxCompressionEnabled(cr ConfigurationResolver) bool {
 	if val, ok := cr.Resolve(BinaryCompressionMethod); ok {
-		return val == CompressorUpx
+		if c, ok := NewCompressor(val, CompressorOptions{}); ok {
+			return c.PlanEntry() == PlanEntryUpx
+		}
+	}
+	return false
+}
+
+func (d Detect) jvmArtifactsRetainedAtLaunch(cr ConfigurationResolver) bool {
+	if val, ok := cr.Resolve(ConfigRetainJVMArtifacts); ok {
+		return val == RetainJVMArtifactsLaunch
 	}
 	return false
 }
 
-func (d Detect) nativeImageEnabled(cr libpak.ConfigurationResolver) (bool, error) {
+func (d Detect) nativeImageEnabled(cr ConfigurationResolver) (bool, error) {
 	if _, ok := cr.Resolve(ConfigNativeImage); ok {
 		return sherpa.ResolveBoolErr(ConfigNativeImage)
 	}
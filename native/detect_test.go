@@ -18,6 +18,7 @@ package native_test
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/buildpacks/libcnb"
@@ -555,6 +556,43 @@ func testDetect(t *testing.T, context spec.G, it spec.S) {
 		})
 	})
 
+	context("$BP_NATIVE_IMAGE_RETAIN_JVM_ARTIFACTS", func() {
+		it.Before(func() {
+			t.Setenv("BP_NATIVE_IMAGE", "true")
+		})
+
+		it("prefers a launch jre when retained at launch", func() {
+			t.Setenv("BP_NATIVE_IMAGE_RETAIN_JVM_ARTIFACTS", "build")
+			without, err := detect.Detect(ctx)
+			Expect(err).NotTo(HaveOccurred())
+
+			t.Setenv("BP_NATIVE_IMAGE_RETAIN_JVM_ARTIFACTS", "launch")
+			result, err := detect.Detect(ctx)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Plans).To(HaveLen(2 * len(without.Plans)))
+			for i, plan := range without.Plans {
+				Expect(result.Plans[2*i].Requires).To(Equal(append(plan.Requires, libcnb.BuildPlanRequire{
+					Name:     "jre",
+					Metadata: map[string]interface{}{"launch": true},
+				})))
+				Expect(result.Plans[2*i+1]).To(Equal(plan))
+			}
+		})
+
+		it("does not require a jre when retained at build", func() {
+			t.Setenv("BP_NATIVE_IMAGE_RETAIN_JVM_ARTIFACTS", "build")
+
+			result, err := detect.Detect(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			for _, plan := range result.Plans {
+				for _, r := range plan.Requires {
+					Expect(r.Name).NotTo(Equal("jre"))
+				}
+			}
+		})
+	})
+
 	context("$BP_BOOT_NATIVE_IMAGE", func() {
 		it.Before(func() {
 			Expect(os.Setenv("BP_BOOT_NATIVE_IMAGE", "true")).To(Succeed())
@@ -626,4 +664,61 @@ func testDetect(t *testing.T, context spec.G, it spec.S) {
 			}))
 		})
 	})
+
+	context("Quarkus native sources", func() {
+		it.Before(func() {
+			ctx.Application.Path = t.TempDir()
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "native-image.args"), []byte{}, 0644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "app-1.0-runner.jar"), []byte{}, 0644)).To(Succeed())
+		})
+
+		it.After(func() {
+			ctx.Application.Path = ""
+		})
+
+		it("prefers building the native sources without an upstream buildpack", func() {
+			result, err := detect.Detect(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Pass).To(BeTrue())
+			Expect(result.Plans[0]).To(Equal(libcnb.BuildPlan{
+				Provides: []libcnb.BuildPlanProvide{
+					{Name: "native-image-application"},
+				},
+				Requires: []libcnb.BuildPlanRequire{
+					{Name: "native-image-builder"},
+					{Name: "native-image-application"},
+				},
+			}))
+		})
+	})
+	context("native-image.toml", func() {
+		it.Before(func() {
+			ctx.Application.Path = t.TempDir()
+		})
+
+		it.After(func() {
+			ctx.Application.Path = ""
+		})
+
+		it("requires upx if compression.method is upx", func() {
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "native-image.toml"), []byte(`
+[compression]
+method = "upx"
+`), 0644)).To(Succeed())
+
+			result, err := detect.Detect(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Plans[0].Requires).To(ContainElement(libcnb.BuildPlanRequire{Name: "upx"}))
+		})
+
+		it("fails on an invalid descriptor", func() {
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "native-image.toml"), []byte(`
+[compression]
+method = "zip"
+`), 0644)).To(Succeed())
+
+			_, err := detect.Detect(ctx)
+			Expect(err).To(MatchError(ContainSubstring("compression.method: must be none, upx or gzexe, not zip")))
+		})
+	})
 }
@@ -0,0 +1,114 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package native
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// The garbage collectors that native-image can build into a native image, spelt as its --gc option documents them
+const (
+	GCSerial  = "serial"
+	GCG1      = "G1"
+	GCEpsilon = "epsilon"
+
+	gcArgumentPrefix = "--gc="
+)
+
+// legacyNativeImageVersion matches the version printed by native-image releases that predate the Java version scheme,
+// such as `GraalVM 22.3.0 Java 17 EE`
+var legacyNativeImageVersion = regexp.MustCompile(`^GraalVM (\d+)\.(\d+)`)
+
+// G1Architectures are the architectures on which native-image supports the G1 garbage collector
+var G1Architectures = []string{"amd64", "arm64"}
+
+// ParseGC returns the garbage collector gc, compared case-insensitively, spelt as the --gc option of native-image
+// documents it. It returns false if gc is not a garbage collector that native-image is known to build into a native
+// image.
+func ParseGC(gc string) (string, bool) {
+	for _, known := range []string{GCSerial, GCG1, GCEpsilon} {
+		if strings.EqualFold(gc, known) {
+			return known, true
+		}
+	}
+	return "", false
+}
+
+// GCArgument returns the garbage collector selected by the --gc argument in arguments, empty if there is none. As with
+// other native-image options, the last one wins.
+func GCArgument(arguments []string) string {
+	var gc string
+	for _, a := range arguments {
+		if strings.HasPrefix(a, gcArgumentPrefix) {
+			gc = strings.TrimPrefix(a, gcArgumentPrefix)
+		}
+	}
+	return gc
+}
+
+// CheckGC returns an error if the native-image that printed version cannot build the garbage collector gc into a
+// native image for arch. The serial garbage collector is always available, epsilon requires GraalVM 21.2 or later and
+// G1 is only included in Oracle GraalVM, formerly GraalVM Enterprise Edition, for amd64 and arm64. Garbage collectors
+// that are not known are left to native-image.
+func CheckGC(gc string, version string, arch string) error {
+	gc, _ = ParseGC(gc)
+
+	switch gc {
+	case "", GCSerial:
+		return nil
+
+	case GCEpsilon:
+		if major, minor, ok := legacyGraalVMVersion(version); ok && (major < 21 || (major == 21 && minor < 2)) {
+			return fmt.Errorf("the epsilon garbage collector requires GraalVM 21.2 or later, not %d.%d", major, minor)
+		}
+		return nil
+
+	case GCG1:
+		if !strings.Contains(version, "Oracle GraalVM") && !strings.Contains(version, " EE") {
+			return fmt.Errorf("the G1 garbage collector is only available in Oracle GraalVM, not %s", firstLine(version))
+		}
+
+		for _, a := range G1Architectures {
+			if a == arch {
+				return nil
+			}
+		}
+		return fmt.Errorf("the G1 garbage collector is only available on %s, not %s", strings.Join(G1Architectures, " and "), arch)
+	}
+
+	return nil
+}
+
+// legacyGraalVMVersion returns the GraalVM version printed by native-image releases that predate the Java version
+// scheme
+func legacyGraalVMVersion(version string) (int, int, bool) {
+	m := legacyNativeImageVersion.FindStringSubmatch(strings.TrimSpace(version))
+	if m == nil {
+		return 0, 0, false
+	}
+
+	major, _ := strconv.Atoi(m[1])
+	minor, _ := strconv.Atoi(m[2])
+	return major, minor, true
+}
+
+func firstLine(s string) string {
+	return strings.TrimSpace(strings.SplitN(strings.TrimSpace(s), "\n", 2)[0])
+}
@@ -0,0 +1,83 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package native_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+
+	"github.com/paketo-buildpacks/native-image/v5/native"
+)
+
+func testGC(t *testing.T, context spec.G, it spec.S) {
+	var Expect = NewWithT(t).Expect
+
+	const (
+		oracle = "native-image 21.0.2 2024-01-16\nGraalVM Runtime Environment Oracle GraalVM 21.0.2+13.1 (build 21.0.2+13-LTS-jvmci-23.1-b30)\n"
+		ce     = "native-image 21.0.2 2024-01-16\nGraalVM Runtime Environment GraalVM CE 21.0.2+13.1 (build 21.0.2+13-jvmci-23.1-b30)\n"
+	)
+
+	it("parses garbage collectors case-insensitively", func() {
+		for gc, expected := range map[string]string{"serial": "serial", "g1": "G1", "G1": "G1", "Epsilon": "epsilon"} {
+			g, ok := native.ParseGC(gc)
+			Expect(ok).To(BeTrue())
+			Expect(g).To(Equal(expected))
+		}
+
+		_, ok := native.ParseGC("parallel")
+		Expect(ok).To(BeFalse())
+	})
+
+	it("finds the last --gc argument", func() {
+		Expect(native.GCArgument([]string{"--gc=g1", "-g", "--gc=serial"})).To(Equal("serial"))
+		Expect(native.GCArgument([]string{"-g"})).To(BeEmpty())
+	})
+
+	context("CheckGC", func() {
+		it("always allows the default and serial garbage collectors", func() {
+			Expect(native.CheckGC("", ce, "arm64")).To(Succeed())
+			Expect(native.CheckGC("serial", ce, "amd64")).To(Succeed())
+		})
+
+		it("allows G1 with Oracle GraalVM", func() {
+			Expect(native.CheckGC("g1", oracle, "amd64")).To(Succeed())
+			Expect(native.CheckGC("G1", oracle, "arm64")).To(Succeed())
+			Expect(native.CheckGC("g1", "GraalVM 22.3.0 Java 17 EE (Java Version 17.0.5+9-LTS-jvmci-22.3-b07)", "amd64")).To(Succeed())
+		})
+
+		it("rejects G1 with other distributions", func() {
+			Expect(native.CheckGC("g1", ce, "amd64")).To(MatchError("the G1 garbage collector is only available in Oracle GraalVM, not native-image 21.0.2 2024-01-16"))
+		})
+
+		it("rejects G1 on other architectures", func() {
+			Expect(native.CheckGC("g1", oracle, "riscv64")).To(MatchError("the G1 garbage collector is only available on amd64 and arm64, not riscv64"))
+		})
+
+		it("leaves unknown garbage collectors to native-image", func() {
+			Expect(native.CheckGC("parallel", ce, "amd64")).To(Succeed())
+		})
+
+		it("requires GraalVM 21.2 for epsilon", func() {
+			Expect(native.CheckGC("epsilon", ce, "amd64")).To(Succeed())
+			Expect(native.CheckGC("epsilon", "GraalVM 21.2.0 Java 11 CE (Java Version 11.0.12+6-jvmci-21.2-b08)", "amd64")).To(Succeed())
+			Expect(native.CheckGC("epsilon", "GraalVM 21.1.0 Java 11 CE (Java Version 11.0.11+8-jvmci-21.1-b05)", "amd64")).
+				To(MatchError("the epsilon garbage collector requires GraalVM 21.2 or later, not 21.1"))
+		})
+	})
+}
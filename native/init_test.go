@@ -25,9 +25,33 @@ import (
 
 func TestUnit(t *testing.T) {
 	suite := spec.New("native", spec.Report(report.Terminal{}))
+	suite("Binding", testBinding)
+	suite("Boot", testBoot)
 	suite("Build", testBuild)
+	suite("BuildCache", testBuildCache)
+	suite("BuildReport", testBuildReport)
+	suite("CacheExplanation", testCacheExplanation)
+	suite("Compatibility", testCompatibility)
+	suite("Compressor", testCompressor)
+	suite("DebugInfo", testDebugInfo)
+	suite("Descriptor", testDescriptor)
 	suite("Detect", testDetect)
+	suite("GC", testGC)
 	suite("Arguments", testArguments)
+	suite("Inputs", testInputs)
+	suite("JARs", testJARs)
+	suite("JVMArtifacts", testJVMArtifacts)
+	suite("Launcher", testLauncher)
+	suite("Layered", testLayered)
+	suite("Libraries", testLibraries)
+	suite("Module", testModule)
 	suite("NativeImage", testNativeImage)
+	suite("Output", testOutput)
+	suite("Plan", testPlan)
+	suite("Policy", testPolicy)
+	suite("Quarkus", testQuarkus)
+	suite("Size", testSize)
+	suite("SmokeTest", testSmokeTest)
+	suite("Target", testTarget)
 	suite.Run(t)
 }
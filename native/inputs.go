@@ -0,0 +1,315 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package native
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/paketo-buildpacks/libjvm"
+	"github.com/paketo-buildpacks/libpak/bard"
+)
+
+// InputDigestFile is the name of the file in the native-image layer that records the digest of each input file
+const InputDigestFile = "native-image-inputs.json"
+
+// InputDigest is a content digest of the files that are inputs to a native-image build
+type InputDigest struct {
+
+	// Root is the digest of all input files, computed from their paths and digests.
+	Root string `json:"root"`
+
+	// Files maps the path of each input file to its SHA256 digest.
+	Files map[string]string `json:"files"`
+}
+
+// InputPaths returns the files and directories referenced by arguments that affect the build: the class path or
+// module path, the JAR and the Class-Path of its manifest, argument files, configuration directories and profiles.
+// The arguments of argument files are included, as they may reference the class path themselves.
+func InputPaths(arguments []string) []string {
+	var paths []string
+
+	arguments = expandArgumentFiles(arguments, map[string]bool{})
+
+	for i := 0; i < len(arguments); i++ {
+		arg := arguments[i]
+
+		switch {
+		case (arg == "-cp" || arg == "-classpath" || arg == "--class-path" || arg == "-p" || arg == "--module-path") && i+1 < len(arguments):
+			i++
+			for _, entry := range filepath.SplitList(arguments[i]) {
+				if strings.HasSuffix(entry, "*") {
+					matches, _ := filepath.Glob(fmt.Sprintf("%s.jar", entry))
+					paths = append(paths, matches...)
+				} else if entry != "" {
+					paths = append(paths, entry)
+				}
+			}
+		case arg == "-jar" && i+1 < len(arguments):
+			i++
+			paths = append(append(paths, arguments[i]), jarClassPath(arguments[i])...)
+		case strings.HasPrefix(arg, "@"):
+			paths = append(paths, strings.TrimPrefix(arg, "@"))
+		case strings.HasPrefix(arg, "-H:ConfigurationFileDirectories="):
+			paths = append(paths, strings.Split(strings.TrimPrefix(arg, "-H:ConfigurationFileDirectories="), ",")...)
+		case strings.HasPrefix(arg, "--pgo="):
+			paths = append(paths, strings.Split(strings.TrimPrefix(arg, "--pgo="), ",")...)
+		case strings.HasPrefix(arg, "--layer-use="):
+			paths = append(paths, strings.TrimPrefix(arg, "--layer-use="))
+		}
+	}
+
+	return paths
+}
+
+// expandArgumentFiles returns arguments followed, for each argument file, by its arguments. Argument files that cannot
+// be read, or that were already expanded, are not expanded.
+func expandArgumentFiles(arguments []string, expanded map[string]bool) []string {
+	var result []string
+
+	for _, a := range arguments {
+		result = append(result, a)
+
+		file := strings.TrimPrefix(a, "@")
+		if file == a || expanded[file] {
+			continue
+		}
+		expanded[file] = true
+
+		if args, err := readArgumentsFile(file); err == nil {
+			result = append(result, expandArgumentFiles(args, expanded)...)
+		}
+	}
+
+	return result
+}
+
+// jarClassPath returns the existing entries of the Class-Path of the manifest of jar, nil if it cannot be read
+func jarClassPath(jar string) []string {
+	manifest, err := libjvm.NewManifestFromJAR(jar)
+	if err != nil {
+		return nil
+	}
+
+	entries, err := ManifestClassPath(manifest, filepath.Dir(jar), bard.Logger{})
+	if err != nil {
+		return nil
+	}
+	return entries
+}
+
+// NewInputDigest computes the digest of all files under roots, hashing files in parallel. Roots that do not exist are
+// ignored.
+func NewInputDigest(roots ...string) (InputDigest, error) {
+	var files []string
+	for _, root := range roots {
+		p, err := filepath.EvalSymlinks(root)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return InputDigest{}, fmt.Errorf("unable to resolve %s\n%w", root, err)
+		}
+
+		if err := filepath.Walk(p, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if info.IsDir() && info.Name() == ".git" {
+				return filepath.SkipDir
+			}
+
+			if info.Mode()&os.ModeSymlink != 0 {
+				if info, err = os.Stat(path); err != nil {
+					return nil
+				}
+			}
+
+			if info.Mode().IsRegular() {
+				rel, err := filepath.Rel(p, path)
+				if err != nil {
+					return err
+				}
+				files = append(files, filepath.Join(root, rel))
+			}
+			return nil
+		}); err != nil {
+			return InputDigest{}, fmt.Errorf("unable to walk %s\n%w", root, err)
+		}
+	}
+
+	digests := make([]string, len(files))
+	errs := make([]error, len(files))
+	indices := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < runtime.NumCPU(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				digests[i], errs[i] = fileDigest(files[i])
+			}
+		}()
+	}
+	for i := range files {
+		indices <- i
+	}
+	close(indices)
+	wg.Wait()
+
+	d := InputDigest{Files: make(map[string]string, len(files))}
+	for i, file := range files {
+		if errs[i] != nil {
+			return InputDigest{}, errs[i]
+		}
+		d.Files[file] = digests[i]
+	}
+
+	paths := make([]string, 0, len(d.Files))
+	for path := range d.Files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	root := sha256.New()
+	for _, path := range paths {
+		_, _ = fmt.Fprintf(root, "%s\x00%s\n", path, d.Files[path])
+	}
+	d.Root = hex.EncodeToString(root.Sum(nil))
+
+	return d, nil
+}
+
+// ReadInputDigest reads the digest from file, returning an empty digest if the file does not exist
+func ReadInputDigest(file string) (InputDigest, error) {
+	raw, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return InputDigest{}, nil
+	} else if err != nil {
+		return InputDigest{}, fmt.Errorf("unable to read %s\n%w", file, err)
+	}
+
+	var d InputDigest
+	if err := json.Unmarshal(raw, &d); err != nil {
+		return InputDigest{}, fmt.Errorf("unable to decode %s\n%w", file, err)
+	}
+
+	return d, nil
+}
+
+// Write writes the digest to file
+func (d InputDigest) Write(file string) error {
+	raw, err := json.Marshal(d)
+	if err != nil {
+		return fmt.Errorf("unable to encode input digest\n%w", err)
+	}
+
+	if err := os.WriteFile(file, raw, 0644); err != nil {
+		return fmt.Errorf("unable to write %s\n%w", file, err)
+	}
+
+	return nil
+}
+
+// Diff returns the files added, removed and changed since previous
+func (d InputDigest) Diff(previous InputDigest) (added []string, removed []string, changed []string) {
+	for path, digest := range d.Files {
+		if p, ok := previous.Files[path]; !ok {
+			added = append(added, path)
+		} else if p != digest {
+			changed = append(changed, path)
+		}
+	}
+
+	for path := range previous.Files {
+		if _, ok := d.Files[path]; !ok {
+			removed = append(removed, path)
+		}
+	}
+
+	sort.Strings(added)
+	sort.Strings(removed)
+	sort.Strings(changed)
+	return added, removed, changed
+}
+
+// Changes describes the files added, removed and changed since previous, relative to base. Files are named
+// individually unless more than a handful changed in the same directory, in which case they are counted.
+func (d InputDigest) Changes(previous InputDigest, base string) []string {
+	const limit = 3
+
+	added, removed, changed := d.Diff(previous)
+
+	var descriptions []string
+	for _, c := range []struct {
+		verb  string
+		paths []string
+	}{{"added", added}, {"removed", removed}, {"changed", changed}} {
+		var dirs []string
+		files := map[string][]string{}
+		for _, path := range c.paths {
+			if rel, err := filepath.Rel(base, path); err == nil && !strings.HasPrefix(rel, "..") {
+				path = rel
+			}
+
+			dir := filepath.Dir(path)
+			if _, ok := files[dir]; !ok {
+				dirs = append(dirs, dir)
+			}
+			files[dir] = append(files[dir], path)
+		}
+
+		for _, dir := range dirs {
+			if len(files[dir]) <= limit {
+				for _, file := range files[dir] {
+					descriptions = append(descriptions, fmt.Sprintf("file `%s` %s", file, c.verb))
+				}
+			} else if dir == "." {
+				descriptions = append(descriptions, fmt.Sprintf("%d files %s", len(files[dir]), c.verb))
+			} else {
+				descriptions = append(descriptions, fmt.Sprintf("%d files %s under %s", len(files[dir]), c.verb, dir))
+			}
+		}
+	}
+
+	return descriptions
+}
+
+func fileDigest(path string) (string, error) {
+	in, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("unable to open %s\n%w", path, err)
+	}
+	defer in.Close()
+
+	s := sha256.New()
+	if _, err := io.Copy(s, in); err != nil {
+		return "", fmt.Errorf("unable to hash %s\n%w", path, err)
+	}
+
+	return hex.EncodeToString(s.Sum(nil)), nil
+}
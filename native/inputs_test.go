@@ -0,0 +1,179 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package native_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+
+	"github.com/paketo-buildpacks/native-image/v5/native"
+)
+
+func testInputs(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		path string
+	)
+
+	it.Before(func() {
+		path = t.TempDir()
+
+		Expect(os.MkdirAll(filepath.Join(path, "BOOT-INF", "lib"), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(path, "BOOT-INF", "lib", "a.jar"), []byte("a"), 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(path, "BOOT-INF", "lib", "b.jar"), []byte("b"), 0644)).To(Succeed())
+	})
+
+	context("input paths", func() {
+		it("finds the class path, JAR, argfiles and configuration directories", func() {
+			Expect(native.InputPaths([]string{
+				"--no-fallback",
+				"@/args.txt",
+				"-H:ConfigurationFileDirectories=/config-1,/config-2",
+				"--pgo=/pgo/a.iprof,/pgo/b.iprof",
+				"-H:Name=/layer/test",
+				"-cp", strings.Join([]string{"/workspace", "/lib/c.jar"}, string(filepath.ListSeparator)),
+				"test-start-class",
+				"-jar", "/workspace/app.jar",
+			})).To(Equal([]string{
+				"/args.txt",
+				"/config-1",
+				"/config-2",
+				"/pgo/a.iprof",
+				"/pgo/b.iprof",
+				"/workspace",
+				"/lib/c.jar",
+				"/workspace/app.jar",
+			}))
+		})
+
+		it("finds the paths referenced from argument files", func() {
+			outer := filepath.Join(path, "outer.args")
+			inner := filepath.Join(path, "inner.args")
+			Expect(os.WriteFile(outer, []byte("-cp\n/lib/a.jar\n@"+inner+"\n"), 0644)).To(Succeed())
+			Expect(os.WriteFile(inner, []byte("-H:ConfigurationFileDirectories=/config @"+outer), 0644)).To(Succeed())
+
+			Expect(native.InputPaths([]string{"@" + outer, "@/missing.args"})).To(Equal([]string{
+				outer,
+				"/lib/a.jar",
+				inner,
+				"/config",
+				outer,
+				"/missing.args",
+			}))
+		})
+
+		it("finds the Class-Path of the JAR", func() {
+			jar := filepath.Join(path, "app-runner.jar")
+			writeJAR(t, jar, map[string]string{
+				"META-INF/MANIFEST.MF": "Main-Class: org.example.Main\nClass-Path: lib/dependency.jar lib/missing.jar\n",
+			})
+			Expect(os.MkdirAll(filepath.Join(path, "lib"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(path, "lib", "dependency.jar"), []byte{}, 0644)).To(Succeed())
+
+			Expect(native.InputPaths([]string{"-jar", jar})).To(Equal([]string{
+				jar,
+				filepath.Join(path, "lib", "dependency.jar"),
+			}))
+		})
+
+		it("expands class path wildcards", func() {
+			Expect(native.InputPaths([]string{"-cp", filepath.Join(path, "BOOT-INF", "lib", "*")})).To(Equal([]string{
+				filepath.Join(path, "BOOT-INF", "lib", "a.jar"),
+				filepath.Join(path, "BOOT-INF", "lib", "b.jar"),
+			}))
+		})
+	})
+
+	context("digest", func() {
+		it("digests files and ignores missing roots", func() {
+			d, err := native.NewInputDigest(path, filepath.Join(path, "missing"))
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(d.Root).NotTo(BeEmpty())
+			Expect(d.Files).To(HaveLen(2))
+			Expect(d.Files).To(HaveKey(filepath.Join(path, "BOOT-INF", "lib", "a.jar")))
+		})
+
+		it("is stable and changes with content", func() {
+			d1, err := native.NewInputDigest(path)
+			Expect(err).NotTo(HaveOccurred())
+
+			d2, err := native.NewInputDigest(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(d2.Root).To(Equal(d1.Root))
+
+			Expect(os.WriteFile(filepath.Join(path, "BOOT-INF", "lib", "a.jar"), []byte("changed"), 0644)).To(Succeed())
+
+			d3, err := native.NewInputDigest(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(d3.Root).NotTo(Equal(d1.Root))
+		})
+
+		it("round trips through a file", func() {
+			d, err := native.NewInputDigest(path)
+			Expect(err).NotTo(HaveOccurred())
+
+			file := filepath.Join(t.TempDir(), "inputs.json")
+			Expect(d.Write(file)).To(Succeed())
+			Expect(native.ReadInputDigest(file)).To(Equal(d))
+		})
+
+		it("returns an empty digest for a missing file", func() {
+			Expect(native.ReadInputDigest(filepath.Join(path, "missing.json"))).To(Equal(native.InputDigest{}))
+		})
+	})
+
+	context("diff", func() {
+		it("describes added, removed and changed files", func() {
+			previous, err := native.NewInputDigest(path)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(os.WriteFile(filepath.Join(path, "BOOT-INF", "lib", "a.jar"), []byte("changed"), 0644)).To(Succeed())
+			Expect(os.Remove(filepath.Join(path, "BOOT-INF", "lib", "b.jar"))).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(path, "BOOT-INF", "lib", "c.jar"), []byte("c"), 0644)).To(Succeed())
+
+			current, err := native.NewInputDigest(path)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(current.Changes(previous, path)).To(Equal([]string{
+				"file `BOOT-INF/lib/c.jar` added",
+				"file `BOOT-INF/lib/b.jar` removed",
+				"file `BOOT-INF/lib/a.jar` changed",
+			}))
+		})
+
+		it("counts files when many change in a directory", func() {
+			previous := native.InputDigest{Files: map[string]string{}}
+			current := native.InputDigest{Files: map[string]string{}}
+			for _, name := range []string{"1", "2", "3", "4", "5", "6", "7"} {
+				current.Files[filepath.Join(path, "BOOT-INF", "lib", name)] = name
+				current.Files[filepath.Join(path, name)] = name
+			}
+
+			Expect(current.Changes(previous, path)).To(Equal([]string{
+				"7 files added",
+				"7 files added under BOOT-INF/lib",
+			}))
+		})
+	})
+}
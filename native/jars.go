@@ -0,0 +1,151 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package native
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/paketo-buildpacks/libjvm"
+	"github.com/paketo-buildpacks/libpak/bard"
+)
+
+// IgnoredJARSuffixes are the classifiers of JARs that build tools create next to the application JAR
+var IgnoredJARSuffixes = []string{"-plain", "-sources", "-javadoc", "-tests", "-test-sources", "-test-javadoc"}
+
+// FindJAR returns the single JAR matching pattern in applicationPath. If several match, JARs whose path or name
+// matches one of the exclusions, JARs with one of the IgnoredJARSuffixes or prefixed with original-, and JARs without a
+// Main-Class or Start-Class are rejected in turn until a single JAR remains. The choice is logged with the reason each
+// other candidate was rejected.
+func FindJAR(applicationPath string, pattern string, exclusions []string, logger bard.Logger) (string, error) {
+	candidates, err := filepath.Glob(filepath.Join(applicationPath, pattern))
+	if err != nil {
+		return "", fmt.Errorf("unable to find JAR with %s\n%w", pattern, err)
+	}
+	sort.Strings(candidates)
+
+	if len(candidates) == 1 {
+		return candidates[0], nil
+	}
+
+	rejected := map[string]string{}
+	remaining := candidates
+
+	// reject removes the candidates reason returns a rejection for. Heuristics that would reject every candidate are
+	// ignored, unlike explicit exclusions.
+	reject := func(heuristic bool, reason func(string) (string, error)) error {
+		var kept []string
+		reasons := map[string]string{}
+		for _, c := range remaining {
+			r, err := reason(c)
+			if err != nil {
+				return err
+			}
+			if r == "" {
+				kept = append(kept, c)
+			} else {
+				reasons[c] = r
+			}
+		}
+
+		if len(kept) > 0 || !heuristic {
+			remaining = kept
+			for c, r := range reasons {
+				rejected[c] = r
+			}
+		}
+		return nil
+	}
+
+	if err := reject(false, func(c string) (string, error) {
+		rel, err := filepath.Rel(applicationPath, c)
+		if err != nil {
+			return "", fmt.Errorf("unable to relativize %s\n%w", c, err)
+		}
+		for _, e := range exclusions {
+			for _, name := range []string{rel, filepath.Base(c)} {
+				if ok, err := filepath.Match(e, name); err != nil {
+					return "", fmt.Errorf("unable to match %s\n%w", e, err)
+				} else if ok {
+					return fmt.Sprintf("excluded by %s", e), nil
+				}
+			}
+		}
+		return "", nil
+	}); err != nil {
+		return "", err
+	}
+
+	if len(remaining) > 1 {
+		if err := reject(true, func(c string) (string, error) {
+			name := strings.TrimSuffix(filepath.Base(c), filepath.Ext(c))
+			if strings.HasPrefix(name, "original-") {
+				return "original JAR replaced by a repackaged one", nil
+			}
+			for _, s := range IgnoredJARSuffixes {
+				if strings.HasSuffix(name, s) {
+					return fmt.Sprintf("%s classifier", strings.TrimPrefix(s, "-")), nil
+				}
+			}
+			return "", nil
+		}); err != nil {
+			return "", err
+		}
+	}
+
+	if len(remaining) > 1 {
+		if err := reject(true, func(c string) (string, error) {
+			manifest, err := libjvm.NewManifestFromJAR(c)
+			if err != nil {
+				return "unable to read manifest", nil
+			}
+			if _, ok := manifest.Get("Main-Class"); ok {
+				return "", nil
+			}
+			if _, ok := manifest.Get("Start-Class"); ok {
+				return "", nil
+			}
+			return "no Main-Class or Start-Class in manifest", nil
+		}); err != nil {
+			return "", err
+		}
+	}
+
+	var reasons []string
+	for _, c := range candidates {
+		if r, ok := rejected[c]; ok {
+			reasons = append(reasons, fmt.Sprintf("%s: %s", filepath.Base(c), r))
+		}
+	}
+
+	if len(remaining) != 1 {
+		if len(reasons) == 0 {
+			return "", fmt.Errorf("unable to find single JAR in %s, candidates: %s", pattern, candidates)
+		}
+		return "", fmt.Errorf("unable to find single JAR in %s, candidates: %s, rejected %s",
+			pattern, candidates, strings.Join(reasons, ", "))
+	}
+
+	logger.Bodyf("Selected %s from %d JARs matching %s", filepath.Base(remaining[0]), len(candidates), pattern)
+	for _, r := range reasons {
+		logger.Bodyf("  Rejected %s", r)
+	}
+
+	return remaining[0], nil
+}
@@ -0,0 +1,109 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package native_test
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/paketo-buildpacks/libpak/bard"
+	"github.com/sclevine/spec"
+
+	"github.com/paketo-buildpacks/native-image/v5/native"
+)
+
+func testJARs(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		appPath string
+		out     *bytes.Buffer
+		logger  bard.Logger
+	)
+
+	it.Before(func() {
+		appPath = t.TempDir()
+		out = &bytes.Buffer{}
+		logger = bard.NewLogger(out)
+	})
+
+	main := map[string]string{"META-INF/MANIFEST.MF": "Main-Class: org.example.Main\n"}
+	library := map[string]string{"META-INF/MANIFEST.MF": "Manifest-Version: 1.0\n"}
+
+	it("returns a single candidate", func() {
+		writeJAR(t, filepath.Join(appPath, "app.jar"), library)
+
+		jar, err := native.FindJAR(appPath, "*.jar", nil, logger)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(jar).To(Equal(filepath.Join(appPath, "app.jar")))
+		Expect(out.String()).To(BeEmpty())
+	})
+
+	it("rejects the JARs of classifiers", func() {
+		writeJAR(t, filepath.Join(appPath, "app.jar"), main)
+		writeJAR(t, filepath.Join(appPath, "app-plain.jar"), main)
+		writeJAR(t, filepath.Join(appPath, "app-sources.jar"), library)
+		writeJAR(t, filepath.Join(appPath, "original-app.jar"), main)
+
+		jar, err := native.FindJAR(appPath, "*.jar", nil, logger)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(jar).To(Equal(filepath.Join(appPath, "app.jar")))
+		Expect(out.String()).To(ContainSubstring("Selected app.jar from 4 JARs matching *.jar"))
+		Expect(out.String()).To(ContainSubstring("Rejected app-plain.jar: plain classifier"))
+		Expect(out.String()).To(ContainSubstring("Rejected app-sources.jar: sources classifier"))
+		Expect(out.String()).To(ContainSubstring("Rejected original-app.jar: original JAR replaced by a repackaged one"))
+	})
+
+	it("prefers JARs with a main class", func() {
+		writeJAR(t, filepath.Join(appPath, "app.jar"), main)
+		writeJAR(t, filepath.Join(appPath, "library.jar"), library)
+
+		jar, err := native.FindJAR(appPath, "*.jar", nil, logger)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(jar).To(Equal(filepath.Join(appPath, "app.jar")))
+		Expect(out.String()).To(ContainSubstring("Rejected library.jar: no Main-Class or Start-Class in manifest"))
+	})
+
+	it("rejects excluded JARs", func() {
+		writeJAR(t, filepath.Join(appPath, "app.jar"), main)
+		writeJAR(t, filepath.Join(appPath, "tool.jar"), main)
+
+		jar, err := native.FindJAR(appPath, "*.jar", []string{"tool*.jar"}, logger)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(jar).To(Equal(filepath.Join(appPath, "app.jar")))
+		Expect(out.String()).To(ContainSubstring("Rejected tool.jar: excluded by tool*.jar"))
+	})
+
+	it("fails if several candidates remain", func() {
+		writeJAR(t, filepath.Join(appPath, "app.jar"), main)
+		writeJAR(t, filepath.Join(appPath, "tool.jar"), main)
+		writeJAR(t, filepath.Join(appPath, "app-javadoc.jar"), library)
+
+		_, err := native.FindJAR(appPath, "*.jar", nil, logger)
+		Expect(err).To(MatchError(MatchRegexp(`unable to find single JAR in \*\.jar, candidates: \[.*app-javadoc\.jar .*app\.jar .*tool\.jar\], rejected app-javadoc\.jar: javadoc classifier`)))
+	})
+
+	it("fails if every candidate is excluded", func() {
+		writeJAR(t, filepath.Join(appPath, "app.jar"), main)
+		writeJAR(t, filepath.Join(appPath, "tool.jar"), main)
+
+		_, err := native.FindJAR(appPath, "*.jar", []string{"*.jar"}, logger)
+		Expect(err).To(MatchError(ContainSubstring("rejected app.jar: excluded by *.jar, tool.jar: excluded by *.jar")))
+	})
+}
@@ -0,0 +1,125 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package native
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/buildpacks/libcnb"
+	"github.com/magiconair/properties"
+	"github.com/paketo-buildpacks/libpak"
+	"github.com/paketo-buildpacks/libpak/bard"
+	"github.com/paketo-buildpacks/libpak/sherpa"
+)
+
+const (
+	RetainJVMArtifactsNone   = "none"
+	RetainJVMArtifactsBuild  = "build"
+	RetainJVMArtifactsLaunch = "launch"
+)
+
+// JVMArtifacts retains the application bytecode that is removed from the workspace once the native image is built
+type JVMArtifacts struct {
+	ApplicationPath string
+	Launch          bool
+	Logger          bard.Logger
+}
+
+// NewJVMArtifacts creates a new instance, exposing the layer at launch if requested and at build otherwise
+func NewJVMArtifacts(applicationPath string, launch bool) JVMArtifacts {
+	return JVMArtifacts{
+		ApplicationPath: applicationPath,
+		Launch:          launch,
+	}
+}
+
+func (j JVMArtifacts) Contribute(layer libcnb.Layer) (libcnb.Layer, error) {
+	hash, err := sherpa.NewFileListingHash(j.ApplicationPath)
+	if err != nil {
+		return libcnb.Layer{}, fmt.Errorf("unable to create file listing for %s\n%w", j.ApplicationPath, err)
+	}
+
+	contributor := libpak.NewLayerContributor("JVM Artifacts", map[string]interface{}{
+		"files": hash,
+	}, libcnb.LayerTypes{
+		Build:  !j.Launch,
+		Launch: j.Launch,
+	})
+	contributor.Logger = j.Logger
+
+	layer, err = contributor.Contribute(layer, func() (libcnb.Layer, error) {
+		j.Logger.Bodyf("Retaining JVM artifacts from %s", j.ApplicationPath)
+		if err := sherpa.CopyDir(j.ApplicationPath, layer.Path); err != nil {
+			return libcnb.Layer{}, fmt.Errorf("unable to copy %s to %s\n%w", j.ApplicationPath, layer.Path, err)
+		}
+
+		return layer, nil
+	})
+	if err != nil {
+		return libcnb.Layer{}, fmt.Errorf("unable to contribute jvm-artifacts layer\n%w", err)
+	}
+
+	return layer, nil
+}
+
+func (JVMArtifacts) Name() string {
+	return "jvm-artifacts"
+}
+
+// JVMProcess returns a process that runs the application retained from applicationPath in the layer at layerPath. An
+// exploded application is run with the class path the native image is built from, moved to the layer.
+func JVMProcess(applicationPath string, layerPath string, manifest *properties.Properties, jarFilePattern string, jarExclusions []string) (libcnb.Process, error) {
+	process := libcnb.Process{Type: "jvm", Command: "java", Direct: true, WorkingDirectory: layerPath}
+
+	if jarFilePattern != "" {
+		if jar, err := FindJAR(applicationPath, jarFilePattern, jarExclusions, bard.Logger{}); err == nil {
+			rel, err := filepath.Rel(applicationPath, jar)
+			if err != nil {
+				return libcnb.Process{}, fmt.Errorf("unable to relativize %s\n%w", jar, err)
+			}
+
+			process.Arguments = []string{"-jar", filepath.Join(layerPath, rel)}
+			return process, nil
+		}
+	}
+
+	mainClass, ok := manifest.Get("Start-Class")
+	if !ok {
+		mainClass, ok = manifest.Get("Main-Class")
+		if !ok {
+			return libcnb.Process{}, NoStartOrMainClass{}
+		}
+	}
+
+	cp, err := ExplodedJarArguments{ApplicationPath: applicationPath, Manifest: manifest}.ClassPath()
+	if err != nil {
+		return libcnb.Process{}, fmt.Errorf("unable to determine class path\n%w", err)
+	}
+
+	var entries []string
+	for _, entry := range filepath.SplitList(cp) {
+		if rel, err := filepath.Rel(applicationPath, entry); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			entry = filepath.Join(layerPath, rel)
+		}
+		entries = append(entries, entry)
+	}
+
+	process.Arguments = []string{"-cp", strings.Join(entries, string(filepath.ListSeparator)), mainClass}
+	return process, nil
+}
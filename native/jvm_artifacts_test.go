@@ -0,0 +1,165 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package native_test
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/buildpacks/libcnb"
+	"github.com/magiconair/properties"
+	. "github.com/onsi/gomega"
+	"github.com/paketo-buildpacks/libpak/bard"
+	"github.com/sclevine/spec"
+
+	"github.com/paketo-buildpacks/native-image/v5/native"
+)
+
+func testJVMArtifacts(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		ctx libcnb.BuildContext
+	)
+
+	it.Before(func() {
+		ctx.Application.Path = t.TempDir()
+		ctx.Layers.Path = t.TempDir()
+
+		Expect(os.MkdirAll(filepath.Join(ctx.Application.Path, "BOOT-INF", "classes"), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "BOOT-INF", "classes", "Test.class"), []byte("bytecode"), 0644)).To(Succeed())
+	})
+
+	it("copies the application into a build layer", func() {
+		j := native.NewJVMArtifacts(ctx.Application.Path, false)
+		j.Logger = bard.NewLogger(io.Discard)
+
+		layer, err := ctx.Layers.Layer("jvm-artifacts")
+		Expect(err).NotTo(HaveOccurred())
+
+		layer, err = j.Contribute(layer)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(layer.Build).To(BeTrue())
+		Expect(layer.Launch).To(BeFalse())
+		Expect(layer.Metadata).To(HaveKey("files"))
+		Expect(filepath.Join(layer.Path, "BOOT-INF", "classes", "Test.class")).To(BeARegularFile())
+	})
+
+	it("copies the application into a launch layer", func() {
+		j := native.NewJVMArtifacts(ctx.Application.Path, true)
+		j.Logger = bard.NewLogger(io.Discard)
+
+		layer, err := ctx.Layers.Layer("jvm-artifacts")
+		Expect(err).NotTo(HaveOccurred())
+
+		layer, err = j.Contribute(layer)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(layer.Build).To(BeFalse())
+		Expect(layer.Launch).To(BeTrue())
+	})
+
+	context("jvm process", func() {
+		it("runs an exploded application with the Main-Class", func() {
+			props := properties.NewProperties()
+			_, _, err := props.Set("Main-Class", "test-main-class")
+			Expect(err).NotTo(HaveOccurred())
+
+			p, err := native.JVMProcess(ctx.Application.Path, "/layers/jvm-artifacts", props, "", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(p).To(Equal(libcnb.Process{
+				Type:             "jvm",
+				Command:          "java",
+				Arguments:        []string{"-cp", "/layers/jvm-artifacts", "test-main-class"},
+				Direct:           true,
+				WorkingDirectory: "/layers/jvm-artifacts",
+			}))
+		})
+
+		it("runs an exploded application with the class path of the native image", func() {
+			Expect(os.MkdirAll(filepath.Join(ctx.Application.Path, "lib"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "lib", "dependency.jar"), []byte{}, 0644)).To(Succeed())
+
+			props := properties.NewProperties()
+			_, _, err := props.Set("Start-Class", "test-start-class")
+			Expect(err).NotTo(HaveOccurred())
+			_, _, err = props.Set("Class-Path", "lib/dependency.jar")
+			Expect(err).NotTo(HaveOccurred())
+
+			p, err := native.JVMProcess(ctx.Application.Path, "/layers/jvm-artifacts", props, "", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(p.Arguments).To(Equal([]string{
+				"-cp", "/layers/jvm-artifacts" + string(filepath.ListSeparator) + "/layers/jvm-artifacts/lib/dependency.jar",
+				"test-start-class",
+			}))
+		})
+
+		it("runs an exploded Spring Boot application with its classes and libraries", func() {
+			Expect(os.MkdirAll(filepath.Join(ctx.Application.Path, "BOOT-INF", "lib"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "BOOT-INF", "lib", "dependency.jar"), []byte{}, 0644)).To(Succeed())
+
+			props := properties.NewProperties()
+			_, _, err := props.Set("Main-Class", "org.springframework.boot.loader.JarLauncher")
+			Expect(err).NotTo(HaveOccurred())
+			_, _, err = props.Set("Start-Class", "test-start-class")
+			Expect(err).NotTo(HaveOccurred())
+			_, _, err = props.Set("Spring-Boot-Classes", "BOOT-INF/classes/")
+			Expect(err).NotTo(HaveOccurred())
+			_, _, err = props.Set("Spring-Boot-Lib", "BOOT-INF/lib/")
+			Expect(err).NotTo(HaveOccurred())
+
+			p, err := native.JVMProcess(ctx.Application.Path, "/layers/jvm-artifacts", props, "", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(p.Arguments).To(Equal([]string{
+				"-cp", "/layers/jvm-artifacts/BOOT-INF/classes" + string(filepath.ListSeparator) + "/layers/jvm-artifacts/BOOT-INF/lib/dependency.jar",
+				"test-start-class",
+			}))
+		})
+
+		it("keeps class path entries outside of the application", func() {
+			t.Setenv("CLASSPATH", ctx.Application.Path+string(filepath.ListSeparator)+"/layers/other/other.jar")
+
+			props := properties.NewProperties()
+			_, _, err := props.Set("Main-Class", "test-main-class")
+			Expect(err).NotTo(HaveOccurred())
+
+			p, err := native.JVMProcess(ctx.Application.Path, "/layers/jvm-artifacts", props, "", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(p.Arguments).To(Equal([]string{
+				"-cp", "/layers/jvm-artifacts" + string(filepath.ListSeparator) + "/layers/other/other.jar",
+				"test-main-class",
+			}))
+		})
+
+		it("runs a JAR relative to the layer", func() {
+			Expect(os.MkdirAll(filepath.Join(ctx.Application.Path, "target"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "target", "app.jar"), []byte{}, 0644)).To(Succeed())
+
+			p, err := native.JVMProcess(ctx.Application.Path, "/layers/jvm-artifacts", properties.NewProperties(), "target/*.jar", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(p.Arguments).To(Equal([]string{"-jar", "/layers/jvm-artifacts/target/app.jar"}))
+		})
+
+		it("fails without a main class", func() {
+			_, err := native.JVMProcess(ctx.Application.Path, "/layers/jvm-artifacts", properties.NewProperties(), "", nil)
+			Expect(err).To(MatchError(native.NoStartOrMainClass{}))
+		})
+	})
+}
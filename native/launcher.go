@@ -0,0 +1,90 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package native
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/buildpacks/libcnb"
+	"github.com/paketo-buildpacks/libpak"
+	"github.com/paketo-buildpacks/libpak/bard"
+	"github.com/paketo-buildpacks/libpak/sherpa"
+)
+
+// Launcher contributes the launcher that starts the variant of a native image, built for several microarchitectures
+// with $BP_NATIVE_IMAGE_MARCH, best suited to the CPU it runs on and passes the runtime options of
+// $BPL_NATIVE_IMAGE_GC_ARGS to it
+type Launcher struct {
+	BuildpackPath string
+	Logger        bard.Logger
+}
+
+// NewLauncher creates a new instance, copying the launcher shipped in the buildpack at buildpackPath
+func NewLauncher(buildpackPath string) Launcher {
+	return Launcher{BuildpackPath: buildpackPath}
+}
+
+func (l Launcher) Contribute(layer libcnb.Layer) (libcnb.Layer, error) {
+	src := filepath.Join(l.BuildpackPath, "bin", "launcher")
+
+	hash, err := sherpa.NewFileListingHash(src)
+	if err != nil {
+		return libcnb.Layer{}, fmt.Errorf("unable to create file listing for %s\n%w", src, err)
+	}
+
+	contributor := libpak.NewLayerContributor("Native Image Launcher", map[string]interface{}{
+		"launcher": hash,
+	}, libcnb.LayerTypes{
+		Launch: true,
+	})
+	contributor.Logger = l.Logger
+
+	layer, err = contributor.Contribute(layer, func() (libcnb.Layer, error) {
+		dst := l.Path(layer.Path)
+		l.Logger.Bodyf("Copying launcher to %s", filepath.Dir(dst))
+
+		if err := copyFile(src, dst); err != nil {
+			return libcnb.Layer{}, fmt.Errorf("unable to copy %s to %s\n%w", src, dst, err)
+		}
+
+		return layer, nil
+	})
+	if err != nil {
+		return libcnb.Layer{}, fmt.Errorf("unable to contribute native-image-launcher layer\n%w", err)
+	}
+
+	return layer, nil
+}
+
+func (Launcher) Name() string {
+	return "native-image-launcher"
+}
+
+// Path returns the path of the launcher in the layer at layerPath
+func (Launcher) Path(layerPath string) string {
+	return filepath.Join(layerPath, "bin", "launcher")
+}
+
+// Process creates the process starting executable, built for each of variants into its own directory of appPath or
+// into appPath itself if there are no variants, through the launcher in the layer at layerPath
+func (l Launcher) Process(layerPath string, appPath string, executable string, variants []string, process libcnb.Process) libcnb.Process {
+	process.Command = l.Path(layerPath)
+	process.Arguments = append(append(append([]string{appPath, executable}, variants...), "--"), process.Arguments...)
+	process.Direct = true
+	return process
+}
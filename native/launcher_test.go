@@ -0,0 +1,86 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package native_test
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/buildpacks/libcnb"
+	. "github.com/onsi/gomega"
+	"github.com/paketo-buildpacks/libpak/bard"
+	"github.com/sclevine/spec"
+
+	"github.com/paketo-buildpacks/native-image/v5/native"
+)
+
+func testLauncher(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		ctx libcnb.BuildContext
+	)
+
+	it.Before(func() {
+		ctx.Buildpack.Path = t.TempDir()
+		ctx.Layers.Path = t.TempDir()
+
+		Expect(os.MkdirAll(filepath.Join(ctx.Buildpack.Path, "bin"), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(ctx.Buildpack.Path, "bin", "launcher"), []byte("test-launcher"), 0755)).To(Succeed())
+	})
+
+	it("contributes the launcher to a launch layer", func() {
+		l := native.NewLauncher(ctx.Buildpack.Path)
+		l.Logger = bard.NewLogger(io.Discard)
+
+		layer, err := ctx.Layers.Layer(l.Name())
+		Expect(err).NotTo(HaveOccurred())
+
+		layer, err = l.Contribute(layer)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(layer.Launch).To(BeTrue())
+		Expect(layer.Cache).To(BeFalse())
+		Expect(os.ReadFile(filepath.Join(layer.Path, "bin", "launcher"))).To(Equal([]byte("test-launcher")))
+
+		info, err := os.Stat(filepath.Join(layer.Path, "bin", "launcher"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Mode().Perm() & 0100).NotTo(BeZero())
+	})
+
+	it("starts the variants through the launcher", func() {
+		p := native.NewLauncher(ctx.Buildpack.Path).Process("/layers/launcher", "/workspace", "test-start-class",
+			[]string{"x86-64-v3", "compatibility"}, libcnb.Process{Type: "web", Command: "./test-start-class", Default: true})
+
+		Expect(p).To(Equal(libcnb.Process{
+			Type:      "web",
+			Command:   "/layers/launcher/bin/launcher",
+			Arguments: []string{"/workspace", "test-start-class", "x86-64-v3", "compatibility", "--"},
+			Direct:    true,
+			Default:   true,
+		}))
+	})
+
+	it("passes the arguments of the process after the variants", func() {
+		p := native.NewLauncher(ctx.Buildpack.Path).Process("/layers/launcher", "/workspace", "test-start-class",
+			nil, libcnb.Process{Type: "worker", Command: "./test-start-class", Arguments: []string{"--worker"}})
+
+		Expect(p.Arguments).To(Equal([]string{"/workspace", "test-start-class", "--", "--worker"}))
+	})
+}
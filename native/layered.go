@@ -0,0 +1,260 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package native
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/buildpacks/libcnb"
+	"github.com/paketo-buildpacks/libpak"
+	"github.com/paketo-buildpacks/libpak/bard"
+	"github.com/paketo-buildpacks/libpak/effect"
+	"github.com/paketo-buildpacks/libpak/sherpa"
+
+	"github.com/paketo-buildpacks/native-image/v5/native/slices"
+)
+
+const (
+	// DependenciesLayerFile is the native image layer file created from the library JARs
+	DependenciesLayerFile = "dependencies.nil"
+
+	// MinimumLayeredImageVersion is the first Java version whose native-image supports layered images
+	MinimumLayeredImageVersion = 25
+)
+
+// SupportsLayeredImages returns whether the native-image that printed version supports --layer-create and
+// --layer-use
+func SupportsLayeredImages(version string) bool {
+	major, ok := nativeImageMajorVersion(version)
+	return ok && major >= MinimumLayeredImageVersion
+}
+
+// LibraryJARs returns the third-party JARs on classPath, expanding wildcard entries. Directories, which hold the
+// application classes, and entries that do not exist are omitted.
+func LibraryJARs(classPath string) ([]string, error) {
+	var jars []string
+
+	for _, entry := range filepath.SplitList(classPath) {
+		candidates := []string{entry}
+		if strings.HasSuffix(entry, "*") {
+			matches, err := filepath.Glob(entry + ".jar")
+			if err != nil {
+				return nil, fmt.Errorf("unable to expand %s\n%w", entry, err)
+			}
+			candidates = matches
+		}
+
+		for _, c := range candidates {
+			if !strings.HasSuffix(c, ".jar") {
+				continue
+			}
+
+			if ok, err := sherpa.FileExists(c); err != nil {
+				return nil, fmt.Errorf("unable to check for %s\n%w", c, err)
+			} else if ok {
+				jars = append(jars, c)
+			}
+		}
+	}
+
+	return jars, nil
+}
+
+// NativeImageDependencies builds a base native image layer from the library JARs on the class path so that only the
+// application layer has to be built when the application code changes. The layer is cached and keyed by the
+// dependency set and options.
+type NativeImageDependencies struct {
+	Arguments     string
+	ArgumentsFile string
+	ClassPath     string
+	Executor      effect.Executor
+	Logger        bard.Logger
+	StackID       string
+	Target        Target
+
+	// March, GC, DebugInfo, PlanMetadata, BindingPath and the user arguments configure the layer with the same options
+	// as the application layer, which native-image requires to use it
+	March        string
+	GC           string
+	DebugInfo    string
+	PlanMetadata PlanMetadata
+	BindingPath  string
+}
+
+func NewNativeImageDependencies(arguments string, classPath string, stackID string) NativeImageDependencies {
+	return NativeImageDependencies{
+		Arguments: arguments,
+		ClassPath: classPath,
+		Executor:  effect.NewExecutor(),
+		StackID:   stackID,
+		DebugInfo: DebugInfoNone,
+	}
+}
+
+func (d NativeImageDependencies) Contribute(layer libcnb.Layer) (libcnb.Layer, error) {
+	buf := &bytes.Buffer{}
+	if err := d.Executor.Execute(effect.Execution{
+		Command: "native-image",
+		Args:    []string{"--version"},
+		Stdout:  buf,
+		Stderr:  d.Logger.BodyWriter(),
+	}); err != nil {
+		return libcnb.Layer{}, fmt.Errorf("error running version\n%w", err)
+	}
+
+	if !SupportsLayeredImages(buf.String()) {
+		warn(d.Logger, fmt.Sprintf("Layered native images require native-image %d or later, a single native image will be built instead",
+			MinimumLayeredImageVersion))
+		return layer, nil
+	}
+
+	jars, err := LibraryJARs(d.ClassPath)
+	if err != nil {
+		return libcnb.Layer{}, fmt.Errorf("unable to find library JARs\n%w", err)
+	}
+
+	if len(jars) == 0 {
+		d.Logger.Body("No library JARs found, a single native image will be built instead")
+		return layer, nil
+	}
+
+	inputs, err := NewInputDigest(jars...)
+	if err != nil {
+		return libcnb.Layer{}, fmt.Errorf("unable to compute digest of library JARs\n%w", err)
+	}
+
+	arguments, err := d.options()
+	if err != nil {
+		return libcnb.Layer{}, err
+	}
+
+	// argument files and configuration directories are digested, as the options they hold have to be those of the
+	// application layer
+	options, err := NewInputDigest(InputPaths(arguments)...)
+	if err != nil {
+		return libcnb.Layer{}, fmt.Errorf("unable to compute digest of options\n%w", err)
+	}
+
+	create := []string{filepath.Join(layer.Path, DependenciesLayerFile)}
+	for _, jar := range jars {
+		create = append(create, fmt.Sprintf("path=%s", jar))
+	}
+
+	arguments = append(arguments,
+		fmt.Sprintf("--layer-create=%s", strings.Join(create, ",")),
+		fmt.Sprintf("-H:Name=%s", filepath.Join(layer.Path, "libdependencies")),
+		"-cp", strings.Join(jars, string(filepath.ListSeparator)),
+	)
+
+	contributor := libpak.NewLayerContributor("Native Image Dependencies", map[string]interface{}{
+		"arguments":    arguments,
+		"dependencies": inputs.Root,
+		"options":      options.Root,
+		"version-hash": fmt.Sprintf("%x", sha256.Sum256(buf.Bytes())),
+	}, libcnb.LayerTypes{
+		Cache: true,
+	})
+	contributor.Logger = d.Logger
+
+	layer, err = contributor.Contribute(layer, func() (libcnb.Layer, error) {
+		d.Logger.Bodyf("Building native image layer from %d library JARs", len(jars))
+		d.Logger.Bodyf("Executing native-image %s", strings.Join(arguments, " "))
+		if err := d.Executor.Execute(effect.Execution{
+			Command: "native-image",
+			Args:    arguments,
+			Dir:     layer.Path,
+			Stdout:  d.Logger.InfoWriter(),
+			Stderr:  d.Logger.InfoWriter(),
+		}); err != nil {
+			return libcnb.Layer{}, fmt.Errorf("error running build\n%w", err)
+		}
+
+		return layer, nil
+	})
+	if err != nil {
+		return libcnb.Layer{}, fmt.Errorf("unable to contribute native-image-dependencies layer\n%w", err)
+	}
+
+	return layer, nil
+}
+
+// options returns the arguments that configure native-image in the same order as the application layer, without the
+// class path and main class
+func (d NativeImageDependencies) options() ([]string, error) {
+	arguments, _, err := BaselineArguments{StackID: d.StackID, Target: d.Target, DebugInfo: d.DebugInfo != DebugInfoNone, GC: d.GC}.Configure(nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to set baseline arguments\n%w", err)
+	}
+
+	arguments, _, err = PlanArguments{Metadata: d.PlanMetadata}.Configure(arguments)
+	if err != nil {
+		return nil, fmt.Errorf("unable to append plan arguments\n%w", err)
+	}
+
+	arguments, _, err = BindingArguments{LayerPath: d.BindingPath}.Configure(arguments)
+	if err != nil {
+		return nil, fmt.Errorf("unable to append binding arguments\n%w", err)
+	}
+
+	if d.ArgumentsFile != "" {
+		arguments, _, err = UserFileArguments{ArgumentsFile: d.ArgumentsFile}.Configure(arguments)
+		if err != nil {
+			return nil, fmt.Errorf("unable to create user file arguments\n%w", err)
+		}
+	}
+
+	arguments, _, err = UserArguments{Arguments: d.Arguments}.Configure(arguments)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create user arguments\n%w", err)
+	}
+
+	if d.March != "" {
+		arguments = append([]string{fmt.Sprintf("-march=%s", d.March)}, arguments...)
+	}
+
+	if !slices.Contains(arguments, "--auto-fallback") && !slices.Contains(arguments, "--force-fallback") {
+		arguments = append([]string{"--no-fallback"}, arguments...)
+	}
+
+	return arguments, nil
+}
+
+func (NativeImageDependencies) Name() string {
+	return "native-image-dependencies"
+}
+
+// layerUseArgument returns the --layer-use argument for the dependencies layer, if the native-image that printed
+// version supports layered images and the dependencies layer has been built
+func (n NativeImage) layerUseArgument(version string) (string, bool, error) {
+	if n.DependenciesLayerPath == "" || !SupportsLayeredImages(version) {
+		return "", false, nil
+	}
+
+	file := filepath.Join(n.DependenciesLayerPath, DependenciesLayerFile)
+	if _, err := os.Stat(file); os.IsNotExist(err) {
+		return "", false, nil
+	} else if err != nil {
+		return "", false, fmt.Errorf("unable to check for %s\n%w", file, err)
+	}
+
+	return fmt.Sprintf("--layer-use=%s", file), true, nil
+}
@@ -0,0 +1,200 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package native_test
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/buildpacks/libcnb"
+	. "github.com/onsi/gomega"
+	"github.com/paketo-buildpacks/libpak/bard"
+	"github.com/paketo-buildpacks/libpak/effect"
+	"github.com/paketo-buildpacks/libpak/effect/mocks"
+	"github.com/sclevine/spec"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/paketo-buildpacks/native-image/v5/native"
+)
+
+func testLayered(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		ctx      libcnb.BuildContext
+		executor *mocks.Executor
+		version  string
+	)
+
+	it.Before(func() {
+		ctx.Application.Path = t.TempDir()
+		ctx.Layers.Path = t.TempDir()
+
+		executor = &mocks.Executor{}
+		version = "native-image 25.0.1 2025-10-21"
+
+		executor.On("Execute", mock.MatchedBy(func(e effect.Execution) bool {
+			return e.Command == "native-image" && len(e.Args) == 1 && e.Args[0] == "--version"
+		})).Run(func(args mock.Arguments) {
+			_, err := args.Get(0).(effect.Execution).Stdout.Write([]byte(version))
+			Expect(err).NotTo(HaveOccurred())
+		}).Return(nil)
+
+		executor.On("Execute", mock.Anything).Return(nil)
+
+		Expect(os.MkdirAll(filepath.Join(ctx.Application.Path, "BOOT-INF", "classes"), 0755)).To(Succeed())
+		Expect(os.MkdirAll(filepath.Join(ctx.Application.Path, "BOOT-INF", "lib"), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "BOOT-INF", "lib", "a.jar"), []byte("a"), 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "BOOT-INF", "lib", "b.jar"), []byte("b"), 0644)).To(Succeed())
+	})
+
+	context("SupportsLayeredImages", func() {
+		it("supports Java 25 and later", func() {
+			Expect(native.SupportsLayeredImages("native-image 25.0.1 2025-10-21\nGraalVM Runtime Environment")).To(BeTrue())
+			Expect(native.SupportsLayeredImages("native-image 26 2026-03-17")).To(BeTrue())
+		})
+
+		it("does not support earlier versions", func() {
+			Expect(native.SupportsLayeredImages("native-image 21.0.2 2024-01-16")).To(BeFalse())
+			Expect(native.SupportsLayeredImages("GraalVM 22.3.0 Java 17 CE (Java Version 17.0.5+8-jvmci-22.3-b08)")).To(BeFalse())
+			Expect(native.SupportsLayeredImages("1.2.3")).To(BeFalse())
+		})
+	})
+
+	context("LibraryJARs", func() {
+		it("returns JARs and omits directories and missing entries", func() {
+			jars, err := native.LibraryJARs(filepath.Join(ctx.Application.Path, "BOOT-INF", "classes") +
+				string(filepath.ListSeparator) + filepath.Join(ctx.Application.Path, "BOOT-INF", "lib", "a.jar") +
+				string(filepath.ListSeparator) + filepath.Join(ctx.Application.Path, "missing.jar"))
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(jars).To(Equal([]string{filepath.Join(ctx.Application.Path, "BOOT-INF", "lib", "a.jar")}))
+		})
+
+		it("expands wildcards", func() {
+			jars, err := native.LibraryJARs(filepath.Join(ctx.Application.Path, "BOOT-INF", "lib", "*"))
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(jars).To(Equal([]string{
+				filepath.Join(ctx.Application.Path, "BOOT-INF", "lib", "a.jar"),
+				filepath.Join(ctx.Application.Path, "BOOT-INF", "lib", "b.jar"),
+			}))
+		})
+	})
+
+	context("NativeImageDependencies", func() {
+		var (
+			dependencies native.NativeImageDependencies
+			layer        libcnb.Layer
+		)
+
+		it.Before(func() {
+			dependencies = native.NewNativeImageDependencies("-O3",
+				filepath.Join(ctx.Application.Path, "BOOT-INF", "classes")+string(filepath.ListSeparator)+
+					filepath.Join(ctx.Application.Path, "BOOT-INF", "lib", "*"), "")
+			dependencies.Executor = executor
+			dependencies.Logger = bard.NewLogger(io.Discard)
+
+			var err error
+			layer, err = ctx.Layers.Layer("native-image-dependencies")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		it("builds a layer from the library JARs", func() {
+			layer, err := dependencies.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(layer.Cache).To(BeTrue())
+			Expect(layer.Metadata).To(HaveKey("dependencies"))
+
+			execution := executor.Calls[1].Arguments[0].(effect.Execution)
+			a := filepath.Join(ctx.Application.Path, "BOOT-INF", "lib", "a.jar")
+			b := filepath.Join(ctx.Application.Path, "BOOT-INF", "lib", "b.jar")
+			Expect(execution.Args).To(Equal([]string{
+				"--no-fallback",
+				"-O3",
+				fmt.Sprintf("--layer-create=%s,path=%s,path=%s", filepath.Join(layer.Path, "dependencies.nil"), a, b),
+				fmt.Sprintf("-H:Name=%s", filepath.Join(layer.Path, "libdependencies")),
+				"-cp", a + string(filepath.ListSeparator) + b,
+			}))
+			Expect(execution.Dir).To(Equal(layer.Path))
+		})
+
+		it("keys the layer on the dependency set", func() {
+			layer, err := dependencies.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+			before := layer.Metadata["dependencies"]
+
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "BOOT-INF", "lib", "b.jar"), []byte("changed"), 0644)).To(Succeed())
+
+			layer, err = dependencies.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(layer.Metadata["dependencies"]).NotTo(Equal(before))
+		})
+
+		it("builds the layer with the options of the application layer", func() {
+			config := filepath.Join(ctx.Application.Path, "META-INF", "native-image")
+			Expect(os.MkdirAll(config, 0755)).To(Succeed())
+			dependencies.PlanMetadata = native.PlanMetadata{Arguments: []string{"--enable-preview"}, ConfigDirectories: []string{config}}
+			dependencies.ArgumentsFile = filepath.Join(t.TempDir(), "native-image.args")
+			Expect(os.WriteFile(dependencies.ArgumentsFile, []byte("-H:+UnlockExperimentalVMOptions\n"), 0644)).To(Succeed())
+
+			layer, err := dependencies.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			execution := executor.Calls[1].Arguments[0].(effect.Execution)
+			Expect(execution.Args[:5]).To(Equal([]string{
+				"--no-fallback",
+				"--enable-preview",
+				fmt.Sprintf("-H:ConfigurationFileDirectories=%s", config),
+				fmt.Sprintf("@%s", dependencies.ArgumentsFile),
+				"-O3",
+			}))
+			before := layer.Metadata["options"]
+
+			Expect(os.WriteFile(dependencies.ArgumentsFile, []byte("-H:-UnlockExperimentalVMOptions\n"), 0644)).To(Succeed())
+
+			layer, err = dependencies.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(layer.Metadata["options"]).NotTo(Equal(before))
+			Expect(executor.Calls).To(HaveLen(4))
+		})
+
+		it("does nothing if native-image does not support layered images", func() {
+			version = "native-image 21.0.2 2024-01-16"
+
+			layer, err := dependencies.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(layer.Cache).To(BeFalse())
+			Expect(executor.Calls).To(HaveLen(1))
+		})
+
+		it("does nothing without library JARs", func() {
+			dependencies.ClassPath = filepath.Join(ctx.Application.Path, "BOOT-INF", "classes")
+
+			layer, err := dependencies.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(layer.Cache).To(BeFalse())
+			Expect(executor.Calls).To(HaveLen(1))
+		})
+	})
+}
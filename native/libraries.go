@@ -0,0 +1,208 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package native
+
+import (
+	"debug/elf"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	VerifyLibrariesNone = "none"
+	VerifyLibrariesWarn = "warn"
+	VerifyLibrariesFail = "fail"
+
+	glibcVersionPrefix = "GLIBC_"
+)
+
+// glibcLibraries are the libraries provided by glibc itself
+var glibcLibraries = []string{
+	"ld-linux*.so.*",
+	"libc.so.6",
+	"libdl.so.2",
+	"libm.so.6",
+	"libpthread.so.0",
+	"libresolv.so.2",
+	"librt.so.1",
+}
+
+// opensslLibraries are the libraries provided by OpenSSL 3
+var opensslLibraries = []string{
+	"libcrypto.so.3",
+	"libssl.so.3",
+}
+
+// DefaultLibraryAllowlist is the shared libraries, as patterns, provided by each run image flavor. The base and tiny
+// run images ship glibc, OpenSSL and zlib, and the base run images the GCC runtime as well. Distroless run images only
+// ship glibc and OpenSSL.
+var DefaultLibraryAllowlist = map[string][]string{
+	RunImageFlavorBase:       libraries([]string{"libgcc_s.so.1", "libstdc++.so.6", "libz.so.1"}, glibcLibraries, opensslLibraries),
+	RunImageFlavorTiny:       libraries([]string{"libz.so.1"}, glibcLibraries, opensslLibraries),
+	RunImageFlavorDistroless: libraries(glibcLibraries, opensslLibraries),
+	RunImageFlavorStatic:     {},
+}
+
+func libraries(groups ...[]string) []string {
+	var l []string
+	for _, g := range groups {
+		l = append(l, g...)
+	}
+	return l
+}
+
+// ELFDependencies are the shared libraries and glibc symbol versions required by an ELF file
+type ELFDependencies struct {
+	Libraries     []string
+	GLIBCVersions []string
+}
+
+// NewELFDependencies reads the DT_NEEDED entries and the required glibc symbol versions of the ELF file at path. It
+// returns false if path is not an ELF file.
+func NewELFDependencies(path string) (ELFDependencies, bool, error) {
+	f, err := elf.Open(path)
+	if _, ok := err.(*elf.FormatError); ok {
+		return ELFDependencies{}, false, nil
+	} else if err != nil {
+		return ELFDependencies{}, false, fmt.Errorf("unable to open %s\n%w", path, err)
+	}
+	defer f.Close()
+
+	var d ELFDependencies
+
+	if d.Libraries, err = f.ImportedLibraries(); err != nil {
+		return ELFDependencies{}, false, fmt.Errorf("unable to read shared libraries of %s\n%w", path, err)
+	}
+
+	symbols, err := f.ImportedSymbols()
+	if err != nil {
+		return ELFDependencies{}, false, fmt.Errorf("unable to read symbols of %s\n%w", path, err)
+	}
+
+	versions := map[string]bool{}
+	for _, s := range symbols {
+		if strings.HasPrefix(s.Version, glibcVersionPrefix) {
+			versions[strings.TrimPrefix(s.Version, glibcVersionPrefix)] = true
+		}
+	}
+	for v := range versions {
+		d.GLIBCVersions = append(d.GLIBCVersions, v)
+	}
+	sort.Slice(d.GLIBCVersions, func(i, j int) bool {
+		return compareVersions(d.GLIBCVersions[i], d.GLIBCVersions[j]) < 0
+	})
+
+	return d, true, nil
+}
+
+// LibraryVerifier verifies that the shared libraries and glibc version required by a native image are provided by
+// the run image
+type LibraryVerifier struct {
+	Allowed      []string
+	GLIBCVersion string
+	Mode         string
+}
+
+// NewLibraryVerifier creates a verifier for runImage, allowing the libraries of its flavor and additional patterns
+func NewLibraryVerifier(mode string, runImage RunImage, additional []string) LibraryVerifier {
+	return LibraryVerifier{
+		Allowed:      append(append([]string{}, DefaultLibraryAllowlist[runImage.Flavor]...), additional...),
+		GLIBCVersion: runImage.GLIBCVersion,
+		Mode:         mode,
+	}
+}
+
+// Enabled returns whether the libraries are verified
+func (v LibraryVerifier) Enabled() bool {
+	return v.Mode != "" && v.Mode != VerifyLibrariesNone
+}
+
+// Verify checks the executable execName and the shared libraries in layerPath, which are shipped alongside it, and
+// returns the problems found. The shared libraries in libraryPaths, such as the base layer of a layered native image,
+// are shipped alongside it too.
+func (v LibraryVerifier) Verify(layerPath string, execName string, libraryPaths ...string) ([]string, error) {
+	files := []string{filepath.Join(layerPath, execName)}
+	shipped := map[string]bool{}
+
+	for _, path := range append([]string{layerPath}, libraryPaths...) {
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list files in %s\n%w", path, err)
+		}
+		for _, e := range entries {
+			if e.Type().IsRegular() && strings.HasSuffix(e.Name(), ".so") {
+				files = append(files, filepath.Join(path, e.Name()))
+				shipped[e.Name()] = true
+			}
+		}
+	}
+
+	var problems []string
+	for _, path := range files {
+		file := filepath.Base(path)
+		d, ok, err := NewELFDependencies(path)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read dependencies of %s\n%w", file, err)
+		} else if !ok {
+			continue
+		}
+
+		for _, lib := range d.Libraries {
+			if !shipped[lib] && !v.allowed(lib) {
+				problems = append(problems, fmt.Sprintf("%s requires %s, which is not provided by the run image", file, lib))
+			}
+		}
+
+		if n := len(d.GLIBCVersions); n > 0 && v.GLIBCVersion != "" && compareVersions(d.GLIBCVersions[n-1], v.GLIBCVersion) > 0 {
+			problems = append(problems, fmt.Sprintf("%s requires glibc %s, the run image provides glibc %s",
+				file, d.GLIBCVersions[n-1], v.GLIBCVersion))
+		}
+	}
+
+	return problems, nil
+}
+
+func (v LibraryVerifier) allowed(library string) bool {
+	for _, pattern := range v.Allowed {
+		if ok, _ := filepath.Match(pattern, library); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// compareVersions compares dotted numeric versions such as 2.3.4
+func compareVersions(a string, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		if x != y {
+			return x - y
+		}
+	}
+	return 0
+}
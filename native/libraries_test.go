@@ -0,0 +1,132 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package native_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/paketo-buildpacks/libpak/sherpa"
+	"github.com/sclevine/spec"
+
+	"github.com/paketo-buildpacks/native-image/v5/native"
+)
+
+func testLibraries(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		layerPath string
+	)
+
+	it.Before(func() {
+		layerPath = t.TempDir()
+
+		in, err := os.Open("testdata/dynamic-fixture")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sherpa.CopyFile(in, filepath.Join(layerPath, "test-start-class"))).To(Succeed())
+	})
+
+	it("reads the shared libraries and glibc versions", func() {
+		d, ok, err := native.NewELFDependencies(filepath.Join(layerPath, "test-start-class"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+
+		Expect(d.Libraries).To(ConsistOf("libz.so.1", "libc.so.6"))
+		Expect(d.GLIBCVersions).To(Equal([]string{"2.2.5", "2.34"}))
+	})
+
+	it("ignores files that are not ELF files", func() {
+		Expect(os.WriteFile(filepath.Join(layerPath, "script"), []byte("#!/bin/sh"), 0755)).To(Succeed())
+
+		_, ok, err := native.NewELFDependencies(filepath.Join(layerPath, "script"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeFalse())
+	})
+
+	it("reports libraries not provided by the run image", func() {
+		v := native.NewLibraryVerifier(native.VerifyLibrariesFail, native.RunImage{Flavor: native.RunImageFlavorDistroless}, nil)
+
+		problems, err := v.Verify(layerPath, "test-start-class")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(problems).To(Equal([]string{"test-start-class requires libz.so.1, which is not provided by the run image"}))
+	})
+
+	it("allows the libraries of the run image", func() {
+		for _, flavor := range []string{native.RunImageFlavorBase, native.RunImageFlavorTiny} {
+			v := native.NewLibraryVerifier(native.VerifyLibrariesFail, native.RunImage{Flavor: flavor}, nil)
+
+			Expect(v.Verify(layerPath, "test-start-class")).To(BeEmpty())
+		}
+	})
+
+	it("allows additional libraries", func() {
+		v := native.NewLibraryVerifier(native.VerifyLibrariesFail, native.RunImage{Flavor: native.RunImageFlavorDistroless}, []string{"libz.so.*"})
+
+		Expect(v.Verify(layerPath, "test-start-class")).To(BeEmpty())
+	})
+
+	it("checks the libraries of the shipped shared libraries", func() {
+		in, err := os.Open("testdata/dynamic-fixture")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sherpa.CopyFile(in, filepath.Join(layerPath, "libawt.so"))).To(Succeed())
+
+		v := native.NewLibraryVerifier(native.VerifyLibrariesFail, native.RunImage{Flavor: native.RunImageFlavorDistroless}, nil)
+
+		problems, err := v.Verify(layerPath, "test-start-class")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(problems).To(ConsistOf(
+			"test-start-class requires libz.so.1, which is not provided by the run image",
+			"libawt.so requires libz.so.1, which is not provided by the run image",
+		))
+	})
+
+	it("counts the shared libraries of other paths as shipped", func() {
+		in, err := os.Open("testdata/layered-fixture")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sherpa.CopyFile(in, filepath.Join(layerPath, "test-start-class"))).To(Succeed())
+
+		dependenciesPath := t.TempDir()
+		Expect(os.WriteFile(filepath.Join(dependenciesPath, "libdependencies.so"), []byte{}, 0644)).To(Succeed())
+
+		v := native.NewLibraryVerifier(native.VerifyLibrariesFail, native.RunImage{Flavor: native.RunImageFlavorDistroless}, nil)
+
+		problems, err := v.Verify(layerPath, "test-start-class")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(problems).To(Equal([]string{"test-start-class requires libdependencies.so, which is not provided by the run image"}))
+
+		Expect(v.Verify(layerPath, "test-start-class", dependenciesPath)).To(BeEmpty())
+	})
+
+	it("reports a glibc version newer than the run image provides", func() {
+		v := native.NewLibraryVerifier(native.VerifyLibrariesFail, native.RunImage{Flavor: native.RunImageFlavorBase, GLIBCVersion: "2.27"}, []string{"libz.so.1"})
+
+		problems, err := v.Verify(layerPath, "test-start-class")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(problems).To(Equal([]string{"test-start-class requires glibc 2.34, the run image provides glibc 2.27"}))
+
+		v.GLIBCVersion = "2.35"
+		Expect(v.Verify(layerPath, "test-start-class")).To(BeEmpty())
+	})
+
+	it("is disabled with none", func() {
+		Expect(native.NewLibraryVerifier(native.VerifyLibrariesNone, native.RunImage{}, nil).Enabled()).To(BeFalse())
+		Expect(native.NewLibraryVerifier(native.VerifyLibrariesWarn, native.RunImage{}, nil).Enabled()).To(BeTrue())
+	})
+}
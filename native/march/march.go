@@ -0,0 +1,132 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package march selects the variant of a native image, built with `-march`, that is best suited to the CPU it runs on.
+// It has no dependencies, so that it can be used by the launcher without increasing its size.
+package march
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+const (
+	// Compatibility is the most portable microarchitecture supported by native-image on each architecture
+	Compatibility = "compatibility"
+
+	// Native is the microarchitecture of the CPU building the native image
+	Native = "native"
+)
+
+// x86-64 feature levels as defined by the x86-64 psABI, using the flag names of /proc/cpuinfo
+var (
+	x86v2 = []string{"cx16", "lahf_lm", "popcnt", "sse4_1", "sse4_2", "ssse3"}
+	x86v3 = append(append([]string{}, x86v2...), "abm", "avx", "avx2", "bmi1", "bmi2", "f16c", "fma", "movbe", "xsave")
+	x86v4 = append(append([]string{}, x86v3...), "avx512bw", "avx512cd", "avx512dq", "avx512f", "avx512vl")
+)
+
+// requirements are the CPU features required by each microarchitecture, by architecture
+var requirements = map[string]map[string][]string{
+	"amd64": {
+		Compatibility: {},
+		"x86-64":      {},
+		"x86-64-v1":   {},
+		"x86-64-v2":   x86v2,
+		"x86-64-v3":   x86v3,
+		"x86-64-v4":   x86v4,
+	},
+	"arm64": {
+		Compatibility: {},
+		"armv8-a":     {},
+		"armv8.1-a":   {"asimdrdm", "atomics", "crc32"},
+	},
+}
+
+// Known returns whether the CPU features required by march on arch are known, so that it can be selected at launch
+func Known(march string, arch string) bool {
+	_, ok := requirements[arch][march]
+	return ok
+}
+
+// Names returns the microarchitectures that can be selected at launch on arch
+func Names(arch string) []string {
+	var names []string
+	for name := range requirements[arch] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Features reads the CPU features from the `flags` (x86) or `Features` (arm) line of /proc/cpuinfo. All processors are
+// assumed to have the same features, so only the first one is read.
+func Features(cpuinfo io.Reader) (map[string]bool, error) {
+	scanner := bufio.NewScanner(cpuinfo)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+
+		if key = strings.TrimSpace(key); key == "flags" || key == "Features" {
+			features := map[string]bool{}
+			for _, f := range strings.Fields(value) {
+				features[f] = true
+			}
+			return features, nil
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read cpuinfo\n%w", err)
+	}
+
+	return nil, fmt.Errorf("no CPU features found in cpuinfo")
+}
+
+// Supported returns whether a CPU with features can run a native image built for march
+func Supported(march string, features map[string]bool) bool {
+	for _, required := range requirements {
+		r, ok := required[march]
+		if !ok {
+			continue
+		}
+
+		supported := true
+		for _, f := range r {
+			supported = supported && features[f]
+		}
+		if supported {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Select returns the first of variants, ordered from the most to the least preferred, that is supported by a CPU with
+// features
+func Select(variants []string, features map[string]bool) (string, bool) {
+	for _, v := range variants {
+		if Supported(v, features) {
+			return v, true
+		}
+	}
+	return "", false
+}
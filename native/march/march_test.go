@@ -0,0 +1,96 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package march_test
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/paketo-buildpacks/native-image/v5/native/march"
+)
+
+func TestUnit(t *testing.T) {
+	suite := spec.New("march", spec.Report(report.Terminal{}))
+	suite("March", testMarch)
+	suite.Run(t)
+}
+
+func testMarch(t *testing.T, context spec.G, it spec.S) {
+	var Expect = NewWithT(t).Expect
+
+	context("Features", func() {
+		it("reads the flags of x86 CPUs", func() {
+			features, err := march.Features(strings.NewReader("processor\t: 0\nvendor_id\t: GenuineIntel\nflags\t\t: fpu sse4_2 avx2\n\nprocessor\t: 1\nflags\t\t: fpu\n"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(features).To(Equal(map[string]bool{"fpu": true, "sse4_2": true, "avx2": true}))
+		})
+
+		it("reads the features of arm CPUs", func() {
+			features, err := march.Features(strings.NewReader("processor\t: 0\nFeatures\t: fp asimd atomics\n"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(features).To(HaveKey("atomics"))
+		})
+
+		it("fails without features", func() {
+			_, err := march.Features(strings.NewReader("processor\t: 0\n"))
+			Expect(err).To(MatchError("no CPU features found in cpuinfo"))
+		})
+	})
+
+	context("Select", func() {
+		v3 := map[string]bool{}
+		for _, f := range strings.Fields("cx16 lahf_lm popcnt sse4_1 sse4_2 ssse3 abm avx avx2 bmi1 bmi2 f16c fma movbe xsave") {
+			v3[f] = true
+		}
+
+		it("selects the most preferred supported variant", func() {
+			variant, ok := march.Select([]string{"x86-64-v4", "x86-64-v3", "compatibility"}, v3)
+			Expect(ok).To(BeTrue())
+			Expect(variant).To(Equal("x86-64-v3"))
+		})
+
+		it("falls back to compatibility", func() {
+			variant, ok := march.Select([]string{"x86-64-v3", "compatibility"}, map[string]bool{"sse2": true})
+			Expect(ok).To(BeTrue())
+			Expect(variant).To(Equal("compatibility"))
+		})
+
+		it("selects arm variants", func() {
+			variant, ok := march.Select([]string{"armv8.1-a", "armv8-a"}, map[string]bool{"asimdrdm": true, "atomics": true, "crc32": true})
+			Expect(ok).To(BeTrue())
+			Expect(variant).To(Equal("armv8.1-a"))
+		})
+
+		it("fails if no variant is supported", func() {
+			_, ok := march.Select([]string{"x86-64-v3"}, map[string]bool{})
+			Expect(ok).To(BeFalse())
+		})
+	})
+
+	context("Known", func() {
+		it("knows the microarchitectures of each architecture", func() {
+			Expect(march.Known("x86-64-v2", "amd64")).To(BeTrue())
+			Expect(march.Known("x86-64-v2", "arm64")).To(BeFalse())
+			Expect(march.Known("native", "amd64")).To(BeFalse())
+			Expect(march.Names("arm64")).To(Equal([]string{"armv8-a", "armv8.1-a", "compatibility"}))
+		})
+	})
+}
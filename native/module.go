@@ -0,0 +1,393 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package native
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/magiconair/properties"
+	"github.com/paketo-buildpacks/libjvm"
+	"github.com/paketo-buildpacks/libpak/bard"
+	"github.com/paketo-buildpacks/libpak/sherpa"
+)
+
+// ModuleDescriptorFile is the compiled module declaration of a Java module
+const ModuleDescriptorFile = "module-info.class"
+
+// ModuleDescriptor describes a Java module
+type ModuleDescriptor struct {
+	// Name is the name of the module
+	Name string
+
+	// MainClass is the main class recorded in the module declaration, if any
+	MainClass string
+}
+
+// ReadModuleDescriptor reads the name and main class of a module from its module-info.class
+func ReadModuleDescriptor(in io.Reader) (ModuleDescriptor, error) {
+	r := classReader{in: bufio.NewReader(in)}
+
+	if magic := r.u4(); r.err == nil && magic != 0xCAFEBABE {
+		return ModuleDescriptor{}, fmt.Errorf("not a class file")
+	}
+	r.skip(4)
+
+	// constant pool entries hold an offset of the name for UTF8, Class and Module entries
+	count := int(r.u2())
+	utf8 := make(map[int]string, count)
+	names := make(map[int]int, count)
+	for i := 1; i < count && r.err == nil; i++ {
+		switch tag := r.u1(); tag {
+		case 1:
+			utf8[i] = string(r.bytes(int(r.u2())))
+		case 7, 19:
+			names[i] = int(r.u2())
+		case 8, 16, 20:
+			r.skip(2)
+		case 15:
+			r.skip(3)
+		case 3, 4, 9, 10, 11, 12, 17, 18:
+			r.skip(4)
+		case 5, 6:
+			r.skip(8)
+			i++
+		default:
+			return ModuleDescriptor{}, fmt.Errorf("unknown constant pool tag %d", tag)
+		}
+	}
+
+	r.skip(6)
+	r.skip(2 * int(r.u2()))
+	for members := 0; members < 2; members++ {
+		for n := r.u2(); n > 0 && r.err == nil; n-- {
+			r.skip(6)
+			for a := r.u2(); a > 0 && r.err == nil; a-- {
+				r.skip(2)
+				r.skip(int(r.u4()))
+			}
+		}
+	}
+
+	var d ModuleDescriptor
+	for a := r.u2(); a > 0 && r.err == nil; a-- {
+		name := utf8[int(r.u2())]
+		length := int(r.u4())
+
+		switch name {
+		case "Module":
+			d.Name = utf8[names[int(r.u2())]]
+			r.skip(length - 2)
+		case "ModuleMainClass":
+			d.MainClass = strings.ReplaceAll(utf8[names[int(r.u2())]], "/", ".")
+			r.skip(length - 2)
+		default:
+			r.skip(length)
+		}
+	}
+
+	if r.err != nil {
+		return ModuleDescriptor{}, fmt.Errorf("unable to read class file\n%w", r.err)
+	}
+
+	if d.Name == "" {
+		return ModuleDescriptor{}, fmt.Errorf("no Module attribute found")
+	}
+
+	return d, nil
+}
+
+// classReader reads the big-endian values of a class file, recording the first error
+type classReader struct {
+	in  *bufio.Reader
+	err error
+}
+
+func (c *classReader) bytes(n int) []byte {
+	b := make([]byte, n)
+	if c.err == nil {
+		_, c.err = io.ReadFull(c.in, b)
+	}
+	return b
+}
+
+func (c *classReader) skip(n int) {
+	if c.err == nil {
+		_, c.err = c.in.Discard(n)
+	}
+}
+
+func (c *classReader) u1() uint8 {
+	return c.bytes(1)[0]
+}
+
+func (c *classReader) u2() uint16 {
+	return binary.BigEndian.Uint16(c.bytes(2))
+}
+
+func (c *classReader) u4() uint32 {
+	return binary.BigEndian.Uint32(c.bytes(4))
+}
+
+// ExplodedModule returns the module descriptor of the application exploded in path, if it is a module
+func ExplodedModule(path string) (ModuleDescriptor, bool, error) {
+	in, err := os.Open(filepath.Join(path, ModuleDescriptorFile))
+	if os.IsNotExist(err) {
+		return ModuleDescriptor{}, false, nil
+	} else if err != nil {
+		return ModuleDescriptor{}, false, fmt.Errorf("unable to open %s\n%w", filepath.Join(path, ModuleDescriptorFile), err)
+	}
+	defer in.Close()
+
+	d, err := ReadModuleDescriptor(in)
+	if err != nil {
+		return ModuleDescriptor{}, false, fmt.Errorf("unable to read %s\n%w", in.Name(), err)
+	}
+
+	return d, true, nil
+}
+
+// ModularJAR describes a JAR on the module path
+type ModularJAR struct {
+	Path       string
+	Manifest   *properties.Properties
+	Descriptor ModuleDescriptor
+}
+
+// IsMain returns whether the JAR is the one an application is started from
+func (m ModularJAR) IsMain() bool {
+	_, main := m.Manifest.Get("Main-Class")
+	_, agent := m.Manifest.Get("Launcher-Agent-Class")
+	return main || agent
+}
+
+// ReadModularJAR reads the module of a JAR from its module-info.class, at the root or in a versioned directory of a
+// multi-release JAR, or from the Automatic-Module-Name of its manifest. JARs without either have no module name.
+func ReadModularJAR(path string) (ModularJAR, error) {
+	manifest, err := libjvm.NewManifestFromJAR(path)
+	if err != nil {
+		return ModularJAR{}, fmt.Errorf("unable to read manifest of %s\n%w", path, err)
+	}
+
+	jar := ModularJAR{Path: path, Manifest: manifest}
+
+	z, err := zip.OpenReader(path)
+	if err != nil {
+		return ModularJAR{}, fmt.Errorf("unable to open %s\n%w", path, err)
+	}
+	defer z.Close()
+
+	var descriptors []string
+	for _, f := range z.File {
+		if f.Name == ModuleDescriptorFile ||
+			(strings.HasPrefix(f.Name, "META-INF/versions/") && strings.HasSuffix(f.Name, "/"+ModuleDescriptorFile)) {
+			descriptors = append(descriptors, f.Name)
+		}
+	}
+
+	if len(descriptors) > 0 {
+		// the root descriptor sorts last, otherwise each version declares the same module name
+		sort.Strings(descriptors)
+		in, err := z.Open(descriptors[len(descriptors)-1])
+		if err != nil {
+			return ModularJAR{}, fmt.Errorf("unable to open module descriptor of %s\n%w", path, err)
+		}
+		defer in.Close()
+
+		if jar.Descriptor, err = ReadModuleDescriptor(in); err != nil {
+			return ModularJAR{}, fmt.Errorf("unable to read module descriptor of %s\n%w", path, err)
+		}
+	} else {
+		jar.Descriptor.Name = manifest.GetString("Automatic-Module-Name", "")
+	}
+
+	return jar, nil
+}
+
+// ModuleArguments provides a set of arguments specific to building a modular application from the module path
+type ModuleArguments struct {
+	ApplicationPath string
+	LayerPath       string
+	Logger          bard.Logger
+
+	// MainModule is the main module, optionally followed by /main-class. If empty, it is the exploded application if
+	// it is a module, or the single modular JAR with a Main-Class or Launcher-Agent-Class.
+	MainModule string
+
+	// MainClass overrides the main class and ExecutableName the name of the executable, which defaults to the main
+	// class or the main module
+	MainClass      string
+	ExecutableName string
+}
+
+// IsModular returns whether the application at path is built from the module path, as the main module is configured
+// or the application is an exploded module
+func IsModular(path string, mainModule string) (bool, error) {
+	if mainModule != "" {
+		return true, nil
+	}
+
+	return sherpa.FileExists(filepath.Join(path, ModuleDescriptorFile))
+}
+
+// Configure appends arguments to inputArgs for building from the module path
+func (m ModuleArguments) Configure(inputArgs []string) ([]string, string, error) {
+	exploded, isExploded, err := ExplodedModule(m.ApplicationPath)
+	if err != nil {
+		return []string{}, "", fmt.Errorf("unable to check for exploded module\n%w", err)
+	}
+
+	jars, err := m.jars()
+	if err != nil {
+		return []string{}, "", fmt.Errorf("unable to find JARs\n%w", err)
+	}
+
+	var modulePath []string
+	if isExploded {
+		modulePath = append(modulePath, m.ApplicationPath)
+	}
+	for _, jar := range jars {
+		modulePath = append(modulePath, jar.Path)
+	}
+
+	module, mainClass, _ := strings.Cut(m.MainModule, "/")
+	if module == "" {
+		if module, mainClass, err = m.mainModule(exploded, isExploded, jars); err != nil {
+			return []string{}, "", err
+		}
+	} else if mainClass == "" {
+		mainClass = m.mainClassOf(module, exploded, isExploded, jars)
+	}
+
+	if m.MainClass != "" {
+		mainClass = m.MainClass
+	}
+
+	name := mainClass
+	if name == "" {
+		name = module
+	}
+	if m.ExecutableName != "" {
+		name = m.ExecutableName
+	}
+
+	target := module
+	if mainClass != "" {
+		target = fmt.Sprintf("%s/%s", module, mainClass)
+	}
+
+	inputArgs = append(inputArgs,
+		fmt.Sprintf("-H:Name=%s", filepath.Join(m.LayerPath, name)),
+		"--module-path", strings.Join(modulePath, string(filepath.ListSeparator)),
+		"--module", target,
+	)
+
+	return inputArgs, name, nil
+}
+
+// mainModule selects the main module and its main class, if any, when it is not configured
+func (m ModuleArguments) mainModule(exploded ModuleDescriptor, isExploded bool, jars []ModularJAR) (string, string, error) {
+	if isExploded {
+		m.Logger.Bodyf("Using exploded module %s as main module", exploded.Name)
+		return exploded.Name, m.mainClassOf(exploded.Name, exploded, isExploded, jars), nil
+	}
+
+	var candidates []ModularJAR
+	for _, jar := range jars {
+		if jar.IsMain() && jar.Descriptor.Name != "" {
+			candidates = append(candidates, jar)
+		}
+	}
+
+	if len(candidates) != 1 {
+		var names []string
+		for _, c := range candidates {
+			names = append(names, c.Descriptor.Name)
+		}
+		return "", "", fmt.Errorf("unable to select main module from %d modular JARs with a main class %s, set $%s",
+			len(candidates), names, ConfigNativeImageMainModule)
+	}
+
+	m.Logger.Bodyf("Using module %s of %s as main module", candidates[0].Descriptor.Name, filepath.Base(candidates[0].Path))
+	return candidates[0].Descriptor.Name, m.mainClassOf(candidates[0].Descriptor.Name, exploded, isExploded, jars), nil
+}
+
+// mainClassOf returns the main class of module from the Main-Class of its manifest or its module declaration. If
+// empty, native-image reads the main class from the module declaration.
+func (m ModuleArguments) mainClassOf(module string, exploded ModuleDescriptor, isExploded bool, jars []ModularJAR) string {
+	if isExploded && exploded.Name == module {
+		manifest, err := libjvm.NewManifest(m.ApplicationPath)
+		if err == nil {
+			if c, ok := manifest.Get("Main-Class"); ok {
+				return c
+			}
+		}
+		return exploded.MainClass
+	}
+
+	for _, jar := range jars {
+		if jar.Descriptor.Name == module {
+			if c, ok := jar.Manifest.Get("Main-Class"); ok {
+				return c
+			}
+			return jar.Descriptor.MainClass
+		}
+	}
+
+	return ""
+}
+
+// jars returns the JARs in the application, in path order
+func (m ModuleArguments) jars() ([]ModularJAR, error) {
+	var paths []string
+	if err := filepath.Walk(m.ApplicationPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() && info.Name() == ".git" {
+			return filepath.SkipDir
+		}
+
+		if info.Mode().IsRegular() && strings.HasSuffix(path, ".jar") {
+			paths = append(paths, path)
+		}
+		return nil
+	}); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("unable to walk %s\n%w", m.ApplicationPath, err)
+	}
+	sort.Strings(paths)
+
+	jars := make([]ModularJAR, 0, len(paths))
+	for _, path := range paths {
+		jar, err := ReadModularJAR(path)
+		if err != nil {
+			return nil, err
+		}
+		jars = append(jars, jar)
+	}
+
+	return jars, nil
+}
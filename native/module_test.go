@@ -0,0 +1,227 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package native_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+
+	"github.com/paketo-buildpacks/native-image/v5/native"
+)
+
+func testModule(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		appPath string
+	)
+
+	it.Before(func() {
+		appPath = t.TempDir()
+	})
+
+	context("ReadModuleDescriptor", func() {
+		it("reads the name and main class", func() {
+			d, err := native.ReadModuleDescriptor(bytes.NewReader(moduleInfo("org.example.app", "org/example/Main")))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(d).To(Equal(native.ModuleDescriptor{Name: "org.example.app", MainClass: "org.example.Main"}))
+		})
+
+		it("rejects other files", func() {
+			_, err := native.ReadModuleDescriptor(strings.NewReader("not a class file"))
+			Expect(err).To(MatchError("not a class file"))
+		})
+	})
+
+	context("ReadModularJAR", func() {
+		it("reads the module descriptor", func() {
+			writeJAR(t, filepath.Join(appPath, "app.jar"), map[string]string{
+				"META-INF/MANIFEST.MF": "Main-Class: org.example.Main\n",
+				"module-info.class":    string(moduleInfo("org.example.app", "")),
+			})
+
+			jar, err := native.ReadModularJAR(filepath.Join(appPath, "app.jar"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(jar.Descriptor.Name).To(Equal("org.example.app"))
+			Expect(jar.IsMain()).To(BeTrue())
+		})
+
+		it("reads the module descriptor of a multi-release JAR", func() {
+			writeJAR(t, filepath.Join(appPath, "lib.jar"), map[string]string{
+				"META-INF/versions/11/module-info.class": string(moduleInfo("org.example.lib", "")),
+			})
+
+			jar, err := native.ReadModularJAR(filepath.Join(appPath, "lib.jar"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(jar.Descriptor.Name).To(Equal("org.example.lib"))
+			Expect(jar.IsMain()).To(BeFalse())
+		})
+
+		it("reads the automatic module name", func() {
+			writeJAR(t, filepath.Join(appPath, "lib.jar"), map[string]string{
+				"META-INF/MANIFEST.MF": "Automatic-Module-Name: org.example.automatic\n",
+			})
+
+			jar, err := native.ReadModularJAR(filepath.Join(appPath, "lib.jar"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(jar.Descriptor.Name).To(Equal("org.example.automatic"))
+		})
+	})
+
+	context("IsModular", func() {
+		it("is modular if a main module is set", func() {
+			Expect(native.IsModular(appPath, "org.example.app")).To(BeTrue())
+		})
+
+		it("is modular if the application is an exploded module", func() {
+			Expect(native.IsModular(appPath, "")).To(BeFalse())
+
+			Expect(os.WriteFile(filepath.Join(appPath, "module-info.class"), moduleInfo("org.example.app", ""), 0644)).To(Succeed())
+			Expect(native.IsModular(appPath, "")).To(BeTrue())
+		})
+	})
+
+	context("ModuleArguments", func() {
+		it.Before(func() {
+			Expect(os.MkdirAll(filepath.Join(appPath, "lib"), 0755)).To(Succeed())
+			writeJAR(t, filepath.Join(appPath, "lib", "lib.jar"), map[string]string{
+				"META-INF/MANIFEST.MF": "Automatic-Module-Name: org.example.lib\n",
+			})
+		})
+
+		it("builds the exploded module", func() {
+			Expect(os.WriteFile(filepath.Join(appPath, "module-info.class"), moduleInfo("org.example.app", "org/example/Main"), 0644)).To(Succeed())
+
+			args, name, err := native.ModuleArguments{ApplicationPath: appPath, LayerPath: "/layer"}.Configure([]string{"stuff"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(name).To(Equal("org.example.Main"))
+			Expect(args).To(Equal([]string{
+				"stuff",
+				"-H:Name=/layer/org.example.Main",
+				"--module-path", fmt.Sprintf("%s:%s", appPath, filepath.Join(appPath, "lib", "lib.jar")),
+				"--module", "org.example.app/org.example.Main",
+			}))
+		})
+
+		it("selects the modular JAR with a main class", func() {
+			writeJAR(t, filepath.Join(appPath, "app.jar"), map[string]string{
+				"META-INF/MANIFEST.MF": "Main-Class: org.example.Main\n",
+				"module-info.class":    string(moduleInfo("org.example.app", "")),
+			})
+
+			args, name, err := native.ModuleArguments{ApplicationPath: appPath}.Configure(nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(name).To(Equal("org.example.Main"))
+			Expect(args).To(Equal([]string{
+				"-H:Name=org.example.Main",
+				"--module-path", fmt.Sprintf("%s:%s", filepath.Join(appPath, "app.jar"), filepath.Join(appPath, "lib", "lib.jar")),
+				"--module", "org.example.app/org.example.Main",
+			}))
+		})
+
+		it("uses the configured main module", func() {
+			args, name, err := native.ModuleArguments{
+				ApplicationPath: appPath,
+				MainModule:      "org.example.lib",
+				ExecutableName:  "example",
+			}.Configure(nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(name).To(Equal("example"))
+			Expect(args).To(Equal([]string{
+				"-H:Name=example",
+				"--module-path", filepath.Join(appPath, "lib", "lib.jar"),
+				"--module", "org.example.lib",
+			}))
+
+			args, name, err = native.ModuleArguments{ApplicationPath: appPath, MainModule: "org.example.lib/org.example.Tool"}.Configure(nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(name).To(Equal("org.example.Tool"))
+			Expect(args[len(args)-1]).To(Equal("org.example.lib/org.example.Tool"))
+		})
+
+		it("fails if the main module cannot be selected", func() {
+			_, _, err := native.ModuleArguments{ApplicationPath: appPath}.Configure(nil)
+			Expect(err).To(MatchError(ContainSubstring("unable to select main module from 0 modular JARs with a main class [], set $BP_NATIVE_IMAGE_MAIN_MODULE")))
+		})
+	})
+}
+
+// moduleInfo returns a module-info.class declaring module name with the main class in internal form, if any
+func moduleInfo(name string, mainClass string) []byte {
+	b := &bytes.Buffer{}
+	u1 := func(v uint8) { b.WriteByte(v) }
+	u2 := func(v uint16) { _ = binary.Write(b, binary.BigEndian, v) }
+	u4 := func(v uint32) { _ = binary.Write(b, binary.BigEndian, v) }
+	utf8 := func(s string) { u1(1); u2(uint16(len(s))); b.WriteString(s) }
+
+	u4(0xCAFEBABE)
+	u2(0)
+	u2(53)
+
+	if mainClass == "" {
+		u2(6)
+	} else {
+		u2(9)
+	}
+	utf8("module-info") // 1
+	u1(7)               // 2
+	u2(1)
+	utf8(name) // 3
+	u1(19)     // 4
+	u2(3)
+	utf8("Module") // 5
+	if mainClass != "" {
+		utf8(mainClass) // 6
+		u1(7)           // 7
+		u2(6)
+		utf8("ModuleMainClass") // 8
+	}
+
+	u2(0x8000)
+	u2(2)
+	u2(0)
+	u2(0)
+	u2(0)
+	u2(0)
+
+	if mainClass == "" {
+		u2(1)
+	} else {
+		u2(2)
+	}
+	u2(5)
+	u4(16)
+	u2(4)
+	for i := 0; i < 7; i++ {
+		u2(0)
+	}
+	if mainClass != "" {
+		u2(8)
+		u4(2)
+		u2(7)
+	}
+
+	return b.Bytes()
+}
@@ -19,11 +19,16 @@ package native
 import (
 	"bytes"
 	"crypto/sha256"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
 
+	"github.com/paketo-buildpacks/native-image/v5/native/march"
 	"github.com/paketo-buildpacks/native-image/v5/native/slices"
 
 	"github.com/buildpacks/libcnb"
@@ -34,16 +39,79 @@ import (
 	"github.com/paketo-buildpacks/libpak/sherpa"
 )
 
+var nativeImageVersion = regexp.MustCompile(`^native-image (\d+)`)
+
 type NativeImage struct {
-	ApplicationPath string
-	Arguments       string
-	ArgumentsFile   string
-	Executor        effect.Executor
-	JarFilePattern  string
-	Logger          bard.Logger
-	Manifest        *properties.Properties
-	StackID         string
-	Compressor      string
+	ApplicationPath  string
+	Arguments        string
+	ArgumentsFile    string
+	Executor         effect.Executor
+	JarFilePattern   string
+	Logger           bard.Logger
+	Manifest         *properties.Properties
+	StackID          string
+	Target           Target
+	BuildCache       BuildCache
+	Compressor       string
+	CompressionLevel string
+	CompressionLZMA  bool
+	DebugInfo        string
+
+	// MaxSize fails the build if the native image is larger, WarnSize only warns. Zero disables either check.
+	MaxSize  int64
+	WarnSize int64
+
+	// LibraryVerifier checks the shared libraries required by the native image when it is built
+	LibraryVerifier LibraryVerifier
+
+	// SmokeTest is run against the native image, through Executor, before the bytecode is removed
+	SmokeTest SmokeTest
+
+	// DependenciesLayerPath is the path of the NativeImageDependencies layer, empty unless layered images are enabled
+	DependenciesLayerPath string
+
+	// Variants are the microarchitectures the native image is built for with -march, from the most to the least
+	// preferred. Each variant is built into its own directory if there is more than one, and the one best suited to the
+	// CPU is selected by the Launcher.
+	Variants []string
+
+	// CPUInfoPath is read to select the variant that is smoke tested on the build host
+	CPUInfoPath string
+
+	// GC is the garbage collector built into the native image, the default of native-image if empty
+	GC string
+
+	// Policies restrict the arguments of native-image for the Profile selected with $BP_NATIVE_IMAGE_PROFILE
+	Policies []Policy
+	Profile  string
+
+	// ArgumentsSource and ArgumentsFileSource are where Arguments and ArgumentsFile are configured, reported when
+	// they violate a policy. They default to the environment.
+	ArgumentsSource     string
+	ArgumentsFileSource string
+
+	// BindingPath is the path of the NativeImageBinding layer, empty if there is no native-image binding. Its policy
+	// applies in addition to Policies.
+	BindingPath string
+
+	// JarExclusions are patterns of JARs that are not built if JarFilePattern matches several
+	JarExclusions []string
+
+	// Keep are patterns of files of the application, relative to it, that are kept when the bytecode is removed. A
+	// pattern ending in /** keeps everything under a directory.
+	Keep []string
+
+	// ExtractionPath is where a Spring Boot JAR is extracted to be built, as native-image cannot read its nested
+	// libraries. Spring Boot JARs are built as is if empty.
+	ExtractionPath string
+
+	// MainModule is the main module of a modular application, optionally followed by /main-class. Applications are
+	// built from the module path if it is set or they are an exploded module.
+	MainModule string
+
+	// PlanMetadata is the configuration contributed by upstream buildpacks, which user configuration takes precedence
+	// over
+	PlanMetadata PlanMetadata
 }
 
 func NewNativeImage(applicationPath string, arguments string, argumentsFile string, compressor string, jarFilePattern string, manifest *properties.Properties, stackID string) (NativeImage, error) {
@@ -56,22 +124,21 @@ func NewNativeImage(applicationPath string, arguments string, argumentsFile stri
 		Manifest:        manifest,
 		StackID:         stackID,
 		Compressor:      compressor,
+		DebugInfo:       DebugInfoNone,
+		CPUInfoPath:     "/proc/cpuinfo",
 	}, nil
 }
 
 func (n NativeImage) Contribute(layer libcnb.Layer) (libcnb.Layer, error) {
-	files, err := sherpa.NewFileListing(n.ApplicationPath)
-	if err != nil {
-		return libcnb.Layer{}, fmt.Errorf("unable to create file listing for %s\n%w", n.ApplicationPath, err)
-	}
-
 	arguments, startClass, err := n.ProcessArguments(layer)
 	if err != nil {
 		return libcnb.Layer{}, fmt.Errorf("unable to process arguments\n%w", err)
 	}
 
-	if !slices.Contains(arguments, "--auto-fallback") && !slices.Contains(arguments, "--force-fallback") {
-		arguments = append([]string{"--no-fallback"}, arguments...)
+	if len(n.Variants) > 0 && containsArg("-march", arguments) {
+		return libcnb.Layer{}, fmt.Errorf("$%s cannot be combined with -march in the native-image arguments", ConfigNativeImageMarch)
+	} else if len(n.Variants) == 1 {
+		arguments = append([]string{fmt.Sprintf("-march=%s", n.Variants[0])}, arguments...)
 	}
 
 	buf := &bytes.Buffer{}
@@ -85,120 +152,333 @@ func (n NativeImage) Contribute(layer libcnb.Layer) (libcnb.Layer, error) {
 	}
 	nativeBinaryHash := fmt.Sprintf("%x", sha256.Sum256(buf.Bytes()))
 
-	contributor := libpak.NewLayerContributor("Native Image", map[string]interface{}{
-		"files":        files,
-		"arguments":    arguments,
-		"compression":  n.Compressor,
-		"version-hash": nativeBinaryHash,
-	}, libcnb.LayerTypes{
+	gc := GCArgument(arguments)
+	if _, ok := ParseGC(gc); gc != "" && !ok {
+		warn(n.Logger, fmt.Sprintf("Garbage collector [%s] is unknown, it is passed to native-image as is", gc))
+	} else if err := CheckGC(gc, buf.String(), NewRunImageForTarget(n.Target, n.StackID).Arch); err != nil {
+		return libcnb.Layer{}, fmt.Errorf("unable to build garbage collector into native image\n%w", err)
+	}
+
+	layerUse, layered, err := n.layerUseArgument(buf.String())
+	if err != nil {
+		return libcnb.Layer{}, fmt.Errorf("unable to configure layered native image\n%w", err)
+	} else if layered {
+		n.Logger.Bodyf("Using native image layer from %s", n.DependenciesLayerPath)
+		arguments = append([]string{layerUse}, arguments...)
+	}
+
+	report := SupportsBuildReport(buf.String()) && !containsArg("-H:BuildOutputJSONFile", arguments)
+	if report {
+		arguments = append(BuildReportArguments(layer.Path), arguments...)
+	}
+
+	if !slices.Contains(arguments, "--auto-fallback") && !slices.Contains(arguments, "--force-fallback") {
+		arguments = append([]string{"--no-fallback"}, arguments...)
+	}
+
+	inputs, err := NewInputDigest(InputPaths(arguments)...)
+	if err != nil {
+		return libcnb.Layer{}, fmt.Errorf("unable to compute digest of build inputs\n%w", err)
+	}
+
+	previousInputs, err := ReadInputDigest(filepath.Join(layer.Path, InputDigestFile))
+	if err != nil {
+		return libcnb.Layer{}, fmt.Errorf("unable to read digest of previous build inputs\n%w", err)
+	}
+
+	version := firstLine(buf.String())
+
+	expected := map[string]interface{}{
+		"inputs":            inputs.Root,
+		"arguments":         arguments,
+		"compression":       n.Compressor,
+		"compression-level": n.CompressionLevel,
+		"compression-lzma":  n.CompressionLZMA,
+		"debug-info":        n.DebugInfo,
+		"linking":           LinkingMode(expandArgumentFiles(arguments, map[string]bool{})),
+		"version":           version,
+		"version-hash":      nativeBinaryHash,
+	}
+	if n.multiVariant() {
+		expected["variants"] = n.Variants
+	}
+	// the measured size is not part of the expected metadata, so that it does not invalidate the cached layer
+	previous := make(map[string]interface{}, len(layer.Metadata))
+	for k, v := range layer.Metadata {
+		if k != "size" {
+			previous[k] = v
+		}
+	}
+	layer.Metadata = previous
+
+	contributor := libpak.NewLayerContributor("Native Image", expected, libcnb.LayerTypes{
 		Cache: true,
 	})
 	contributor.Logger = n.Logger
 
 	layer, err = contributor.Contribute(layer, func() (libcnb.Layer, error) {
-		n.Logger.Bodyf("Executing native-image %s", strings.Join(arguments, " "))
-		if err := n.Executor.Execute(effect.Execution{
-			Command: "native-image",
-			Args:    arguments,
-			Dir:     layer.Path,
-			Stdout:  n.Logger.InfoWriter(),
-			Stderr:  n.Logger.InfoWriter(),
-		}); err != nil {
-			return libcnb.Layer{}, fmt.Errorf("error running build\n%w", err)
-		}
-
-		if n.Compressor == CompressorUpx {
-			n.Logger.Bodyf("Executing %s to compress native image", n.Compressor)
-			if err := n.Executor.Execute(effect.Execution{
-				Command: "upx",
-				Args:    []string{"-q", "-9", filepath.Join(layer.Path, startClass)},
-				Dir:     layer.Path,
-				Stdout:  n.Logger.InfoWriter(),
-				Stderr:  n.Logger.InfoWriter(),
-			}); err != nil {
-				return libcnb.Layer{}, fmt.Errorf("error compressing\n%w", err)
+		n.Logger.Body("Building native image because:")
+		for _, reason := range ExplainCacheMiss(previous, expected, previousInputs, inputs, n.ApplicationPath) {
+			n.Logger.Bodyf("  %s", reason)
+		}
+
+		var key string
+		if n.BuildCache.Enabled() {
+			if key, err = n.BuildCache.Key(expected); err != nil {
+				return libcnb.Layer{}, fmt.Errorf("unable to compute build cache key\n%w", err)
 			}
-		} else if n.Compressor == CompressorGzexe {
-			n.Logger.Bodyf("Executing %s to compress native image", n.Compressor)
-			if err := n.Executor.Execute(effect.Execution{
-				Command: "gzexe",
-				Args:    []string{filepath.Join(layer.Path, startClass)},
-				Dir:     layer.Path,
-				Stdout:  n.Logger.InfoWriter(),
-				Stderr:  n.Logger.InfoWriter(),
-			}); err != nil {
-				return libcnb.Layer{}, fmt.Errorf("error compressing\n%w", err)
+
+			if ok, err := n.restoreFromBuildCache(key, layer.Path); err != nil {
+				return libcnb.Layer{}, fmt.Errorf("unable to restore from build cache\n%w", err)
+			} else if ok {
+				return layer, nil
 			}
+		}
 
-			if err := os.Remove(filepath.Join(layer.Path, fmt.Sprintf("%s~", startClass))); err != nil {
-				return libcnb.Layer{}, fmt.Errorf("error removing\n%w", err)
+		for _, v := range n.variantPaths(layer.Path) {
+			if err := n.build(layer.Path, v, arguments, startClass, report); err != nil {
+				return libcnb.Layer{}, err
 			}
 		}
 
+		if err := inputs.Write(filepath.Join(layer.Path, InputDigestFile)); err != nil {
+			return libcnb.Layer{}, fmt.Errorf("unable to write digest of build inputs\n%w", err)
+		}
+
+		if n.BuildCache.Enabled() {
+			n.storeInBuildCache(key, layer.Path)
+		}
+
 		return layer, nil
 	})
 	if err != nil {
 		return libcnb.Layer{}, fmt.Errorf("unable to contribute native-image layer\n%w", err)
 	}
 
-	n.Logger.Header("Removing bytecode")
-	cs, err := os.ReadDir(n.ApplicationPath)
-	if err != nil {
-		return libcnb.Layer{}, fmt.Errorf("unable to list children of %s\n%w", n.ApplicationPath, err)
+	var size int64
+	for _, v := range n.variantPaths(layer.Path) {
+		s, err := n.checkSize(filepath.Join(v, startClass))
+		if err != nil {
+			return libcnb.Layer{}, err
+		}
+		size += s
 	}
-	for _, c := range cs {
-		file := filepath.Join(n.ApplicationPath, c.Name())
-		if err := os.RemoveAll(file); err != nil {
-			return libcnb.Layer{}, fmt.Errorf("unable to remove %s\n%w", file, err)
+	layer.Metadata["size"] = size
+
+	if n.SmokeTest.Enabled() {
+		smokeTest := n.SmokeTest
+		smokeTest.Executor = n.Executor
+		smokeTest.Logger = n.Logger
+
+		path := layer.Path
+		if n.multiVariant() {
+			variant := n.hostVariant()
+			n.Logger.Bodyf("Smoke testing the %s variant, which is selected on this CPU", variant)
+			path = filepath.Join(path, variant)
 		}
+
+		n.Logger.Header("Smoke testing native image")
+		if err := smokeTest.Run(filepath.Join(path, startClass), n.ApplicationPath); err != nil {
+			return libcnb.Layer{}, fmt.Errorf("native image failed smoke test\n%w", err)
+		}
+	}
+
+	n.Logger.Header("Removing bytecode")
+	if err := removeBytecode(n.ApplicationPath, n.Keep); err != nil {
+		return libcnb.Layer{}, fmt.Errorf("unable to remove bytecode\n%w", err)
 	}
 
-	if err := copyFilesFromLayer(layer.Path, startClass, n.ApplicationPath); err != nil {
+	if n.multiVariant() {
+		for _, v := range n.Variants {
+			if err := copyFilesFromLayer(filepath.Join(layer.Path, v), startClass, filepath.Join(n.ApplicationPath, v), n.DebugInfo == DebugInfoEmbedded); err != nil {
+				return libcnb.Layer{}, fmt.Errorf("unable to copy files of variant %s from layer\n%w", v, err)
+			}
+		}
+	} else if err := copyFilesFromLayer(layer.Path, startClass, n.ApplicationPath, n.DebugInfo == DebugInfoEmbedded); err != nil {
 		return libcnb.Layer{}, fmt.Errorf("unable to copy files from layer\n%w", err)
 	}
 
+	if layered {
+		if err := copyFilesFromLayer(n.DependenciesLayerPath, "", n.ApplicationPath, false); err != nil {
+			return libcnb.Layer{}, fmt.Errorf("unable to copy files from dependencies layer\n%w", err)
+		}
+	}
+
 	return layer, nil
 }
 
+// ProcessArguments returns the arguments native-image is run with and the name of the executable. The arguments are
+// checked against the Policies, failing with the sources of those that violate them.
 func (n NativeImage) ProcessArguments(layer libcnb.Layer) ([]string, string, error) {
+	provenance := Provenance{}
+
+	arguments, startClass, err := n.configureArguments(layer, provenance)
+	if err != nil {
+		return []string{}, "", err
+	}
+
+	policies, err := n.policies()
+	if err != nil {
+		return []string{}, "", err
+	}
+
+	if len(policies) > 0 {
+		if err := n.checkPolicies(policies, arguments, provenance); err != nil {
+			return []string{}, "", err
+		}
+	}
+
+	return arguments, startClass, nil
+}
+
+// policies returns the Policies, followed by the policy of the native-image binding copied into the layer at
+// BindingPath, if any
+func (n NativeImage) policies() ([]Policy, error) {
+	policies := append([]Policy{}, n.Policies...)
+	if n.BindingPath == "" {
+		return policies, nil
+	}
+
+	file := filepath.Join(n.BindingPath, BindingPolicy)
+	if ok, err := sherpa.FileExists(file); err != nil {
+		return nil, fmt.Errorf("unable to check for %s\n%w", file, err)
+	} else if !ok {
+		return policies, nil
+	}
+
+	p, err := ReadPolicy(file)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read policy of %s binding\n%w", BindingType, err)
+	}
+
+	return append(policies, p), nil
+}
+
+// checkPolicies checks the arguments, with the contents of argument files and those the buildpack adds when building,
+// against policies
+func (n NativeImage) checkPolicies(policies []Policy, arguments []string, provenance Provenance) error {
+	var added []string
+	if !slices.Contains(arguments, "--auto-fallback") && !slices.Contains(arguments, "--force-fallback") {
+		added = append(added, "--no-fallback")
+	}
+	provenance.Record("the buildpack", nil, added)
+
+	var variants []string
+	for _, v := range n.Variants {
+		variants = append(variants, fmt.Sprintf("-march=%s", v))
+	}
+	provenance.Record(fmt.Sprintf("$%s", ConfigNativeImageMarch), nil, variants)
+
+	expanded, err := provenance.Expand(append(append(added, variants...), arguments...))
+	if err != nil {
+		return fmt.Errorf("unable to expand argument files\n%w", err)
+	}
+
+	var violations []string
+	for _, p := range policies {
+		violations = append(violations, p.Violations(expanded, provenance, n.Profile)...)
+	}
+	if len(violations) > 0 {
+		return fmt.Errorf("native-image arguments violate policy\n%s", strings.Join(violations, "\n"))
+	}
+
+	return nil
+}
+
+// configureArguments configures the arguments from each source in turn, recording the sources in provenance
+func (n NativeImage) configureArguments(layer libcnb.Layer, provenance Provenance) ([]string, string, error) {
 	var arguments []string
 	var startClass string
 	var err error
 
-	arguments, _, err = BaselineArguments{StackID: n.StackID}.Configure(nil)
+	arguments, _, err = provenance.Configure(BaselineArguments{StackID: n.StackID, Target: n.Target, DebugInfo: n.DebugInfo != DebugInfoNone, GC: n.GC}, "the buildpack", nil)
 	if err != nil {
 		return []string{}, "", fmt.Errorf("unable to set baseline arguments\n%w", err)
 	}
 
+	arguments, _, err = provenance.Configure(PlanArguments{Metadata: n.PlanMetadata}, "upstream buildpacks", arguments)
+	if err != nil {
+		return []string{}, "", fmt.Errorf("unable to append plan arguments\n%w", err)
+	}
+
+	arguments, _, err = provenance.Configure(BindingArguments{LayerPath: n.BindingPath}, "the native-image binding", arguments)
+	if err != nil {
+		return []string{}, "", fmt.Errorf("unable to append binding arguments\n%w", err)
+	}
+
+	nativeSources, quarkus, err := FindQuarkusNativeSources(n.ApplicationPath)
+	if err != nil {
+		return []string{}, "", fmt.Errorf("unable to find Quarkus native sources\n%w", err)
+	} else if quarkus {
+		n.Logger.Bodyf("Building from Quarkus native sources in %s", nativeSources)
+		arguments, startClass, err = provenance.Configure(QuarkusArguments{
+			Directory:      nativeSources,
+			ExecutableName: n.PlanMetadata.ExecutableName,
+			LayerPath:      layer.Path,
+		}, fmt.Sprintf("the Quarkus native sources in %s", nativeSources), arguments)
+		if err != nil {
+			return []string{}, "", fmt.Errorf("unable to append Quarkus arguments\n%w", err)
+		}
+	}
+
 	if n.ArgumentsFile != "" {
-		arguments, _, err = UserFileArguments{ArgumentsFile: n.ArgumentsFile}.Configure(arguments)
+		arguments, _, err = provenance.Configure(UserFileArguments{ArgumentsFile: n.ArgumentsFile}, n.source(n.ArgumentsFileSource, ConfigNativeImageArgsFile), arguments)
 		if err != nil {
 			return []string{}, "", fmt.Errorf("unable to create user file arguments\n%w", err)
 		}
 	}
 
-	arguments, _, err = UserArguments{Arguments: n.Arguments}.Configure(arguments)
+	arguments, _, err = provenance.Configure(UserArguments{Arguments: n.Arguments}, n.source(n.ArgumentsSource, ConfigNativeImageArgs), arguments)
 	if err != nil {
 		return []string{}, "", fmt.Errorf("unable to create user arguments\n%w", err)
 	}
 
+	if quarkus {
+		return arguments, startClass, nil
+	}
+
+	if modular, err := IsModular(n.ApplicationPath, n.MainModule); err != nil {
+		return []string{}, "", fmt.Errorf("unable to check for module\n%w", err)
+	} else if modular {
+		arguments, startClass, err = provenance.Configure(ModuleArguments{
+			ApplicationPath: n.ApplicationPath,
+			LayerPath:       layer.Path,
+			Logger:          n.Logger,
+			MainModule:      n.MainModule,
+			MainClass:       n.PlanMetadata.MainClass,
+			ExecutableName:  n.PlanMetadata.ExecutableName,
+		}, "the application", arguments)
+		if err != nil {
+			return []string{}, "", fmt.Errorf("unable to append module arguments\n%w", err)
+		}
+		return arguments, startClass, nil
+	}
+
 	_, err = os.Stat(filepath.Join(n.ApplicationPath, "META-INF", "MANIFEST.MF"))
 	if err != nil && !os.IsNotExist(err) {
 		return []string{}, "", fmt.Errorf("unable to check for manifest\n%w", err)
 	} else if err != nil && os.IsNotExist(err) {
-		arguments, startClass, err = JarArguments{
+		arguments, startClass, err = provenance.Configure(JarArguments{
 			ApplicationPath: n.ApplicationPath,
 			JarFilePattern:  n.JarFilePattern,
-		}.Configure(arguments)
+			ExecutableName:  n.PlanMetadata.ExecutableName,
+			ExtractionPath:  n.ExtractionPath,
+			MainClass:       n.PlanMetadata.MainClass,
+			Exclusions:      n.JarExclusions,
+			Logger:          n.Logger,
+		}, "the application", arguments)
 		if err != nil {
 			return []string{}, "", fmt.Errorf("unable to append jar arguments\n%w", err)
 		}
 	} else {
-		arguments, startClass, err = ExplodedJarArguments{
+		arguments, startClass, err = provenance.Configure(ExplodedJarArguments{
 			ApplicationPath: n.ApplicationPath,
 			LayerPath:       layer.Path,
 			Manifest:        n.Manifest,
-		}.Configure(arguments)
+			MainClass:       n.PlanMetadata.MainClass,
+			ExecutableName:  n.PlanMetadata.ExecutableName,
+			Logger:          n.Logger,
+		}, "the application", arguments)
 		if err != nil {
 			return []string{}, "", fmt.Errorf("unable to append exploded-jar directory arguments\n%w", err)
 		}
@@ -207,12 +487,378 @@ func (n NativeImage) ProcessArguments(layer libcnb.Layer) ([]string, string, err
 	return arguments, startClass, err
 }
 
+// source returns the source of a configuration option, set by the environment unless it is named
+func (NativeImage) source(source string, name string) string {
+	if source != "" {
+		return source
+	}
+	return fmt.Sprintf("$%s", name)
+}
+
 func (NativeImage) Name() string {
 	return "native-image"
 }
 
-// copy the main file & any `*.so` files also in the layer to the application path
-func copyFilesFromLayer(layerPath string, execName string, appPath string) error {
+// nativeImageMajorVersion returns the Java major version of the native-image that printed version. Older releases
+// that report a GraalVM version instead of a Java version are not recognized.
+func nativeImageMajorVersion(version string) (int, bool) {
+	m := nativeImageVersion.FindStringSubmatch(strings.TrimSpace(version))
+	if m == nil {
+		return 0, false
+	}
+
+	major, err := strconv.Atoi(m[1])
+	if err != nil {
+		return 0, false
+	}
+
+	return major, true
+}
+
+// multiVariant returns whether a native image is built for each of several microarchitectures
+func (n NativeImage) multiVariant() bool {
+	return len(n.Variants) > 1
+}
+
+// variantPaths returns the directories of layerPath the native images are built into
+func (n NativeImage) variantPaths(layerPath string) []string {
+	if !n.multiVariant() {
+		return []string{layerPath}
+	}
+
+	var paths []string
+	for _, v := range n.Variants {
+		paths = append(paths, filepath.Join(layerPath, v))
+	}
+	return paths
+}
+
+// hostVariant returns the variant that the launcher would select on the build host, the least preferred one if the
+// CPU features of the build host cannot be read
+func (n NativeImage) hostVariant() string {
+	fallback := n.Variants[len(n.Variants)-1]
+
+	in, err := os.Open(n.CPUInfoPath)
+	if err != nil {
+		warn(n.Logger, fmt.Sprintf("Unable to open %s, the %s variant will be smoke tested\n%s", n.CPUInfoPath, fallback, err))
+		return fallback
+	}
+	defer in.Close()
+
+	features, err := march.Features(in)
+	if err != nil {
+		warn(n.Logger, fmt.Sprintf("Unable to read CPU features, the %s variant will be smoke tested\n%s", fallback, err))
+		return fallback
+	}
+
+	if v, ok := march.Select(n.Variants, features); ok {
+		return v
+	}
+	return fallback
+}
+
+// build runs native-image to build the native image into variantPath, which is either layerPath or the directory of a
+// variant in it, then verifies, splits the debug info of and compresses the executable
+func (n NativeImage) build(layerPath string, variantPath string, arguments []string, startClass string, report bool) error {
+	if variantPath != layerPath {
+		variant := filepath.Base(variantPath)
+		n.Logger.Bodyf("Building %s variant", variant)
+
+		if err := os.MkdirAll(variantPath, 0755); err != nil {
+			return fmt.Errorf("unable to create %s\n%w", variantPath, err)
+		}
+
+		var args []string
+		for _, a := range arguments {
+			args = append(args, strings.ReplaceAll(a, layerPath+string(os.PathSeparator), variantPath+string(os.PathSeparator)))
+		}
+		arguments = append([]string{fmt.Sprintf("-march=%s", variant)}, args...)
+	}
+
+	stdout := n.Logger.InfoWriter()
+	if report {
+		file := filepath.Join(variantPath, BuildOutputLogFile)
+		out, err := os.Create(file)
+		if err != nil {
+			return fmt.Errorf("unable to create %s\n%w", file, err)
+		}
+		defer out.Close()
+		stdout = io.MultiWriter(stdout, out)
+	}
+
+	n.Logger.Bodyf("Executing native-image %s", strings.Join(arguments, " "))
+	if err := n.Executor.Execute(effect.Execution{
+		Command: "native-image",
+		Args:    arguments,
+		Dir:     variantPath,
+		Stdout:  stdout,
+		Stderr:  n.Logger.InfoWriter(),
+	}); err != nil {
+		return fmt.Errorf("error running build\n%w", err)
+	}
+
+	if n.LibraryVerifier.Enabled() {
+		if err := n.verifyLibraries(variantPath, startClass, arguments); err != nil {
+			return err
+		}
+	}
+
+	if n.DebugInfo == DebugInfoSplit {
+		if err := n.splitDebugInfo(variantPath, startClass); err != nil {
+			return fmt.Errorf("unable to split debug info\n%w", err)
+		}
+	}
+
+	if err := n.compress(filepath.Join(variantPath, startClass)); err != nil {
+		return fmt.Errorf("error compressing\n%w", err)
+	}
+
+	return nil
+}
+
+// restoreFromBuildCache restores the native image for key into layerPath. A failure to read the cache is not fatal, it
+// only means that the native image has to be built.
+func (n NativeImage) restoreFromBuildCache(key string, layerPath string) (bool, error) {
+	ok, err := n.BuildCache.Restore(key, layerPath)
+	if err != nil {
+		warn(n.Logger, fmt.Sprintf("Unable to restore native image from %s, it will be built instead\n%s", n.BuildCache.Path, err))
+
+		if err := os.RemoveAll(layerPath); err != nil {
+			return false, fmt.Errorf("unable to remove %s\n%w", layerPath, err)
+		}
+		if err := os.MkdirAll(layerPath, 0755); err != nil {
+			return false, fmt.Errorf("unable to create %s\n%w", layerPath, err)
+		}
+		return false, nil
+	}
+
+	if ok {
+		n.Logger.Bodyf("Restored native image %s from %s", key, n.BuildCache.Path)
+	}
+	return ok, nil
+}
+
+// storeInBuildCache stores the native image in layerPath for key and evicts old entries. A failure to write to the
+// cache is not fatal, as the native image has been built successfully.
+func (n NativeImage) storeInBuildCache(key string, layerPath string) {
+	n.Logger.Bodyf("Storing native image %s in %s", key, n.BuildCache.Path)
+	if err := n.BuildCache.Store(key, layerPath); err != nil {
+		warn(n.Logger, fmt.Sprintf("Unable to store native image in %s\n%s", n.BuildCache.Path, err))
+		return
+	}
+
+	if err := n.BuildCache.Evict(); err != nil {
+		warn(n.Logger, fmt.Sprintf("Unable to evict native images from %s\n%s", n.BuildCache.Path, err))
+	}
+}
+
+// compress compresses the executable at path with the configured Compressor, reporting the size before and after
+func (n NativeImage) compress(path string) error {
+	method := n.Compressor
+	if method == "" {
+		method = CompressorNone
+	}
+
+	c, ok := NewCompressor(method, CompressorOptions{
+		Executor: n.Executor,
+		Level:    n.CompressionLevel,
+		Logger:   n.Logger,
+		LZMA:     n.CompressionLZMA,
+	})
+	if !ok {
+		return fmt.Errorf("unknown compression method %s", method)
+	}
+
+	if c.Name() == CompressorNone {
+		return nil
+	}
+
+	before, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("unable to stat %s\n%w", path, err)
+	}
+
+	n.Logger.Bodyf("Executing %s to compress native image", c.Name())
+	if err := c.Compress(path); err != nil {
+		return fmt.Errorf("unable to compress %s\n%w", path, err)
+	}
+
+	if err := c.Verify(path); err != nil {
+		return fmt.Errorf("unable to verify compressed %s\n%w", path, err)
+	}
+
+	after, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("unable to stat %s\n%w", path, err)
+	}
+
+	n.Logger.Bodyf("Compressed native image from %s to %s", formatSize(before.Size()), formatSize(after.Size()))
+	return nil
+}
+
+// checkSize measures the executable at path against the size budget, failing if it exceeds MaxSize and warning if it
+// exceeds WarnSize
+func (n NativeImage) checkSize(path string) (int64, error) {
+	b, err := NewSizeBreakdown(path)
+	if err != nil {
+		return 0, fmt.Errorf("unable to measure native image\n%w", err)
+	}
+
+	if n.MaxSize > 0 && b.Total > n.MaxSize {
+		return 0, fmt.Errorf("native image size %s exceeds the maximum of %s set by $%s\n%s",
+			formatSize(b.Total), formatSize(n.MaxSize), ConfigNativeImageMaxSize, b)
+	}
+
+	if n.WarnSize > 0 && b.Total > n.WarnSize {
+		warn(n.Logger, fmt.Sprintf("Native image size %s exceeds the threshold of %s set by $%s\n%s",
+			formatSize(b.Total), formatSize(n.WarnSize), ConfigNativeImageWarnSize, b))
+	}
+
+	return b.Total, nil
+}
+
+// verifyLibraries reports the shared libraries and glibc version required by the native image that the run image does
+// not provide, failing the build or only warning depending on the mode of the LibraryVerifier
+func (n NativeImage) verifyLibraries(layerPath string, execName string, arguments []string) error {
+	// the base layer of a layered native image is shipped with it
+	var libraryPaths []string
+	if n.DependenciesLayerPath != "" && containsArg("--layer-use", arguments) {
+		libraryPaths = append(libraryPaths, n.DependenciesLayerPath)
+	}
+
+	problems, err := n.LibraryVerifier.Verify(layerPath, execName, libraryPaths...)
+	if err != nil {
+		return fmt.Errorf("unable to verify shared libraries\n%w", err)
+	}
+
+	if len(problems) == 0 {
+		n.Logger.Body("Verified shared libraries against the run image")
+		return nil
+	}
+
+	msg := fmt.Sprintf("The native image requires shared libraries that the run image does not provide:\n  %s",
+		strings.Join(problems, "\n  "))
+	if LinkingMode(arguments) == LinkingMostlyStatic {
+		msg = fmt.Sprintf("%s\nThe native image is linked statically except for glibc, so any other library has to be shipped with it or provided by the run image", msg)
+	}
+	msg = fmt.Sprintf("%s\nAdditional libraries can be allowed with $%s", msg, ConfigNativeImageAllowedLibraries)
+
+	if n.LibraryVerifier.Mode == VerifyLibrariesFail {
+		return errors.New(msg)
+	}
+	warn(n.Logger, msg)
+	return nil
+}
+
+// splitDebugInfo extracts the debug info of the executable into a `.debug` file, strips it from the executable and
+// links the two together. It is skipped if native-image has already split the debug info itself.
+func (n NativeImage) splitDebugInfo(layerPath string, execName string) error {
+	debugFile := fmt.Sprintf("%s.debug", execName)
+
+	if exists, err := sherpa.FileExists(filepath.Join(layerPath, debugFile)); err != nil {
+		return fmt.Errorf("unable to check for %s\n%w", debugFile, err)
+	} else if exists {
+		n.Logger.Bodyf("Debug info already split into %s", debugFile)
+		return nil
+	}
+
+	for _, args := range [][]string{
+		{"--only-keep-debug", execName, debugFile},
+		{"--strip-debug", execName},
+		{fmt.Sprintf("--add-gnu-debuglink=%s", debugFile), execName},
+	} {
+		n.Logger.Bodyf("Executing objcopy %s", strings.Join(args, " "))
+		if err := n.Executor.Execute(effect.Execution{
+			Command: "objcopy",
+			Args:    args,
+			Dir:     layerPath,
+			Stdout:  n.Logger.InfoWriter(),
+			Stderr:  n.Logger.InfoWriter(),
+		}); err != nil {
+			return fmt.Errorf("error running objcopy\n%w", err)
+		}
+	}
+
+	return nil
+}
+
+// removeBytecode removes the contents of appPath except the files matching keep, and the directories containing them
+func removeBytecode(appPath string, keep []string) error {
+	if len(keep) == 0 {
+		cs, err := os.ReadDir(appPath)
+		if err != nil {
+			return fmt.Errorf("unable to list children of %s\n%w", appPath, err)
+		}
+		for _, c := range cs {
+			file := filepath.Join(appPath, c.Name())
+			if err := os.RemoveAll(file); err != nil {
+				return fmt.Errorf("unable to remove %s\n%w", file, err)
+			}
+		}
+		return nil
+	}
+
+	var dirs []string
+	if err := filepath.WalkDir(appPath, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(appPath, path)
+		if err != nil {
+			return fmt.Errorf("unable to determine relative path of %s\n%w", path, err)
+		}
+		if rel == "." {
+			return nil
+		}
+
+		if d.IsDir() {
+			dirs = append(dirs, path)
+			return nil
+		}
+
+		if kept(filepath.ToSlash(rel), keep) {
+			return nil
+		}
+
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("unable to remove %s\n%w", path, err)
+		}
+		return nil
+	}); err != nil {
+		return fmt.Errorf("unable to walk %s\n%w", appPath, err)
+	}
+
+	// directories are removed deepest first, leaving those that are not empty
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if cs, err := os.ReadDir(dirs[i]); err != nil {
+			return fmt.Errorf("unable to list children of %s\n%w", dirs[i], err)
+		} else if len(cs) == 0 {
+			if err := os.Remove(dirs[i]); err != nil {
+				return fmt.Errorf("unable to remove %s\n%w", dirs[i], err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// kept returns whether the slash separated path matches one of patterns
+func kept(path string, patterns []string) bool {
+	for _, p := range patterns {
+		if dir := strings.TrimSuffix(p, "/**"); dir != p && strings.HasPrefix(path, dir+"/") {
+			return true
+		}
+		if ok, _ := filepath.Match(p, path); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// copy the main file & any `*.so` files also in the layer to the application path, along with the `*.debug` files and
+// `sources/` cache if debug info is embedded
+func copyFilesFromLayer(layerPath string, execName string, appPath string, debugInfo bool) error {
 	files, err := os.ReadDir(layerPath)
 	if err != nil {
 		return fmt.Errorf("unable to list files on layer %s\n%w", layerPath, err)
@@ -237,6 +883,26 @@ func copyFilesFromLayer(layerPath string, execName string, appPath string) error
 		}
 	}
 
+	if debugInfo {
+		files, err := debugFiles(layerPath)
+		if err != nil {
+			return fmt.Errorf("unable to find debug files\n%w", err)
+		}
+
+		for _, src := range files {
+			dst := filepath.Join(appPath, filepath.Base(src))
+
+			if filepath.Base(src) == debugSourcesDir {
+				err = sherpa.CopyDir(src, dst)
+			} else {
+				err = copyFile(src, dst)
+			}
+			if err != nil {
+				return fmt.Errorf("unable to copy %s to %s\n%w", src, dst, err)
+			}
+		}
+	}
+
 	return nil
 }
 
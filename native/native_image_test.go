@@ -17,12 +17,15 @@
 package native_test
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/buildpacks/libcnb"
 	"github.com/magiconair/properties"
@@ -31,6 +34,7 @@ import (
 	"github.com/paketo-buildpacks/libpak/bard"
 	"github.com/paketo-buildpacks/libpak/effect"
 	"github.com/paketo-buildpacks/libpak/effect/mocks"
+	"github.com/paketo-buildpacks/libpak/sherpa"
 	"github.com/sclevine/spec"
 	"github.com/stretchr/testify/mock"
 
@@ -62,6 +66,7 @@ func testNativeImage(t *testing.T, context spec.G, it spec.S) {
 		Expect(err).NotTo(HaveOccurred())
 
 		Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "fixture-marker"), []byte{}, 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "manifest-class-path"), []byte{}, 0644)).To(Succeed())
 		Expect(os.MkdirAll(filepath.Join(ctx.Application.Path, "BOOT-INF"), 0755)).To(Succeed())
 		Expect(os.MkdirAll(filepath.Join(ctx.Application.Path, "META-INF"), 0755)).To(Succeed())
 		Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "META-INF", "MANIFEST.MF"), []byte{}, 0644)).To(Succeed())
@@ -168,7 +173,7 @@ func testNativeImage(t *testing.T, context spec.G, it spec.S) {
 				"-cp",
 				strings.Join([]string{
 					ctx.Application.Path,
-					"manifest-class-path",
+					filepath.Join(ctx.Application.Path, "manifest-class-path"),
 				}, ":"),
 				"test-start-class",
 			}))
@@ -195,7 +200,7 @@ func testNativeImage(t *testing.T, context spec.G, it spec.S) {
 				"-cp",
 				strings.Join([]string{
 					ctx.Application.Path,
-					"manifest-class-path",
+					filepath.Join(ctx.Application.Path, "manifest-class-path"),
 				}, ":"),
 				"test-start-class",
 			}))
@@ -244,7 +249,7 @@ func testNativeImage(t *testing.T, context spec.G, it spec.S) {
 				"-cp",
 				strings.Join([]string{
 					ctx.Application.Path,
-					"manifest-class-path",
+					filepath.Join(ctx.Application.Path, "manifest-class-path"),
 				}, ":"),
 				"test-start-class",
 			}))
@@ -289,7 +294,7 @@ func testNativeImage(t *testing.T, context spec.G, it spec.S) {
 				"-cp",
 				strings.Join([]string{
 					ctx.Application.Path,
-					"manifest-class-path",
+					filepath.Join(ctx.Application.Path, "manifest-class-path"),
 				}, ":"),
 				"test-start-class",
 			}))
@@ -319,7 +324,7 @@ func testNativeImage(t *testing.T, context spec.G, it spec.S) {
 				"-cp",
 				strings.Join([]string{
 					ctx.Application.Path,
-					"manifest-class-path",
+					filepath.Join(ctx.Application.Path, "manifest-class-path"),
 				}, ":"),
 				"test-main-class",
 			}))
@@ -345,6 +350,10 @@ func testNativeImage(t *testing.T, context spec.G, it spec.S) {
 			execution = executor.Calls[2].Arguments[0].(effect.Execution)
 			Expect(execution.Command).To(Equal("upx"))
 
+			execution = executor.Calls[3].Arguments[0].(effect.Execution)
+			Expect(execution.Command).To(Equal("upx"))
+			Expect(execution.Args).To(ContainElement("-t"))
+
 			bin := filepath.Join(layer.Path, "test-start-class")
 			Expect(bin).To(BeARegularFile())
 
@@ -384,6 +393,813 @@ func testNativeImage(t *testing.T, context spec.G, it spec.S) {
 		})
 	})
 
+	context("linking", func() {
+		it("records the linking mode of the arguments", func() {
+			layer, err := nativeImage.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(layer.Metadata["linking"]).To(Equal(native.LinkingDynamic))
+		})
+
+		it("records the linking mode of the arguments read from argument files", func() {
+			args := filepath.Join(t.TempDir(), "static.args")
+			Expect(os.WriteFile(args, []byte("--static-nolibc\n"), 0644)).To(Succeed())
+			nativeImage.Arguments = fmt.Sprintf("@%s", args)
+
+			layer, err := nativeImage.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(layer.Metadata["linking"]).To(Equal(native.LinkingMostlyStatic))
+		})
+	})
+
+	context("build inputs", func() {
+		it("records a digest of the build inputs", func() {
+			layer, err := nativeImage.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(layer.Metadata).NotTo(HaveKey("files"))
+			Expect(layer.Metadata["inputs"]).NotTo(BeEmpty())
+
+			inputs, err := native.ReadInputDigest(filepath.Join(layer.Path, "native-image-inputs.json"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(inputs.Root).To(Equal(layer.Metadata["inputs"]))
+			Expect(inputs.Files).To(HaveKey(filepath.Join(ctx.Application.Path, "fixture-marker")))
+		})
+
+		it("rebuilds when a JAR referenced from an argument file changes", func() {
+			jar := filepath.Join(t.TempDir(), "dependency.jar")
+			Expect(os.WriteFile(jar, []byte("1"), 0644)).To(Succeed())
+			nativeImage.ArgumentsFile = filepath.Join(t.TempDir(), "native-image.args")
+			Expect(os.WriteFile(nativeImage.ArgumentsFile, []byte("-cp\n"+jar+"\n"), 0644)).To(Succeed())
+
+			layer, err := nativeImage.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			inputs, err := native.ReadInputDigest(filepath.Join(layer.Path, "native-image-inputs.json"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(inputs.Files).To(HaveKey(jar))
+
+			Expect(os.WriteFile(jar, []byte("2"), 0644)).To(Succeed())
+			Expect(os.MkdirAll(filepath.Join(ctx.Application.Path, "META-INF"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "META-INF", "MANIFEST.MF"), []byte{}, 0644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "fixture-marker"), []byte{}, 0644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "manifest-class-path"), []byte{}, 0644)).To(Succeed())
+			out := &bytes.Buffer{}
+			nativeImage.Logger = bard.NewLogger(out)
+
+			rebuilt, err := nativeImage.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(rebuilt.Metadata["inputs"]).NotTo(Equal(layer.Metadata["inputs"]))
+			Expect(out.String()).To(ContainSubstring("Building native image because:"))
+			Expect(out.String()).To(ContainSubstring("dependency.jar` changed"))
+		})
+
+		it("explains why the native image is rebuilt", func() {
+			out := &bytes.Buffer{}
+			nativeImage.Logger = bard.NewLogger(out)
+
+			previous := native.InputDigest{
+				Root:  "previous",
+				Files: map[string]string{filepath.Join(ctx.Application.Path, "fixture-marker"): "previous"},
+			}
+			Expect(os.MkdirAll(layer.Path, 0755)).To(Succeed())
+			Expect(previous.Write(filepath.Join(layer.Path, "native-image-inputs.json"))).To(Succeed())
+			layer.Metadata = map[string]interface{}{
+				"inputs":    "previous",
+				"arguments": []interface{}{"--no-fallback", "test-argument-2"},
+			}
+
+			_, err := nativeImage.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(out.String()).To(ContainSubstring("Building native image because:"))
+			Expect(out.String()).To(ContainSubstring("argument `test-argument-1` added"))
+			Expect(out.String()).To(ContainSubstring("native-image version changed"))
+			Expect(out.String()).To(ContainSubstring("file `fixture-marker` changed"))
+		})
+	})
+
+	context("build cache", func() {
+		it.Before(func() {
+			nativeImage.BuildCache = native.BuildCache{
+				Logger:  bard.NewLogger(io.Discard),
+				MaxSize: 1024 * 1024,
+				Path:    t.TempDir(),
+			}
+		})
+
+		it("stores the native image after building it", func() {
+			_, err := nativeImage.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			entries, err := os.ReadDir(nativeImage.BuildCache.Path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entries).To(HaveLen(1))
+			Expect(filepath.Join(nativeImage.BuildCache.Path, entries[0].Name(), "test-start-class")).To(BeARegularFile())
+		})
+
+		it("restores the native image instead of building it", func() {
+			_, err := nativeImage.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(executor.Calls).To(HaveLen(2))
+
+			Expect(os.RemoveAll(layer.Path)).To(Succeed())
+			layer.Metadata = nil
+			Expect(os.RemoveAll(ctx.Application.Path)).To(Succeed())
+			Expect(os.MkdirAll(filepath.Join(ctx.Application.Path, "META-INF"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "META-INF", "MANIFEST.MF"), []byte{}, 0644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "fixture-marker"), []byte{}, 0644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "manifest-class-path"), []byte{}, 0644)).To(Succeed())
+
+			_, err = nativeImage.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(executor.Calls).To(HaveLen(3))
+			Expect(executor.Calls[2].Arguments[0].(effect.Execution).Args).To(Equal([]string{"--version"}))
+			Expect(filepath.Join(layer.Path, "test-start-class")).To(BeARegularFile())
+			Expect(filepath.Join(ctx.Application.Path, "test-start-class")).To(BeARegularFile())
+		})
+	})
+
+	context("debug info", func() {
+		var debugExecutor *mocks.Executor
+
+		it.Before(func() {
+			debugExecutor = &mocks.Executor{}
+
+			debugExecutor.On("Execute", mock.MatchedBy(func(e effect.Execution) bool {
+				return e.Command == "native-image" && len(e.Args) == 1 && e.Args[0] == "--version"
+			})).Run(func(args mock.Arguments) {
+				exec := args.Get(0).(effect.Execution)
+				_, err := exec.Stdout.Write([]byte("1.2.3"))
+				Expect(err).To(Succeed())
+			}).Return(nil)
+
+			debugExecutor.On("Execute", mock.MatchedBy(func(e effect.Execution) bool {
+				return e.Command == "native-image" && e.Args[0] == "--no-fallback"
+			})).Run(func(args mock.Arguments) {
+				exec := args.Get(0).(effect.Execution)
+				lastArg := exec.Args[len(exec.Args)-1]
+				Expect(os.WriteFile(filepath.Join(layer.Path, lastArg), []byte{}, 0755)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(layer.Path, fmt.Sprintf("%s.debug", lastArg)), []byte{}, 0644)).To(Succeed())
+				Expect(os.MkdirAll(filepath.Join(layer.Path, "sources", "test"), 0755)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(layer.Path, "sources", "test", "Test.java"), []byte{}, 0644)).To(Succeed())
+			}).Return(nil)
+		})
+
+		it("splits debug info from the executable", func() {
+			nativeImage.DebugInfo = "split"
+
+			executor.On("Execute", mock.MatchedBy(func(e effect.Execution) bool {
+				return e.Command == "objcopy"
+			})).Return(nil)
+
+			_, err := nativeImage.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			execution := executor.Calls[1].Arguments[0].(effect.Execution)
+			Expect(execution.Args).To(ContainElement("-g"))
+
+			var objcopy [][]string
+			for _, call := range executor.Calls[2:] {
+				execution := call.Arguments[0].(effect.Execution)
+				Expect(execution.Command).To(Equal("objcopy"))
+				Expect(execution.Dir).To(Equal(layer.Path))
+				objcopy = append(objcopy, execution.Args)
+			}
+			Expect(objcopy).To(Equal([][]string{
+				{"--only-keep-debug", "test-start-class", "test-start-class.debug"},
+				{"--strip-debug", "test-start-class"},
+				{"--add-gnu-debuglink=test-start-class.debug", "test-start-class"},
+			}))
+		})
+
+		it("skips objcopy if native-image already split debug info", func() {
+			nativeImage.DebugInfo = "split"
+			nativeImage.Executor = debugExecutor
+
+			_, err := nativeImage.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(debugExecutor.Calls).To(HaveLen(2))
+			Expect(filepath.Join(ctx.Application.Path, "test-start-class.debug")).NotTo(BeAnExistingFile())
+		})
+
+		it("copies debug files to the application when embedded", func() {
+			nativeImage.DebugInfo = "embedded"
+			nativeImage.Executor = debugExecutor
+
+			_, err := nativeImage.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			execution := debugExecutor.Calls[1].Arguments[0].(effect.Execution)
+			Expect(execution.Args).To(ContainElement("-g"))
+			Expect(debugExecutor.Calls).To(HaveLen(2))
+
+			Expect(filepath.Join(ctx.Application.Path, "test-start-class.debug")).To(BeARegularFile())
+			Expect(filepath.Join(ctx.Application.Path, "sources", "test", "Test.java")).To(BeARegularFile())
+		})
+	})
+
+	context("layered", func() {
+		var (
+			dependenciesPath string
+			layeredExecutor  *mocks.Executor
+			version          string
+		)
+
+		it.Before(func() {
+			dependenciesPath = filepath.Join(ctx.Layers.Path, "native-image-dependencies")
+			Expect(os.MkdirAll(dependenciesPath, 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(dependenciesPath, "dependencies.nil"), []byte{}, 0644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(dependenciesPath, "libdependencies.so"), []byte{}, 0644)).To(Succeed())
+
+			nativeImage.DependenciesLayerPath = dependenciesPath
+			version = "native-image 25.0.1 2025-10-21"
+
+			layeredExecutor = &mocks.Executor{}
+			layeredExecutor.On("Execute", mock.MatchedBy(func(e effect.Execution) bool {
+				return e.Command == "native-image" && len(e.Args) == 1 && e.Args[0] == "--version"
+			})).Run(func(args mock.Arguments) {
+				_, err := args.Get(0).(effect.Execution).Stdout.Write([]byte(version))
+				Expect(err).To(Succeed())
+			}).Return(nil)
+
+			layeredExecutor.On("Execute", mock.MatchedBy(func(e effect.Execution) bool {
+				return e.Command == "native-image" && e.Args[0] == "--no-fallback"
+			})).Run(func(args mock.Arguments) {
+				exec := args.Get(0).(effect.Execution)
+				lastArg := exec.Args[len(exec.Args)-1]
+				Expect(os.WriteFile(filepath.Join(layer.Path, lastArg), []byte{}, 0755)).To(Succeed())
+			}).Return(nil)
+			nativeImage.Executor = layeredExecutor
+		})
+
+		it("builds the application layer on top of the dependencies layer", func() {
+			_, err := nativeImage.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			execution := layeredExecutor.Calls[1].Arguments[0].(effect.Execution)
+			Expect(execution.Args).To(ContainElement(fmt.Sprintf("--layer-use=%s", filepath.Join(dependenciesPath, "dependencies.nil"))))
+
+			Expect(filepath.Join(ctx.Application.Path, "test-start-class")).To(BeARegularFile())
+			Expect(filepath.Join(ctx.Application.Path, "libdependencies.so")).To(BeARegularFile())
+		})
+
+		it("builds the dependencies layer with the options of the application layer", func() {
+			nativeImage.Variants = []string{"x86-64-v3"}
+			nativeImage.GC = native.GCSerial
+			nativeImage.DebugInfo = native.DebugInfoEmbedded
+
+			_, err := nativeImage.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+			application := layeredExecutor.Calls[1].Arguments[0].(effect.Execution).Args
+
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "dependency.jar"), []byte{}, 0644)).To(Succeed())
+			dependencies := native.NewNativeImageDependencies("test-argument-1 test-argument-2",
+				filepath.Join(ctx.Application.Path, "dependency.jar"), ctx.StackID)
+			dependencies.March = nativeImage.Variants[0]
+			dependencies.GC = nativeImage.GC
+			dependencies.DebugInfo = nativeImage.DebugInfo
+			dependencies.Logger = bard.NewLogger(io.Discard)
+
+			dependenciesExecutor := &mocks.Executor{}
+			dependenciesExecutor.On("Execute", mock.MatchedBy(func(e effect.Execution) bool {
+				return len(e.Args) == 1 && e.Args[0] == "--version"
+			})).Run(func(args mock.Arguments) {
+				_, err := args.Get(0).(effect.Execution).Stdout.Write([]byte(version))
+				Expect(err).To(Succeed())
+			}).Return(nil)
+			dependenciesExecutor.On("Execute", mock.Anything).Return(nil)
+			dependencies.Executor = dependenciesExecutor
+
+			dependenciesLayer, err := ctx.Layers.Layer("native-image-dependencies")
+			Expect(err).NotTo(HaveOccurred())
+			_, err = dependencies.Contribute(dependenciesLayer)
+			Expect(err).NotTo(HaveOccurred())
+			layered := dependenciesExecutor.Calls[1].Arguments[0].(effect.Execution).Args
+
+			// the layer flags, outputs, class path and main class necessarily differ
+			options := func(args []string) []string {
+				var o []string
+				for i := 0; i < len(args); i++ {
+					switch {
+					case args[i] == "-cp" || args[i] == "--emit":
+						i++
+					case strings.HasPrefix(args[i], "--layer-"), strings.HasPrefix(args[i], "-H:Name="),
+						strings.HasPrefix(args[i], "-H:BuildOutputJSONFile="):
+					default:
+						o = append(o, args[i])
+					}
+				}
+				return o
+			}
+
+			Expect(options(layered)).To(Equal(options(application[:len(application)-1])))
+			Expect(options(layered)).To(ContainElements("--no-fallback", "-g", "-march=x86-64-v3", "--gc=serial"))
+		})
+
+		context("library verification", func() {
+			it.Before(func() {
+				verifyingExecutor := &mocks.Executor{}
+				verifyingExecutor.On("Execute", mock.MatchedBy(func(e effect.Execution) bool {
+					return e.Command == "native-image" && len(e.Args) == 1 && e.Args[0] == "--version"
+				})).Run(func(args mock.Arguments) {
+					_, err := args.Get(0).(effect.Execution).Stdout.Write([]byte(version))
+					Expect(err).To(Succeed())
+				}).Return(nil)
+
+				verifyingExecutor.On("Execute", mock.MatchedBy(func(e effect.Execution) bool {
+					return e.Command == "native-image" && e.Args[0] == "--no-fallback"
+				})).Run(func(args mock.Arguments) {
+					exec := args.Get(0).(effect.Execution)
+					in, err := os.Open("testdata/layered-fixture")
+					Expect(err).NotTo(HaveOccurred())
+					Expect(sherpa.CopyFile(in, filepath.Join(layer.Path, exec.Args[len(exec.Args)-1]))).To(Succeed())
+				}).Return(nil)
+				nativeImage.Executor = verifyingExecutor
+				nativeImage.LibraryVerifier = native.NewLibraryVerifier("fail", native.RunImage{Flavor: "distroless"}, nil)
+			})
+
+			it("counts the libraries of the dependencies layer as shipped with the native image", func() {
+				_, err := nativeImage.Contribute(layer)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			it("does not count the libraries of a dependencies layer that is not used", func() {
+				Expect(os.Remove(filepath.Join(dependenciesPath, "dependencies.nil"))).To(Succeed())
+
+				_, err := nativeImage.Contribute(layer)
+				Expect(err).To(MatchError(ContainSubstring("test-start-class requires libdependencies.so, which is not provided by the run image")))
+			})
+		})
+
+		it("builds a single native image if native-image does not support layered images", func() {
+			version = "native-image 21.0.2 2024-01-16"
+
+			_, err := nativeImage.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			execution := layeredExecutor.Calls[1].Arguments[0].(effect.Execution)
+			Expect(execution.Args).NotTo(ContainElement(HavePrefix("--layer-use=")))
+			Expect(filepath.Join(ctx.Application.Path, "libdependencies.so")).NotTo(BeAnExistingFile())
+		})
+
+		it("builds a single native image if the dependencies layer was not built", func() {
+			Expect(os.Remove(filepath.Join(dependenciesPath, "dependencies.nil"))).To(Succeed())
+
+			_, err := nativeImage.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			execution := layeredExecutor.Calls[1].Arguments[0].(effect.Execution)
+			Expect(execution.Args).NotTo(ContainElement(HavePrefix("--layer-use=")))
+		})
+	})
+
+	context("build report", func() {
+		var reportExecutor *mocks.Executor
+
+		it.Before(func() {
+			reportExecutor = &mocks.Executor{}
+			reportExecutor.On("Execute", mock.MatchedBy(func(e effect.Execution) bool {
+				return e.Command == "native-image" && len(e.Args) == 1 && e.Args[0] == "--version"
+			})).Run(func(args mock.Arguments) {
+				_, err := args.Get(0).(effect.Execution).Stdout.Write([]byte("native-image 21.0.2 2024-01-16"))
+				Expect(err).To(Succeed())
+			}).Return(nil)
+
+			reportExecutor.On("Execute", mock.MatchedBy(func(e effect.Execution) bool {
+				return e.Command == "native-image" && e.Args[0] == "--no-fallback"
+			})).Run(func(args mock.Arguments) {
+				exec := args.Get(0).(effect.Execution)
+				lastArg := exec.Args[len(exec.Args)-1]
+				Expect(os.WriteFile(filepath.Join(layer.Path, lastArg), []byte{}, 0755)).To(Succeed())
+				_, err := exec.Stdout.Write([]byte("[1/8] Initializing...    (3.9s @ 0.14GB)\n"))
+				Expect(err).To(Succeed())
+			}).Return(nil)
+			nativeImage.Executor = reportExecutor
+		})
+
+		it("requests the build outputs and records the console output", func() {
+			_, err := nativeImage.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			execution := reportExecutor.Calls[1].Arguments[0].(effect.Execution)
+			Expect(execution.Args[:4]).To(Equal([]string{
+				"--no-fallback",
+				fmt.Sprintf("-H:BuildOutputJSONFile=%s", filepath.Join(layer.Path, "build-output.json")),
+				"--emit", fmt.Sprintf("build-report=%s", filepath.Join(layer.Path, "build-report.html")),
+			}))
+
+			Expect(os.ReadFile(filepath.Join(layer.Path, "build-output.log"))).To(ContainSubstring("[1/8] Initializing..."))
+		})
+
+		it("does not override a user provided build output", func() {
+			nativeImage.Arguments = "-H:BuildOutputJSONFile=/tmp/output.json"
+
+			_, err := nativeImage.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			execution := reportExecutor.Calls[1].Arguments[0].(effect.Execution)
+			Expect(execution.Args).NotTo(ContainElement("--emit"))
+			Expect(filepath.Join(layer.Path, "build-output.log")).NotTo(BeAnExistingFile())
+		})
+	})
+
+	context("size budget", func() {
+		var sizeExecutor *mocks.Executor
+
+		it.Before(func() {
+			sizeExecutor = &mocks.Executor{}
+			sizeExecutor.On("Execute", mock.MatchedBy(func(e effect.Execution) bool {
+				return e.Command == "native-image" && len(e.Args) == 1 && e.Args[0] == "--version"
+			})).Return(nil)
+
+			sizeExecutor.On("Execute", mock.MatchedBy(func(e effect.Execution) bool {
+				return e.Command == "native-image" && e.Args[0] == "--no-fallback"
+			})).Run(func(args mock.Arguments) {
+				exec := args.Get(0).(effect.Execution)
+				lastArg := exec.Args[len(exec.Args)-1]
+				Expect(os.WriteFile(filepath.Join(layer.Path, lastArg), make([]byte, 2048), 0755)).To(Succeed())
+			}).Return(nil)
+			nativeImage.Executor = sizeExecutor
+		})
+
+		it("records the size in the layer metadata", func() {
+			layer, err := nativeImage.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(layer.Metadata["size"]).To(Equal(int64(2048)))
+		})
+
+		it("fails if the native image exceeds the maximum size", func() {
+			nativeImage.MaxSize = 1024
+
+			_, err := nativeImage.Contribute(layer)
+			Expect(err).To(MatchError(ContainSubstring("native image size 2.0 KiB exceeds the maximum of 1.0 KiB set by $BP_NATIVE_IMAGE_MAX_SIZE")))
+			Expect(err).To(MatchError(ContainSubstring("total: 2.0 KiB")))
+
+			Expect(filepath.Join(ctx.Application.Path, "fixture-marker")).To(BeARegularFile())
+		})
+
+		it("warns if the native image exceeds the warning threshold", func() {
+			out := &bytes.Buffer{}
+			nativeImage.Logger = bard.NewLogger(out)
+			nativeImage.MaxSize = 4096
+			nativeImage.WarnSize = 1024
+
+			_, err := nativeImage.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(out.String()).To(ContainSubstring("Native image size 2.0 KiB exceeds the threshold of 1.0 KiB set by $BP_NATIVE_IMAGE_WARN_SIZE"))
+		})
+
+		it("does not invalidate the cached layer", func() {
+			layer, err := nativeImage.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(os.RemoveAll(ctx.Application.Path)).To(Succeed())
+			Expect(os.MkdirAll(filepath.Join(ctx.Application.Path, "BOOT-INF"), 0755)).To(Succeed())
+			Expect(os.MkdirAll(filepath.Join(ctx.Application.Path, "META-INF"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "fixture-marker"), []byte{}, 0644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "manifest-class-path"), []byte{}, 0644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "META-INF", "MANIFEST.MF"), []byte{}, 0644)).To(Succeed())
+			out := &bytes.Buffer{}
+			nativeImage.Logger = bard.NewLogger(out)
+
+			layer, err = nativeImage.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(out.String()).To(ContainSubstring("Reusing"))
+			Expect(out.String()).NotTo(ContainSubstring("Executing native-image"))
+			Expect(layer.Metadata["size"]).To(Equal(int64(2048)))
+		})
+	})
+
+	context("smoke test", func() {
+		it.Before(func() {
+			nativeImage.SmokeTest = native.SmokeTest{Command: "{{.Executable}} --help", Timeout: time.Minute}
+		})
+
+		it("runs the native image before removing the bytecode", func() {
+			executor.On("Execute", mock.MatchedBy(func(e effect.Execution) bool {
+				return e.Command == "timeout"
+			})).Run(func(args mock.Arguments) {
+				Expect(filepath.Join(ctx.Application.Path, "fixture-marker")).To(BeARegularFile())
+			}).Return(nil)
+
+			_, err := nativeImage.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			execution := executor.Calls[2].Arguments[0].(effect.Execution)
+			Expect(execution.Command).To(Equal("timeout"))
+			Expect(execution.Args).To(Equal([]string{"--kill-after=5s", "60s", filepath.Join(layer.Path, "test-start-class"), "--help"}))
+			Expect(execution.Dir).To(Equal(ctx.Application.Path))
+		})
+
+		it("fails the build if the smoke test fails", func() {
+			executor.On("Execute", mock.MatchedBy(func(e effect.Execution) bool {
+				return e.Command == "timeout"
+			})).Return(exec.Command("false").Run())
+
+			_, err := nativeImage.Contribute(layer)
+			Expect(err).To(MatchError(ContainSubstring("native image failed smoke test")))
+
+			Expect(filepath.Join(ctx.Application.Path, "fixture-marker")).To(BeARegularFile())
+		})
+	})
+
+	context("microarchitecture", func() {
+		it("builds a single variant", func() {
+			nativeImage.Variants = []string{"x86-64-v3"}
+
+			_, err := nativeImage.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			execution := executor.Calls[1].Arguments[0].(effect.Execution)
+			Expect(execution.Args[:2]).To(Equal([]string{"--no-fallback", "-march=x86-64-v3"}))
+			Expect(filepath.Join(ctx.Application.Path, "test-start-class")).To(BeARegularFile())
+		})
+
+		it("rejects -march in the arguments", func() {
+			nativeImage.Variants = []string{"x86-64-v3"}
+			nativeImage.Arguments = "-march=native"
+
+			_, err := nativeImage.Contribute(layer)
+			Expect(err).To(MatchError("$BP_NATIVE_IMAGE_MARCH cannot be combined with -march in the native-image arguments"))
+		})
+
+		context("multiple variants", func() {
+			var variantExecutor *mocks.Executor
+
+			it.Before(func() {
+				nativeImage.Variants = []string{"x86-64-v3", "compatibility"}
+
+				variantExecutor = &mocks.Executor{}
+				variantExecutor.On("Execute", mock.MatchedBy(func(e effect.Execution) bool {
+					return e.Command == "native-image" && len(e.Args) == 1 && e.Args[0] == "--version"
+				})).Return(nil)
+				variantExecutor.On("Execute", mock.MatchedBy(func(e effect.Execution) bool {
+					return e.Command == "native-image" && strings.HasPrefix(e.Args[0], "-march=")
+				})).Run(func(args mock.Arguments) {
+					exec := args.Get(0).(effect.Execution)
+					Expect(os.WriteFile(filepath.Join(exec.Dir, "test-start-class"), []byte{}, 0755)).To(Succeed())
+					Expect(os.WriteFile(filepath.Join(exec.Dir, "libawt.so"), []byte{}, 0644)).To(Succeed())
+				}).Return(nil)
+				variantExecutor.On("Execute", mock.MatchedBy(func(e effect.Execution) bool {
+					return e.Command == "timeout"
+				})).Return(nil)
+				nativeImage.Executor = variantExecutor
+			})
+
+			it("builds each variant into its own directory", func() {
+				_, err := nativeImage.Contribute(layer)
+				Expect(err).NotTo(HaveOccurred())
+
+				for i, variant := range []string{"x86-64-v3", "compatibility"} {
+					execution := variantExecutor.Calls[i+1].Arguments[0].(effect.Execution)
+					Expect(execution.Dir).To(Equal(filepath.Join(layer.Path, variant)))
+					Expect(execution.Args[0]).To(Equal(fmt.Sprintf("-march=%s", variant)))
+					Expect(execution.Args).To(ContainElement(fmt.Sprintf("-H:Name=%s", filepath.Join(layer.Path, variant, "test-start-class"))))
+
+					Expect(filepath.Join(ctx.Application.Path, variant, "test-start-class")).To(BeARegularFile())
+					Expect(filepath.Join(ctx.Application.Path, variant, "libawt.so")).To(BeARegularFile())
+				}
+				Expect(filepath.Join(ctx.Application.Path, "test-start-class")).NotTo(BeAnExistingFile())
+			})
+
+			it("smoke tests the variant selected on the build host", func() {
+				cpuinfo := filepath.Join(t.TempDir(), "cpuinfo")
+				Expect(os.WriteFile(cpuinfo, []byte("processor\t: 0\nflags\t\t: fpu sse2 ssse3 sse4_1\n"), 0644)).To(Succeed())
+				nativeImage.CPUInfoPath = cpuinfo
+				nativeImage.SmokeTest = native.SmokeTest{Command: "{{.Executable}}", Timeout: time.Minute}
+
+				_, err := nativeImage.Contribute(layer)
+				Expect(err).NotTo(HaveOccurred())
+
+				execution := variantExecutor.Calls[3].Arguments[0].(effect.Execution)
+				Expect(execution.Command).To(Equal("timeout"))
+				Expect(execution.Args[2]).To(Equal(filepath.Join(layer.Path, "compatibility", "test-start-class")))
+			})
+		})
+	})
+
+	context("garbage collector", func() {
+		it("builds the garbage collector into the native image", func() {
+			nativeImage.GC = "epsilon"
+
+			_, err := nativeImage.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			execution := executor.Calls[1].Arguments[0].(effect.Execution)
+			Expect(execution.Args).To(ContainElement("--gc=epsilon"))
+		})
+
+		it("rejects a garbage collector the native-image does not support", func() {
+			nativeImage.GC = "g1"
+
+			_, err := nativeImage.Contribute(layer)
+			Expect(err).To(MatchError(ContainSubstring("the G1 garbage collector is only available in Oracle GraalVM, not 1.2.3")))
+		})
+
+		it("checks the garbage collector set in the arguments", func() {
+			nativeImage.Arguments = "--gc=G1"
+
+			_, err := nativeImage.Contribute(layer)
+			Expect(err).To(MatchError(ContainSubstring("the G1 garbage collector is only available in Oracle GraalVM")))
+		})
+
+		it("passes an unknown garbage collector to native-image", func() {
+			out := &bytes.Buffer{}
+			nativeImage.Logger = bard.NewLogger(out)
+			nativeImage.Arguments = "--gc=parallel"
+
+			_, err := nativeImage.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			execution := executor.Calls[1].Arguments[0].(effect.Execution)
+			Expect(execution.Args).To(ContainElement("--gc=parallel"))
+			Expect(out.String()).To(ContainSubstring("Garbage collector [parallel] is unknown"))
+		})
+	})
+
+	context("plan metadata", func() {
+		it("configures the plan metadata below the user arguments", func() {
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "argfile"), []byte("-O2"), 0644)).To(Succeed())
+			nativeImage.Arguments = "--gc=serial"
+			nativeImage.PlanMetadata = native.PlanMetadata{
+				Arguments:     []string{"--gc=epsilon", "-H:+ReportExceptionStackTraces"},
+				ArgumentFiles: []string{filepath.Join(ctx.Application.Path, "argfile")},
+				MainClass:     "org.example.Main",
+			}
+
+			_, err := nativeImage.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			execution := executor.Calls[1].Arguments[0].(effect.Execution)
+			Expect(execution.Args).To(Equal([]string{
+				"--no-fallback",
+				"-H:+ReportExceptionStackTraces",
+				fmt.Sprintf("@%s", filepath.Join(ctx.Application.Path, "argfile")),
+				"--gc=serial",
+				fmt.Sprintf("-H:Name=%s", filepath.Join(layer.Path, "org.example.Main")),
+				"-cp", fmt.Sprintf("%s:%s", ctx.Application.Path, filepath.Join(ctx.Application.Path, "manifest-class-path")),
+				"org.example.Main",
+			}))
+		})
+	})
+
+	context("Quarkus native sources", func() {
+		it("builds the runner JAR with the arguments of Quarkus", func() {
+			dir := filepath.Join(ctx.Application.Path, "target", "native-sources")
+			Expect(os.MkdirAll(dir, 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(dir, "app-1.0-runner.jar"), []byte{}, 0644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(dir, "native-image.args"),
+				[]byte("-H:ReflectionConfigurationFiles=reflection-config.json\n-H:Name=app-1.0-runner\n-jar\napp-1.0-runner.jar\n"), 0644)).To(Succeed())
+
+			quarkusExecutor := &mocks.Executor{}
+			quarkusExecutor.On("Execute", mock.MatchedBy(func(e effect.Execution) bool {
+				return len(e.Args) == 1 && e.Args[0] == "--version"
+			})).Run(func(args mock.Arguments) {
+				_, err := args.Get(0).(effect.Execution).Stdout.Write([]byte("1.2.3"))
+				Expect(err).To(Succeed())
+			}).Return(nil)
+			quarkusExecutor.On("Execute", mock.Anything).Run(func(args mock.Arguments) {
+				Expect(os.WriteFile(filepath.Join(layer.Path, "app-1.0-runner"), []byte{}, 0755)).To(Succeed())
+			}).Return(nil)
+			nativeImage.Executor = quarkusExecutor
+
+			_, err := nativeImage.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(filepath.Join(ctx.Application.Path, "app-1.0-runner")).To(BeARegularFile())
+
+			execution := quarkusExecutor.Calls[1].Arguments[0].(effect.Execution)
+			Expect(execution.Args).To(Equal([]string{
+				"--no-fallback",
+				fmt.Sprintf("-H:ReflectionConfigurationFiles=%s", filepath.Join(dir, "reflection-config.json")),
+				"-jar", filepath.Join(dir, "app-1.0-runner.jar"),
+				fmt.Sprintf("-H:Name=%s", filepath.Join(layer.Path, "app-1.0-runner")),
+				"test-argument-1",
+				"test-argument-2",
+			}))
+		})
+	})
+
+	context("library verification", func() {
+		var libraryExecutor *mocks.Executor
+
+		it.Before(func() {
+			libraryExecutor = &mocks.Executor{}
+			libraryExecutor.On("Execute", mock.MatchedBy(func(e effect.Execution) bool {
+				return e.Command == "native-image" && len(e.Args) == 1 && e.Args[0] == "--version"
+			})).Return(nil)
+
+			libraryExecutor.On("Execute", mock.MatchedBy(func(e effect.Execution) bool {
+				return e.Command == "native-image" && e.Args[0] == "--no-fallback"
+			})).Run(func(args mock.Arguments) {
+				exec := args.Get(0).(effect.Execution)
+				in, err := os.Open("testdata/dynamic-fixture")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(sherpa.CopyFile(in, filepath.Join(layer.Path, exec.Args[len(exec.Args)-1]))).To(Succeed())
+			}).Return(nil)
+			nativeImage.Executor = libraryExecutor
+		})
+
+		it("fails if the run image does not provide a library", func() {
+			nativeImage.LibraryVerifier = native.NewLibraryVerifier("fail", native.RunImage{Flavor: "distroless"}, nil)
+			nativeImage.Arguments = "-H:+StaticExecutableWithDynamicLibC"
+
+			_, err := nativeImage.Contribute(layer)
+			Expect(err).To(MatchError(ContainSubstring("test-start-class requires libz.so.1, which is not provided by the run image")))
+			Expect(err).To(MatchError(ContainSubstring("linked statically except for glibc")))
+		})
+
+		it("warns if the run image does not provide a library", func() {
+			out := &bytes.Buffer{}
+			nativeImage.Logger = bard.NewLogger(out)
+			nativeImage.LibraryVerifier = native.NewLibraryVerifier("warn", native.RunImage{Flavor: "distroless"}, nil)
+
+			_, err := nativeImage.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(out.String()).To(ContainSubstring("requires libz.so.1"))
+		})
+	})
+
+	context("policy", func() {
+		it.Before(func() {
+			nativeImage.Policies = []native.Policy{{
+				File: "policy.toml",
+				Deny: []native.PolicyRule{{Pattern: "test-argument-1"}, {Pattern: "-march=native"}},
+			}}
+		})
+
+		it("fails with the source of denied arguments", func() {
+			nativeImage.Variants = []string{"native"}
+
+			_, err := nativeImage.Contribute(layer)
+			Expect(err).To(MatchError(ContainSubstring("native-image arguments violate policy")))
+			Expect(err).To(MatchError(ContainSubstring("test-argument-1 is denied by policy.toml, set by $BP_NATIVE_IMAGE_BUILD_ARGUMENTS")))
+			Expect(err).To(MatchError(ContainSubstring("-march=native is denied by policy.toml, set by $BP_NATIVE_IMAGE_MARCH")))
+			Expect(executor.Calls).To(BeEmpty())
+		})
+
+		it("reports arguments configured by the descriptor", func() {
+			nativeImage.ArgumentsSource = filepath.Join(ctx.Application.Path, "native-image.toml")
+
+			_, err := nativeImage.Contribute(layer)
+			Expect(err).To(MatchError(ContainSubstring(fmt.Sprintf("test-argument-1 is denied by policy.toml, set by %s",
+				filepath.Join(ctx.Application.Path, "native-image.toml")))))
+		})
+
+		it("checks the arguments against the policy copied from the binding", func() {
+			nativeImage.BindingPath = t.TempDir()
+			Expect(os.WriteFile(filepath.Join(nativeImage.BindingPath, "policy"), []byte(`
+[[deny]]
+pattern = "test-argument-2"
+`), 0644)).To(Succeed())
+			nativeImage.Arguments = "test-argument-2"
+
+			_, err := nativeImage.Contribute(layer)
+			Expect(err).To(MatchError(ContainSubstring(fmt.Sprintf("test-argument-2 is denied by %s",
+				filepath.Join(nativeImage.BindingPath, "policy")))))
+		})
+
+		it("builds arguments that comply", func() {
+			nativeImage.Arguments = "test-argument-2"
+
+			_, err := nativeImage.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	context("keep patterns", func() {
+		it.Before(func() {
+			nativeImage.Keep = []string{"static/**", "*.properties"}
+
+			Expect(os.MkdirAll(filepath.Join(ctx.Application.Path, "static", "css"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "static", "css", "app.css"), []byte{}, 0644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "application.properties"), []byte{}, 0644)).To(Succeed())
+			Expect(os.MkdirAll(filepath.Join(ctx.Application.Path, "BOOT-INF", "classes"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "BOOT-INF", "classes", "logging.properties"), []byte{}, 0644)).To(Succeed())
+		})
+
+		it("keeps the files matching the patterns", func() {
+			_, err := nativeImage.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(filepath.Join(ctx.Application.Path, "static", "css", "app.css")).To(BeARegularFile())
+			Expect(filepath.Join(ctx.Application.Path, "application.properties")).To(BeARegularFile())
+			Expect(filepath.Join(ctx.Application.Path, "test-start-class")).To(BeARegularFile())
+
+			Expect(filepath.Join(ctx.Application.Path, "fixture-marker")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(ctx.Application.Path, "BOOT-INF")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(ctx.Application.Path, "META-INF")).NotTo(BeAnExistingFile())
+		})
+	})
+
 	context("tiny stack", func() {
 		it.Before(func() {
 			nativeImage.StackID = libpak.TinyStackID
@@ -414,7 +1230,7 @@ func testNativeImage(t *testing.T, context spec.G, it spec.S) {
 				"-cp",
 				strings.Join([]string{
 					ctx.Application.Path,
-					"manifest-class-path",
+					filepath.Join(ctx.Application.Path, "manifest-class-path"),
 				}, ":"),
 				"test-start-class",
 			}))
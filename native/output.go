@@ -0,0 +1,199 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package native
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/buildpacks/libcnb"
+	"github.com/paketo-buildpacks/libpak"
+	"github.com/paketo-buildpacks/libpak/bard"
+)
+
+const (
+	// OutputFile is the description of the native image written to the native-image-output layer
+	OutputFile = "output.toml"
+
+	// OutputSchemaVersion is the version of the OutputFile schema. It is incremented whenever a field is removed or
+	// its meaning changes, while fields may be added without incrementing it.
+	OutputSchemaVersion = 1
+
+	// OutputEnvironment is the environment variable that points downstream buildpacks to the OutputFile
+	OutputEnvironment = "NATIVE_IMAGE_OUTPUT"
+)
+
+// Output describes the native image to downstream buildpacks, such as those checking or signing it
+type Output struct {
+	SchemaVersion     int      `toml:"schema-version"`
+	Executable        string   `toml:"executable"`
+	Executables       []string `toml:"executables"`
+	Command           string   `toml:"command"`
+	Linking           string   `toml:"linking"`
+	BuilderVersion    string   `toml:"builder-version"`
+	SharedLibraries   []string `toml:"shared-libraries"`
+	RequiredLibraries []string `toml:"required-libraries"`
+	Compressed        bool     `toml:"compressed"`
+	Compression       string   `toml:"compression"`
+}
+
+// NativeImageOutput publishes the Output in a build layer, once the native image has been copied to the application
+type NativeImageOutput struct {
+	Command              string
+	Compressor           string
+	Executables          []string
+	Logger               bard.Logger
+	NativeImageLayerPath string
+}
+
+// NewNativeImageOutput creates a new instance. Executables are the native images in the application, from the most to
+// the least preferred variant, and command is that of the processes, either the native image or the launcher.
+func NewNativeImageOutput(nativeImageLayerPath string, executables []string, command string, compressor string) NativeImageOutput {
+	return NativeImageOutput{
+		Command:              command,
+		Compressor:           compressor,
+		Executables:          executables,
+		NativeImageLayerPath: nativeImageLayerPath,
+	}
+}
+
+func (o NativeImageOutput) Contribute(layer libcnb.Layer) (libcnb.Layer, error) {
+	output, err := o.output()
+	if err != nil {
+		return libcnb.Layer{}, fmt.Errorf("unable to describe native image\n%w", err)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := toml.NewEncoder(buf).Encode(output); err != nil {
+		return libcnb.Layer{}, fmt.Errorf("unable to encode native image output\n%w", err)
+	}
+
+	contributor := libpak.NewLayerContributor("Native Image Output", map[string]interface{}{
+		"output": fmt.Sprintf("%x", sha256.Sum256(buf.Bytes())),
+	}, libcnb.LayerTypes{
+		Build: true,
+	})
+	contributor.Logger = o.Logger
+
+	layer, err = contributor.Contribute(layer, func() (libcnb.Layer, error) {
+		file := filepath.Join(layer.Path, OutputFile)
+		if err := os.WriteFile(file, buf.Bytes(), 0644); err != nil {
+			return libcnb.Layer{}, fmt.Errorf("unable to write %s\n%w", file, err)
+		}
+
+		layer.BuildEnvironment.Default(OutputEnvironment, file)
+		return layer, nil
+	})
+	if err != nil {
+		return libcnb.Layer{}, fmt.Errorf("unable to contribute native-image-output layer\n%w", err)
+	}
+
+	return layer, nil
+}
+
+func (NativeImageOutput) Name() string {
+	return "native-image-output"
+}
+
+func (o NativeImageOutput) output() (Output, error) {
+	metadata, err := o.nativeImageMetadata()
+	if err != nil {
+		return Output{}, err
+	}
+	version, _ := metadata["version"].(string)
+	linking, _ := metadata["linking"].(string)
+
+	// the least preferred variant runs on any CPU the others run on
+	var executable string
+	if len(o.Executables) > 0 {
+		executable = o.Executables[len(o.Executables)-1]
+	}
+
+	compression := o.Compressor
+	if compression == "" {
+		compression = CompressorNone
+	}
+
+	output := Output{
+		SchemaVersion:     OutputSchemaVersion,
+		Executable:        executable,
+		Executables:       o.Executables,
+		Command:           o.Command,
+		Linking:           linking,
+		BuilderVersion:    version,
+		SharedLibraries:   []string{},
+		RequiredLibraries: []string{},
+		Compressed:        compression != CompressorNone,
+		Compression:       compression,
+	}
+
+	shipped, required := map[string]bool{}, map[string]bool{}
+	for _, exe := range o.Executables {
+		entries, err := os.ReadDir(filepath.Dir(exe))
+		if err != nil {
+			return Output{}, fmt.Errorf("unable to list files in %s\n%w", filepath.Dir(exe), err)
+		}
+		for _, e := range entries {
+			if e.Type().IsRegular() && strings.HasSuffix(e.Name(), ".so") {
+				shipped[filepath.Join(filepath.Dir(exe), e.Name())] = true
+			}
+		}
+
+		d, ok, err := NewELFDependencies(exe)
+		if err != nil {
+			return Output{}, fmt.Errorf("unable to read shared libraries of %s\n%w", exe, err)
+		} else if ok {
+			for _, l := range d.Libraries {
+				required[l] = true
+			}
+		}
+	}
+
+	for l := range shipped {
+		output.SharedLibraries = append(output.SharedLibraries, l)
+	}
+	sort.Strings(output.SharedLibraries)
+
+	for l := range required {
+		output.RequiredLibraries = append(output.RequiredLibraries, l)
+	}
+	sort.Strings(output.RequiredLibraries)
+
+	return output, nil
+}
+
+// nativeImageMetadata reads the metadata of the native-image layer, which libcnb writes as soon as the layer is
+// contributed. It records the native-image version and the linking mode of the arguments the native image was built
+// with, including those read from argument files.
+func (o NativeImageOutput) nativeImageMetadata() (map[string]interface{}, error) {
+	var layer struct {
+		Metadata map[string]interface{} `toml:"metadata"`
+	}
+
+	file := fmt.Sprintf("%s.toml", o.NativeImageLayerPath)
+	if _, err := toml.DecodeFile(file, &layer); err != nil {
+		return nil, fmt.Errorf("unable to read %s\n%w", file, err)
+	}
+
+	return layer.Metadata, nil
+}
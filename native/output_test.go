@@ -0,0 +1,133 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package native_test
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/buildpacks/libcnb"
+	. "github.com/onsi/gomega"
+	"github.com/paketo-buildpacks/libpak/bard"
+	"github.com/paketo-buildpacks/libpak/sherpa"
+	"github.com/sclevine/spec"
+
+	"github.com/paketo-buildpacks/native-image/v5/native"
+)
+
+func testOutput(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		ctx                  libcnb.BuildContext
+		nativeImageLayerPath string
+	)
+
+	it.Before(func() {
+		ctx.Application.Path = t.TempDir()
+		ctx.Layers.Path = t.TempDir()
+		nativeImageLayerPath = filepath.Join(ctx.Layers.Path, "native-image")
+
+		Expect(os.WriteFile(nativeImageLayerPath+".toml", []byte(`
+[metadata]
+  linking = "mostly-static"
+  version = "native-image 21.0.2 2024-01-16"
+`), 0644)).To(Succeed())
+
+		in, err := os.Open("testdata/dynamic-fixture")
+		Expect(err).NotTo(HaveOccurred())
+		defer in.Close()
+		Expect(sherpa.CopyFile(in, filepath.Join(ctx.Application.Path, "test-start-class"))).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "libawt.so"), []byte{}, 0644)).To(Succeed())
+	})
+
+	it("describes the native image to downstream buildpacks", func() {
+		executable := filepath.Join(ctx.Application.Path, "test-start-class")
+		o := native.NewNativeImageOutput(nativeImageLayerPath, []string{executable}, executable, "upx")
+		o.Logger = bard.NewLogger(io.Discard)
+
+		layer, err := ctx.Layers.Layer(o.Name())
+		Expect(err).NotTo(HaveOccurred())
+
+		layer, err = o.Contribute(layer)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(layer.Build).To(BeTrue())
+		Expect(layer.Launch).To(BeFalse())
+		Expect(layer.BuildEnvironment["NATIVE_IMAGE_OUTPUT.default"]).To(Equal(filepath.Join(layer.Path, "output.toml")))
+
+		var output native.Output
+		_, err = toml.DecodeFile(filepath.Join(layer.Path, "output.toml"), &output)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(output).To(Equal(native.Output{
+			SchemaVersion:     1,
+			Executable:        executable,
+			Executables:       []string{executable},
+			Command:           executable,
+			Linking:           native.LinkingMostlyStatic,
+			BuilderVersion:    "native-image 21.0.2 2024-01-16",
+			SharedLibraries:   []string{filepath.Join(ctx.Application.Path, "libawt.so")},
+			RequiredLibraries: []string{"libc.so.6", "libz.so.1"},
+			Compressed:        true,
+			Compression:       "upx",
+		}))
+	})
+
+	it("describes the native image of the least preferred variant as the executable", func() {
+		for _, v := range []string{"x86-64-v3", "compatibility"} {
+			in, err := os.Open("testdata/dynamic-fixture")
+			Expect(err).NotTo(HaveOccurred())
+			defer in.Close()
+			Expect(sherpa.CopyFile(in, filepath.Join(ctx.Application.Path, v, "test-start-class"))).To(Succeed())
+		}
+
+		executables := []string{
+			filepath.Join(ctx.Application.Path, "x86-64-v3", "test-start-class"),
+			filepath.Join(ctx.Application.Path, "compatibility", "test-start-class"),
+		}
+		launcher := filepath.Join(ctx.Layers.Path, "native-image-launcher", "bin", "launcher")
+		o := native.NewNativeImageOutput(nativeImageLayerPath, executables, launcher, "")
+		o.Logger = bard.NewLogger(io.Discard)
+
+		layer, err := ctx.Layers.Layer(o.Name())
+		Expect(err).NotTo(HaveOccurred())
+
+		layer, err = o.Contribute(layer)
+		Expect(err).NotTo(HaveOccurred())
+
+		var output native.Output
+		_, err = toml.DecodeFile(filepath.Join(layer.Path, "output.toml"), &output)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(output.Executable).To(Equal(executables[1]))
+		Expect(output.Executables).To(Equal(executables))
+		Expect(output.Command).To(Equal(launcher))
+	})
+
+	it("fails without the native-image layer", func() {
+		Expect(os.Remove(nativeImageLayerPath + ".toml")).To(Succeed())
+
+		o := native.NewNativeImageOutput(nativeImageLayerPath, nil, "", "")
+		layer, err := ctx.Layers.Layer(o.Name())
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = o.Contribute(layer)
+		Expect(err).To(MatchError(ContainSubstring("unable to describe native image")))
+	})
+}
@@ -0,0 +1,171 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package native
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/buildpacks/libcnb"
+	"github.com/mattn/go-shellwords"
+)
+
+const (
+	PlanMetadataArguments         = "args"
+	PlanMetadataArgumentFiles     = "argfiles"
+	PlanMetadataConfigDirectories = "config-directories"
+	PlanMetadataMainClass         = "main-class"
+	PlanMetadataExecutableName    = "executable-name"
+)
+
+// PlanMetadata is the configuration that upstream buildpacks, such as those of frameworks, contribute through the
+// metadata of the native-image-application plan entries they require. Relative paths are resolved against the
+// application.
+type PlanMetadata struct {
+	Arguments         []string
+	ArgumentFiles     []string
+	ConfigDirectories []string
+	MainClass         string
+	ExecutableName    string
+}
+
+// NewPlanMetadata merges the metadata of the native-image-application entries in plan. Lists are concatenated in the
+// order of the entries, while the main class and executable name must not conflict.
+func NewPlanMetadata(plan libcnb.BuildpackPlan, applicationPath string) (PlanMetadata, error) {
+	var m PlanMetadata
+
+	for _, entry := range plan.Entries {
+		if entry.Name != PlanEntryNativeImage {
+			continue
+		}
+
+		args, err := planStrings(entry.Metadata, PlanMetadataArguments)
+		if err != nil {
+			return PlanMetadata{}, err
+		}
+		for _, a := range args {
+			parsed, err := shellwords.Parse(a)
+			if err != nil {
+				return PlanMetadata{}, fmt.Errorf("unable to parse %s %s\n%w", PlanMetadataArguments, a, err)
+			}
+			m.Arguments = append(m.Arguments, parsed...)
+		}
+
+		files, err := planStrings(entry.Metadata, PlanMetadataArgumentFiles)
+		if err != nil {
+			return PlanMetadata{}, err
+		}
+		for _, f := range files {
+			m.ArgumentFiles = append(m.ArgumentFiles, resolvePath(applicationPath, f))
+		}
+
+		dirs, err := planStrings(entry.Metadata, PlanMetadataConfigDirectories)
+		if err != nil {
+			return PlanMetadata{}, err
+		}
+		for _, d := range dirs {
+			m.ConfigDirectories = append(m.ConfigDirectories, resolvePath(applicationPath, d))
+		}
+
+		if m.MainClass, err = planString(entry.Metadata, PlanMetadataMainClass, m.MainClass); err != nil {
+			return PlanMetadata{}, err
+		}
+		if m.ExecutableName, err = planString(entry.Metadata, PlanMetadataExecutableName, m.ExecutableName); err != nil {
+			return PlanMetadata{}, err
+		}
+	}
+
+	return m, nil
+}
+
+// IsEmpty returns whether no upstream buildpack contributed any configuration
+func (m PlanMetadata) IsEmpty() bool {
+	return len(m.Arguments) == 0 && len(m.ArgumentFiles) == 0 && len(m.ConfigDirectories) == 0 &&
+		m.MainClass == "" && m.ExecutableName == ""
+}
+
+// planStrings reads key of metadata as either a single string or a list of strings
+func planStrings(metadata map[string]interface{}, key string) ([]string, error) {
+	switch v := metadata[key].(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{v}, nil
+	case []string:
+		return v, nil
+	case []interface{}:
+		var s []string
+		for _, e := range v {
+			str, ok := e.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s of %s plan entry must be strings, not %T", key, PlanEntryNativeImage, e)
+			}
+			s = append(s, str)
+		}
+		return s, nil
+	default:
+		return nil, fmt.Errorf("%s of %s plan entry must be a string or a list of strings, not %T", key, PlanEntryNativeImage, v)
+	}
+}
+
+// planString reads key of metadata as a string, which must not conflict with the value of a previous entry
+func planString(metadata map[string]interface{}, key string, previous string) (string, error) {
+	raw, ok := metadata[key]
+	if !ok {
+		return previous, nil
+	}
+
+	s, ok := raw.(string)
+	if !ok {
+		return "", fmt.Errorf("%s of %s plan entry must be a string, not %T", key, PlanEntryNativeImage, raw)
+	}
+
+	s = strings.TrimSpace(s)
+	if previous != "" && s != previous {
+		return "", fmt.Errorf("conflicting %s %s and %s in %s plan entries", key, previous, s, PlanEntryNativeImage)
+	}
+	return s, nil
+}
+
+func resolvePath(base string, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(base, path)
+}
+
+// PlanArguments adds the arguments, argument files and configuration directories of the PlanMetadata. They are
+// configured before the user arguments, which therefore take precedence.
+type PlanArguments struct {
+	Metadata PlanMetadata
+}
+
+// Configure appends the arguments of the plan metadata to inputArgs
+func (p PlanArguments) Configure(inputArgs []string) ([]string, string, error) {
+	inputArgs = append(inputArgs, p.Metadata.Arguments...)
+
+	for _, f := range p.Metadata.ArgumentFiles {
+		inputArgs = append(inputArgs, fmt.Sprintf("@%s", f))
+	}
+
+	if len(p.Metadata.ConfigDirectories) > 0 {
+		inputArgs = append(inputArgs, fmt.Sprintf("-H:ConfigurationFileDirectories=%s", strings.Join(p.Metadata.ConfigDirectories, ",")))
+	}
+
+	return inputArgs, "", nil
+}
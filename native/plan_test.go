@@ -0,0 +1,107 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package native_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/buildpacks/libcnb"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+
+	"github.com/paketo-buildpacks/native-image/v5/native"
+)
+
+func testPlan(t *testing.T, context spec.G, it spec.S) {
+	var Expect = NewWithT(t).Expect
+
+	context("NewPlanMetadata", func() {
+		it("is empty without metadata", func() {
+			m, err := native.NewPlanMetadata(libcnb.BuildpackPlan{Entries: []libcnb.BuildpackPlanEntry{
+				{Name: "native-image-application"},
+			}}, "/workspace")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(m.IsEmpty()).To(BeTrue())
+		})
+
+		it("merges the metadata of the entries", func() {
+			m, err := native.NewPlanMetadata(libcnb.BuildpackPlan{Entries: []libcnb.BuildpackPlanEntry{
+				{Name: "native-image-application", Metadata: map[string]interface{}{
+					"args":               []interface{}{"--initialize-at-build-time=org.example", "-H:+ReportExceptionStackTraces"},
+					"argfiles":           "META-INF/native-image/argfile",
+					"config-directories": []interface{}{"META-INF/native-image", "/config"},
+					"main-class":         "org.example.Main",
+				}},
+				{Name: "jvm-application", Metadata: map[string]interface{}{
+					"args": "--ignored",
+				}},
+				{Name: "native-image-application", Metadata: map[string]interface{}{
+					"args":            "-O2 '-Dkey=a value'",
+					"main-class":      "org.example.Main",
+					"executable-name": "example",
+				}},
+			}}, "/workspace")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(m).To(Equal(native.PlanMetadata{
+				Arguments:         []string{"--initialize-at-build-time=org.example", "-H:+ReportExceptionStackTraces", "-O2", "-Dkey=a value"},
+				ArgumentFiles:     []string{filepath.Join("/workspace", "META-INF", "native-image", "argfile")},
+				ConfigDirectories: []string{filepath.Join("/workspace", "META-INF", "native-image"), "/config"},
+				MainClass:         "org.example.Main",
+				ExecutableName:    "example",
+			}))
+		})
+
+		it("rejects conflicting main classes", func() {
+			_, err := native.NewPlanMetadata(libcnb.BuildpackPlan{Entries: []libcnb.BuildpackPlanEntry{
+				{Name: "native-image-application", Metadata: map[string]interface{}{"main-class": "org.example.A"}},
+				{Name: "native-image-application", Metadata: map[string]interface{}{"main-class": "org.example.B"}},
+			}}, "/workspace")
+			Expect(err).To(MatchError("conflicting main-class org.example.A and org.example.B in native-image-application plan entries"))
+		})
+
+		it("rejects metadata of the wrong type", func() {
+			_, err := native.NewPlanMetadata(libcnb.BuildpackPlan{Entries: []libcnb.BuildpackPlanEntry{
+				{Name: "native-image-application", Metadata: map[string]interface{}{"args": 1}},
+			}}, "/workspace")
+			Expect(err).To(MatchError("args of native-image-application plan entry must be a string or a list of strings, not int"))
+		})
+	})
+
+	context("PlanArguments", func() {
+		it("appends the arguments, argument files and configuration directories", func() {
+			args, startClass, err := native.PlanArguments{Metadata: native.PlanMetadata{
+				Arguments:         []string{"-O2"},
+				ArgumentFiles:     []string{"/workspace/argfile"},
+				ConfigDirectories: []string{"/workspace/a", "/workspace/b"},
+			}}.Configure([]string{"-g"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(startClass).To(BeEmpty())
+			Expect(args).To(Equal([]string{"-g", "-O2", "@/workspace/argfile", "-H:ConfigurationFileDirectories=/workspace/a,/workspace/b"}))
+		})
+
+		it("gives precedence to user arguments", func() {
+			args, _, err := native.PlanArguments{Metadata: native.PlanMetadata{Arguments: []string{"--gc=epsilon", "--verbose"}}}.Configure(nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			args, _, err = native.UserArguments{Arguments: "--gc=serial"}.Configure(args)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(args).To(Equal([]string{"--verbose", "--gc=serial"}))
+		})
+	})
+}
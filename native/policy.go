@@ -0,0 +1,223 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package native
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/paketo-buildpacks/native-image/v5/native/slices"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Policy restricts the arguments passed to native-image, such as those an organization forbids in production
+// images. An argument matching a Deny rule violates the policy unless it also matches an Allow rule, and every Require
+// rule must be matched by at least one argument.
+type Policy struct {
+	// File is the file the policy was read from
+	File string `toml:"-"`
+
+	Allow   []PolicyRule `toml:"allow"`
+	Deny    []PolicyRule `toml:"deny"`
+	Require []PolicyRule `toml:"require"`
+}
+
+// PolicyRule matches arguments with Pattern, in which * matches any sequence of characters and ? any single
+// character. A rule with Profiles only applies when one of them is selected with $BP_NATIVE_IMAGE_PROFILE.
+type PolicyRule struct {
+	Pattern  string   `toml:"pattern"`
+	Reason   string   `toml:"reason"`
+	Profiles []string `toml:"profiles"`
+}
+
+// ReadPolicy reads the policy in file, rejecting unknown keys and rules without a pattern
+func ReadPolicy(file string) (Policy, error) {
+	var p Policy
+
+	md, err := toml.DecodeFile(file, &p)
+	if err != nil {
+		return Policy{}, fmt.Errorf("unable to decode %s\n%w", file, err)
+	}
+	p.File = file
+
+	var problems []string
+	for _, key := range md.Undecoded() {
+		problems = append(problems, fmt.Sprintf("%s: unknown key", key.String()))
+	}
+	for name, rules := range map[string][]PolicyRule{"allow": p.Allow, "deny": p.Deny, "require": p.Require} {
+		for i, r := range rules {
+			if r.Pattern == "" {
+				problems = append(problems, fmt.Sprintf("%s[%d].pattern: must be set", name, i))
+			}
+		}
+	}
+	sort.Strings(problems)
+
+	if len(problems) > 0 {
+		return Policy{}, fmt.Errorf("invalid %s\n%s", file, strings.Join(problems, "\n"))
+	}
+
+	return p, nil
+}
+
+// Violations returns the violations of the policy by arguments, whose sources are recorded in provenance, for the
+// selected profile
+func (p Policy) Violations(arguments []string, provenance Provenance, profile string) []string {
+	var violations []string
+
+	for _, a := range arguments {
+		deny, denied := p.match(p.Deny, a, profile)
+		if !denied {
+			continue
+		}
+		if _, allowed := p.match(p.Allow, a, profile); allowed {
+			continue
+		}
+
+		violations = append(violations, fmt.Sprintf("%s is denied by %s%s, set by %s",
+			a, p.File, reason(deny), strings.Join(provenance.Sources(a), " and ")))
+	}
+
+	for _, r := range p.Require {
+		if !r.applies(profile) {
+			continue
+		}
+
+		found := false
+		for _, a := range arguments {
+			if r.matches(a) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			violations = append(violations, fmt.Sprintf("an argument matching %s is required by %s%s", r.Pattern, p.File, reason(r)))
+		}
+	}
+
+	return violations
+}
+
+// match returns the first of rules that applies to profile and matches argument
+func (Policy) match(rules []PolicyRule, argument string, profile string) (PolicyRule, bool) {
+	for _, r := range rules {
+		if r.applies(profile) && r.matches(argument) {
+			return r, true
+		}
+	}
+	return PolicyRule{}, false
+}
+
+func (r PolicyRule) applies(profile string) bool {
+	if len(r.Profiles) == 0 {
+		return true
+	}
+	for _, p := range r.Profiles {
+		if p == profile {
+			return true
+		}
+	}
+	return false
+}
+
+func (r PolicyRule) matches(argument string) bool {
+	expr := regexp.QuoteMeta(r.Pattern)
+	expr = strings.ReplaceAll(expr, `\*`, ".*")
+	expr = strings.ReplaceAll(expr, `\?`, ".")
+	return regexp.MustCompile("^" + expr + "$").MatchString(argument)
+}
+
+func reason(r PolicyRule) string {
+	if r.Reason == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (%s)", r.Reason)
+}
+
+// Provenance records the sources of the arguments passed to native-image, such as upstream buildpacks, a binding or
+// the user configuration
+type Provenance map[string][]string
+
+// Configure configures a with inputArgs, recording the arguments it adds as coming from source
+func (p Provenance) Configure(a Arguments, source string, inputArgs []string) ([]string, string, error) {
+	outputArgs, startClass, err := a.Configure(inputArgs)
+	if err != nil {
+		return outputArgs, startClass, err
+	}
+
+	p.Record(source, inputArgs, outputArgs)
+	return outputArgs, startClass, nil
+}
+
+// Record records the arguments of after that are not in before as coming from source
+func (p Provenance) Record(source string, before []string, after []string) {
+	counts := map[string]int{}
+	for _, a := range before {
+		counts[a]++
+	}
+
+	for _, a := range after {
+		if counts[a] > 0 {
+			counts[a]--
+			continue
+		}
+
+		if !slices.Contains(p[a], source) {
+			p[a] = append(p[a], source)
+		}
+	}
+}
+
+// Sources returns the sources of argument
+func (p Provenance) Sources(argument string) []string {
+	if s, ok := p[argument]; ok {
+		return s
+	}
+	return []string{"an unknown source"}
+}
+
+// Expand returns arguments with the contents of the argument files they reference, recording each argument read from
+// a file as coming from that file
+func (p Provenance) Expand(arguments []string) ([]string, error) {
+	var expanded []string
+
+	for _, a := range arguments {
+		expanded = append(expanded, a)
+		if !strings.HasPrefix(a, "@") {
+			continue
+		}
+
+		file := strings.TrimPrefix(a, "@")
+		args, err := readArgumentsFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		source := fmt.Sprintf("%s from %s", file, strings.Join(p.Sources(a), " and "))
+		for _, f := range args {
+			expanded = append(expanded, f)
+			if !slices.Contains(p[f], source) {
+				p[f] = append(p[f], source)
+			}
+		}
+	}
+
+	return expanded, nil
+}
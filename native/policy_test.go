@@ -0,0 +1,164 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package native_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+
+	"github.com/paketo-buildpacks/native-image/v5/native"
+)
+
+func testPolicy(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		path string
+	)
+
+	it.Before(func() {
+		path = t.TempDir()
+	})
+
+	context("ReadPolicy", func() {
+		it("reads the rules", func() {
+			file := filepath.Join(path, "policy.toml")
+			Expect(os.WriteFile(file, []byte(`
+[[deny]]
+pattern = "-H:+AllowVMInspection"
+reason  = "exposes the VM"
+
+[[allow]]
+pattern = "-H:Name=*"
+
+[[require]]
+pattern  = "--no-fallback"
+profiles = ["production"]
+`), 0644)).To(Succeed())
+
+			p, err := native.ReadPolicy(file)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(p).To(Equal(native.Policy{
+				File:    file,
+				Allow:   []native.PolicyRule{{Pattern: "-H:Name=*"}},
+				Deny:    []native.PolicyRule{{Pattern: "-H:+AllowVMInspection", Reason: "exposes the VM"}},
+				Require: []native.PolicyRule{{Pattern: "--no-fallback", Profiles: []string{"production"}}},
+			}))
+		})
+
+		it("rejects unknown keys and rules without a pattern", func() {
+			file := filepath.Join(path, "policy.toml")
+			Expect(os.WriteFile(file, []byte(`
+[[deny]]
+flag = "-march=native"
+`), 0644)).To(Succeed())
+
+			_, err := native.ReadPolicy(file)
+			Expect(err).To(MatchError(ContainSubstring("deny.flag: unknown key")))
+			Expect(err).To(MatchError(ContainSubstring("deny[0].pattern: must be set")))
+		})
+	})
+
+	context("Violations", func() {
+		var (
+			policy     native.Policy
+			provenance native.Provenance
+		)
+
+		it.Before(func() {
+			policy = native.Policy{
+				File: "policy.toml",
+				Allow: []native.PolicyRule{
+					{Pattern: "-H:+ReportExceptionStackTraces", Profiles: []string{"development"}},
+				},
+				Deny: []native.PolicyRule{
+					{Pattern: "-H:+AllowVMInspection", Reason: "exposes the VM"},
+					{Pattern: "-march=native"},
+					{Pattern: "-H:+ReportExceptionStackTraces"},
+				},
+				Require: []native.PolicyRule{
+					{Pattern: "--no-fallback"},
+					{Pattern: "-H:+StaticExecutable*", Profiles: []string{"production"}},
+				},
+			}
+
+			provenance = native.Provenance{}
+			provenance.Record("the buildpack", nil, []string{"--no-fallback"})
+			provenance.Record("$BP_NATIVE_IMAGE_BUILD_ARGUMENTS", []string{"--no-fallback"},
+				[]string{"--no-fallback", "-H:+AllowVMInspection", "-H:+ReportExceptionStackTraces"})
+		})
+
+		it("reports denied arguments with their sources", func() {
+			Expect(policy.Violations([]string{"--no-fallback", "-H:+AllowVMInspection", "-H:+ReportExceptionStackTraces"}, provenance, "")).To(Equal([]string{
+				"-H:+AllowVMInspection is denied by policy.toml (exposes the VM), set by $BP_NATIVE_IMAGE_BUILD_ARGUMENTS",
+				"-H:+ReportExceptionStackTraces is denied by policy.toml, set by $BP_NATIVE_IMAGE_BUILD_ARGUMENTS",
+			}))
+		})
+
+		it("applies the rules of the profile", func() {
+			Expect(policy.Violations([]string{"--no-fallback", "-H:+ReportExceptionStackTraces"}, provenance, "development")).To(BeEmpty())
+			Expect(policy.Violations([]string{"--no-fallback"}, provenance, "production")).To(Equal([]string{
+				"an argument matching -H:+StaticExecutable* is required by policy.toml",
+			}))
+		})
+
+		it("reports missing required arguments", func() {
+			Expect(policy.Violations([]string{"--auto-fallback"}, provenance, "")).To(ContainElement(
+				"an argument matching --no-fallback is required by policy.toml",
+			))
+		})
+
+		it("matches patterns against the whole argument", func() {
+			Expect(policy.Violations([]string{"--no-fallback", "-march=native-like"}, provenance, "")).To(BeEmpty())
+		})
+	})
+
+	context("Provenance", func() {
+		it("records the sources of the arguments each configuration adds", func() {
+			provenance := native.Provenance{}
+
+			args, _, err := provenance.Configure(native.UserArguments{Arguments: "-g --gc=serial"}, "upstream buildpacks", nil)
+			Expect(err).NotTo(HaveOccurred())
+			args, _, err = provenance.Configure(native.UserArguments{Arguments: "--gc=epsilon -g"}, "$BP_NATIVE_IMAGE_BUILD_ARGUMENTS", args)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(args).To(Equal([]string{"--gc=epsilon", "-g"}))
+			Expect(provenance.Sources("--gc=serial")).To(Equal([]string{"upstream buildpacks"}))
+			Expect(provenance.Sources("--gc=epsilon")).To(Equal([]string{"$BP_NATIVE_IMAGE_BUILD_ARGUMENTS"}))
+			Expect(provenance.Sources("-g")).To(Equal([]string{"upstream buildpacks"}))
+		})
+
+		it("expands argument files", func() {
+			file := filepath.Join(path, "native-image.args")
+			Expect(os.WriteFile(file, []byte("-march=native\n-H:+AllowVMInspection\n"), 0644)).To(Succeed())
+
+			provenance := native.Provenance{}
+			provenance.Record("the native-image binding", nil, []string{fmt.Sprintf("@%s", file)})
+
+			args, err := provenance.Expand([]string{"-g", fmt.Sprintf("@%s", file)})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(args).To(Equal([]string{"-g", fmt.Sprintf("@%s", file), "-march=native", "-H:+AllowVMInspection"}))
+			Expect(provenance.Sources("-march=native")).To(Equal([]string{fmt.Sprintf("%s from the native-image binding", file)}))
+			Expect(provenance.Sources("-g")).To(Equal([]string{"an unknown source"}))
+		})
+	})
+}
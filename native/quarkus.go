@@ -0,0 +1,181 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package native
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/paketo-buildpacks/libpak/sherpa"
+)
+
+const (
+	// QuarkusArgumentsFile is the file listing the native-image arguments in a Quarkus native-sources directory
+	QuarkusArgumentsFile = "native-image.args"
+)
+
+// QuarkusNativeSourcesDirectories are the directories of the application, relative to it, that are searched for the
+// native-sources directory written by Quarkus with quarkus.package.type=native-sources
+var QuarkusNativeSourcesDirectories = []string{
+	".",
+	filepath.Join("target", "native-sources"),
+	filepath.Join("build", "native-sources"),
+}
+
+// quarkusPathOptions are the native-image options whose values are paths, or comma separated lists of paths
+var quarkusPathOptions = []string{
+	"-H:ConfigurationFileDirectories=",
+	"-H:ReflectionConfigurationFiles=",
+	"-H:ResourceConfigurationFiles=",
+	"-H:JNIConfigurationFiles=",
+	"-H:DynamicProxyConfigurationFiles=",
+	"-H:SerializationConfigurationFiles=",
+	"-H:PredefinedClassesConfigurationFiles=",
+}
+
+// FindQuarkusNativeSources returns the native-sources directory of the application at applicationPath, if any. It
+// contains the native-image.args file and the runner JAR.
+func FindQuarkusNativeSources(applicationPath string) (string, bool, error) {
+	for _, d := range QuarkusNativeSourcesDirectories {
+		dir := filepath.Join(applicationPath, d)
+
+		if ok, err := sherpa.FileExists(filepath.Join(dir, QuarkusArgumentsFile)); err != nil {
+			return "", false, fmt.Errorf("unable to check for %s in %s\n%w", QuarkusArgumentsFile, dir, err)
+		} else if !ok {
+			continue
+		}
+
+		jars, err := filepath.Glob(filepath.Join(dir, "*-runner.jar"))
+		if err != nil {
+			return "", false, fmt.Errorf("unable to find runner JAR in %s\n%w", dir, err)
+		} else if len(jars) > 0 {
+			return dir, true, nil
+		}
+	}
+
+	return "", false, nil
+}
+
+// QuarkusArguments provides the arguments written by Quarkus to the native-image.args file of a native-sources
+// directory. Relative paths are resolved against the directory and the image is named as Quarkus named it, unless
+// ExecutableName is set.
+type QuarkusArguments struct {
+	Directory      string
+	ExecutableName string
+	LayerPath      string
+}
+
+// Configure appends the arguments of the native-image.args file to inputArgs, returning the name of the image
+func (q QuarkusArguments) Configure(inputArgs []string) ([]string, string, error) {
+	args, err := q.read()
+	if err != nil {
+		return []string{}, "", err
+	}
+
+	var name string
+	var outputArgs []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		switch {
+		case strings.HasPrefix(arg, "-H:Name="):
+			name = strings.TrimPrefix(arg, "-H:Name=")
+		case arg == "-o" && i+1 < len(args):
+			i++
+			name = args[i]
+		case (arg == "-jar" || arg == "-cp" || arg == "-classpath" || arg == "--class-path") && i+1 < len(args):
+			i++
+			value := args[i]
+			if arg != "-jar" {
+				var entries []string
+				for _, e := range filepath.SplitList(value) {
+					entries = append(entries, q.resolve(e))
+				}
+				value = strings.Join(entries, string(filepath.ListSeparator))
+			} else {
+				value = q.resolve(value)
+			}
+			outputArgs = append(outputArgs, arg, value)
+		case strings.HasPrefix(arg, "@"):
+			outputArgs = append(outputArgs, fmt.Sprintf("@%s", q.resolve(strings.TrimPrefix(arg, "@"))))
+		default:
+			outputArgs = append(outputArgs, q.resolvePathOption(arg))
+		}
+	}
+
+	if name == "" {
+		jars, err := filepath.Glob(filepath.Join(q.Directory, "*-runner.jar"))
+		if err != nil || len(jars) == 0 {
+			return []string{}, "", fmt.Errorf("unable to find image name in %s", filepath.Join(q.Directory, QuarkusArgumentsFile))
+		}
+		name = strings.TrimSuffix(filepath.Base(jars[0]), ".jar")
+	}
+	name = filepath.Base(name)
+
+	if q.ExecutableName != "" {
+		name = q.ExecutableName
+	}
+
+	inputArgs = append(inputArgs, outputArgs...)
+	inputArgs = append(inputArgs, fmt.Sprintf("-H:Name=%s", filepath.Join(q.LayerPath, name)))
+
+	return inputArgs, name, nil
+}
+
+// ImageName returns the name Quarkus gave the image, or ExecutableName if it is set
+func (q QuarkusArguments) ImageName() (string, error) {
+	_, name, err := q.Configure(nil)
+	return name, err
+}
+
+// read reads the native-image.args file, which has an argument per line
+func (q QuarkusArguments) read() ([]string, error) {
+	file := filepath.Join(q.Directory, QuarkusArgumentsFile)
+	raw, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s\n%w", file, err)
+	}
+
+	var args []string
+	for _, a := range splitArgumentsFile(strings.TrimSpace(string(raw))) {
+		if a = strings.TrimSpace(a); a != "" {
+			args = append(args, a)
+		}
+	}
+	return args, nil
+}
+
+func (q QuarkusArguments) resolve(path string) string {
+	return resolvePath(q.Directory, path)
+}
+
+func (q QuarkusArguments) resolvePathOption(arg string) string {
+	for _, option := range quarkusPathOptions {
+		if !strings.HasPrefix(arg, option) {
+			continue
+		}
+
+		var paths []string
+		for _, p := range strings.Split(strings.TrimPrefix(arg, option), ",") {
+			paths = append(paths, q.resolve(p))
+		}
+		return option + strings.Join(paths, ",")
+	}
+	return arg
+}
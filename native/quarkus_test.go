@@ -0,0 +1,128 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package native_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+
+	"github.com/paketo-buildpacks/native-image/v5/native"
+)
+
+func testQuarkus(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		appPath string
+		dir     string
+	)
+
+	it.Before(func() {
+		appPath = t.TempDir()
+		dir = filepath.Join(appPath, "target", "native-sources")
+		Expect(os.MkdirAll(dir, 0755)).To(Succeed())
+	})
+
+	context("FindQuarkusNativeSources", func() {
+		it("finds native sources in the target directory", func() {
+			Expect(os.WriteFile(filepath.Join(dir, "native-image.args"), []byte{}, 0644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(dir, "app-1.0-runner.jar"), []byte{}, 0644)).To(Succeed())
+
+			found, ok, err := native.FindQuarkusNativeSources(appPath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeTrue())
+			Expect(found).To(Equal(dir))
+		})
+
+		it("finds native sources at the root of the application", func() {
+			Expect(os.WriteFile(filepath.Join(appPath, "native-image.args"), []byte{}, 0644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(appPath, "app-1.0-runner.jar"), []byte{}, 0644)).To(Succeed())
+
+			found, ok, err := native.FindQuarkusNativeSources(appPath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeTrue())
+			Expect(found).To(Equal(appPath))
+		})
+
+		it("requires the runner JAR", func() {
+			Expect(os.WriteFile(filepath.Join(dir, "native-image.args"), []byte{}, 0644)).To(Succeed())
+
+			_, ok, err := native.FindQuarkusNativeSources(appPath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeFalse())
+		})
+	})
+
+	context("QuarkusArguments", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(filepath.Join(dir, "app-1.0-runner.jar"), []byte{}, 0644)).To(Succeed())
+		})
+
+		it("resolves paths against the directory and keeps the image name", func() {
+			Expect(os.WriteFile(filepath.Join(dir, "native-image.args"), []byte(`-J-Djava.util.logging.manager=org.jboss.logmanager.LogManager
+-H:ConfigurationFileDirectories=config,/absolute
+--no-fallback
+@extra.args
+-H:Name=app-1.0-runner
+-jar
+app-1.0-runner.jar
+`), 0644)).To(Succeed())
+
+			args, name, err := native.QuarkusArguments{Directory: dir, LayerPath: "/layer"}.Configure([]string{"-g"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(name).To(Equal("app-1.0-runner"))
+			Expect(args).To(Equal([]string{
+				"-g",
+				"-J-Djava.util.logging.manager=org.jboss.logmanager.LogManager",
+				"-H:ConfigurationFileDirectories=" + filepath.Join(dir, "config") + ",/absolute",
+				"--no-fallback",
+				"@" + filepath.Join(dir, "extra.args"),
+				"-jar", filepath.Join(dir, "app-1.0-runner.jar"),
+				"-H:Name=/layer/app-1.0-runner",
+			}))
+		})
+
+		it("reads the image name from -o", func() {
+			Expect(os.WriteFile(filepath.Join(dir, "native-image.args"), []byte("-o\napp\n-jar\napp-1.0-runner.jar\n"), 0644)).To(Succeed())
+
+			args, name, err := native.QuarkusArguments{Directory: dir}.Configure(nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(name).To(Equal("app"))
+			Expect(args).To(Equal([]string{"-jar", filepath.Join(dir, "app-1.0-runner.jar"), "-H:Name=app"}))
+		})
+
+		it("names the image after the runner JAR", func() {
+			Expect(os.WriteFile(filepath.Join(dir, "native-image.args"), []byte("-jar app-1.0-runner.jar"), 0644)).To(Succeed())
+
+			name, err := native.QuarkusArguments{Directory: dir}.ImageName()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(name).To(Equal("app-1.0-runner"))
+		})
+
+		it("uses the executable name of upstream buildpacks", func() {
+			Expect(os.WriteFile(filepath.Join(dir, "native-image.args"), []byte("-H:Name=app-1.0-runner\n-jar\napp-1.0-runner.jar\n"), 0644)).To(Succeed())
+
+			name, err := native.QuarkusArguments{Directory: dir, ExecutableName: "example"}.ImageName()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(name).To(Equal("example"))
+		})
+	})
+}
@@ -0,0 +1,124 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package native
+
+import (
+	"debug/elf"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// SizeBreakdown is the size of an executable and, if its section headers can be read, the size of its sections
+// grouped by the areas of a native image
+type SizeBreakdown struct {
+	Total     int64
+	Sections  bool
+	CodeArea  int64
+	ImageHeap int64
+	Debug     int64
+	Other     int64
+}
+
+// NewSizeBreakdown measures the executable at path. Compressed executables have no section headers, in which case only
+// the total size is measured.
+func NewSizeBreakdown(path string) (SizeBreakdown, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return SizeBreakdown{}, fmt.Errorf("unable to stat %s\n%w", path, err)
+	}
+	b := SizeBreakdown{Total: info.Size()}
+
+	f, err := elf.Open(path)
+	if err != nil {
+		return b, nil
+	}
+	defer f.Close()
+
+	for _, s := range f.Sections {
+		if s.Type == elf.SHT_NOBITS || s.Type == elf.SHT_NULL {
+			continue
+		}
+
+		b.Sections = true
+		switch {
+		case s.Name == ".text":
+			b.CodeArea += int64(s.FileSize)
+		case s.Name == ".svm_heap":
+			b.ImageHeap += int64(s.FileSize)
+		case strings.HasPrefix(s.Name, ".debug") || strings.HasPrefix(s.Name, ".zdebug"):
+			b.Debug += int64(s.FileSize)
+		}
+	}
+	if b.Sections {
+		b.Other = b.Total - b.CodeArea - b.ImageHeap - b.Debug
+	}
+
+	return b, nil
+}
+
+func (b SizeBreakdown) String() string {
+	if !b.Sections {
+		return fmt.Sprintf("  total: %s (no section headers, the executable may be compressed)", formatSize(b.Total))
+	}
+
+	return strings.Join([]string{
+		fmt.Sprintf("  code area: %s", formatSize(b.CodeArea)),
+		fmt.Sprintf("  image heap: %s", formatSize(b.ImageHeap)),
+		fmt.Sprintf("  debug sections: %s", formatSize(b.Debug)),
+		fmt.Sprintf("  other: %s", formatSize(b.Other)),
+		fmt.Sprintf("  total: %s", formatSize(b.Total)),
+	}, "\n")
+}
+
+// ParseSize parses a number of bytes with an optional `K`, `M`, `G` or `T` suffix, as powers of 1024
+func ParseSize(size string) (int64, error) {
+	s := strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(size)), "B")
+	s = strings.TrimSuffix(s, "I")
+
+	multiplier := int64(1)
+	if i := strings.IndexAny(s, "KMGT"); i >= 0 && i == len(s)-1 {
+		for j := 0; j <= strings.IndexByte("KMGT", s[i]); j++ {
+			multiplier *= 1024
+		}
+		s = s[:i]
+	}
+
+	n, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("unable to parse size %s", size)
+	}
+
+	return int64(n * float64(multiplier)), nil
+}
+
+// formatSize formats size as a human readable number of bytes
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,82 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package native_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+
+	"github.com/paketo-buildpacks/native-image/v5/native"
+)
+
+func testSize(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+	)
+
+	it("parses sizes", func() {
+		for raw, expected := range map[string]int64{
+			"100":    100,
+			"1K":     1024,
+			"1.5M":   1536 * 1024,
+			"5G":     5 * 1024 * 1024 * 1024,
+			"5GB":    5 * 1024 * 1024 * 1024,
+			"5GiB":   5 * 1024 * 1024 * 1024,
+			"2t":     2 * 1024 * 1024 * 1024 * 1024,
+			" 10 M ": 10 * 1024 * 1024,
+		} {
+			Expect(native.ParseSize(raw)).To(Equal(expected), raw)
+		}
+	})
+
+	it("rejects invalid sizes", func() {
+		for _, raw := range []string{"", "G", "-1", "ten"} {
+			_, err := native.ParseSize(raw)
+			Expect(err).To(HaveOccurred(), raw)
+		}
+	})
+
+	context("size breakdown", func() {
+		it("measures the sections of an ELF executable", func() {
+			path, err := os.Executable()
+			Expect(err).NotTo(HaveOccurred())
+
+			b, err := native.NewSizeBreakdown(path)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(b.Sections).To(BeTrue())
+			Expect(b.CodeArea).To(BeNumerically(">", 0))
+			Expect(b.CodeArea + b.ImageHeap + b.Debug + b.Other).To(Equal(b.Total))
+			Expect(b.String()).To(ContainSubstring("code area: "))
+		})
+
+		it("measures only the total size of other files", func() {
+			path := filepath.Join(t.TempDir(), "compressed")
+			Expect(os.WriteFile(path, make([]byte, 2048), 0755)).To(Succeed())
+
+			b, err := native.NewSizeBreakdown(path)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(b).To(Equal(native.SizeBreakdown{Total: 2048}))
+			Expect(b.String()).To(Equal("  total: 2.0 KiB (no section headers, the executable may be compressed)"))
+		})
+	})
+}
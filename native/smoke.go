@@ -0,0 +1,148 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package native
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/mattn/go-shellwords"
+	"github.com/paketo-buildpacks/libpak/bard"
+	"github.com/paketo-buildpacks/libpak/effect"
+)
+
+const (
+	// AnyExitCode disables the check of the exit code of the smoke test
+	AnyExitCode = -1
+
+	// DefaultSmokeTestTimeout is the time the smoke test may run before it is killed
+	DefaultSmokeTestTimeout = "30s"
+
+	// timeoutExitCode is the exit code of timeout(1) when the command timed out
+	timeoutExitCode = 124
+)
+
+var (
+	classNotFound     = regexp.MustCompile(`(?:ClassNotFoundException|NoClassDefFoundError):?\s+([\w.$/]+)`)
+	missingRegistered = regexp.MustCompile(`(Missing\w+RegistrationError)`)
+)
+
+// SmokeTestContext is the data available to the smoke test command template
+type SmokeTestContext struct {
+	Executable string
+}
+
+// SmokeTest runs the native image once it is built and checks its exit code and output
+type SmokeTest struct {
+	Command  string
+	ExitCode int
+	Executor effect.Executor
+	Logger   bard.Logger
+	Output   *regexp.Regexp
+	Timeout  time.Duration
+}
+
+// Enabled returns whether a smoke test command is configured
+func (s SmokeTest) Enabled() bool {
+	return s.Command != ""
+}
+
+// Run runs the smoke test against executable with dir as its working directory
+func (s SmokeTest) Run(executable string, dir string) error {
+	t, err := template.New("smoke-test").Parse(s.Command)
+	if err != nil {
+		return fmt.Errorf("unable to parse smoke test command %s\n%w", s.Command, err)
+	}
+
+	command := &strings.Builder{}
+	if err := t.Execute(command, SmokeTestContext{Executable: executable}); err != nil {
+		return fmt.Errorf("unable to render smoke test command %s\n%w", s.Command, err)
+	}
+
+	args, err := shellwords.Parse(command.String())
+	if err != nil {
+		return fmt.Errorf("unable to parse smoke test command %s\n%w", command, err)
+	} else if len(args) == 0 {
+		return fmt.Errorf("smoke test command %s is empty", s.Command)
+	}
+
+	s.Logger.Bodyf("Executing smoke test %s", command)
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	err = s.Executor.Execute(effect.Execution{
+		Command: "timeout",
+		Args:    append([]string{"--kill-after=5s", fmt.Sprintf("%gs", s.Timeout.Seconds())}, args...),
+		Dir:     dir,
+		Stdout:  stdout,
+		Stderr:  stderr,
+	})
+
+	exitCode := 0
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		exitCode = exitErr.ExitCode()
+	} else if err != nil {
+		return fmt.Errorf("error running smoke test %s\n%w", command, err)
+	}
+
+	var failure string
+	switch {
+	case exitCode == timeoutExitCode:
+		failure = fmt.Sprintf("timed out after %s", s.Timeout)
+	case s.ExitCode != AnyExitCode && exitCode != s.ExitCode:
+		failure = fmt.Sprintf("exited with %d, expected %d", exitCode, s.ExitCode)
+	case s.Output != nil && !s.Output.Match(stdout.Bytes()):
+		failure = fmt.Sprintf("output does not match %s", s.Output)
+	default:
+		s.Logger.Body("Smoke test passed")
+		return nil
+	}
+
+	output := stdout.String() + stderr.String()
+	msg := fmt.Sprintf("smoke test %s %s\nOutput:\n%s", command, failure, output)
+	if hints := SmokeTestHints(output); len(hints) > 0 {
+		msg = fmt.Sprintf("%s\nHints:\n  %s", msg, strings.Join(hints, "\n  "))
+	}
+	return errors.New(msg)
+}
+
+// SmokeTestHints returns hints about missing classes and reachability metadata found in output
+func SmokeTestHints(output string) []string {
+	var hints []string
+	seen := map[string]bool{}
+
+	for _, m := range classNotFound.FindAllStringSubmatch(output, -1) {
+		if !seen[m[1]] {
+			seen[m[1]] = true
+			hints = append(hints, fmt.Sprintf("class %s was not found, it may have to be registered for reflection", m[1]))
+		}
+	}
+
+	for _, m := range missingRegistered.FindAllStringSubmatch(output, -1) {
+		if !seen[m[1]] {
+			seen[m[1]] = true
+			hints = append(hints, fmt.Sprintf("%s: reachability metadata is missing, it can be collected by running the application with -agentlib:native-image-agent", m[1]))
+		}
+	}
+
+	return hints
+}
@@ -0,0 +1,133 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package native_test
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/paketo-buildpacks/libpak/bard"
+	"github.com/paketo-buildpacks/libpak/effect"
+	"github.com/paketo-buildpacks/libpak/effect/mocks"
+	"github.com/sclevine/spec"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/paketo-buildpacks/native-image/v5/native"
+)
+
+func testSmokeTest(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		executor  *mocks.Executor
+		out       *bytes.Buffer
+		smokeTest native.SmokeTest
+	)
+
+	exitError := func(code int) error {
+		err := exec.Command("sh", "-c", fmt.Sprintf("exit %d", code)).Run()
+		Expect(err).To(HaveOccurred())
+		return err
+	}
+
+	respond := func(stdout string, stderr string, err error) {
+		executor.On("Execute", mock.Anything).Run(func(args mock.Arguments) {
+			e := args.Get(0).(effect.Execution)
+			_, _ = e.Stdout.Write([]byte(stdout))
+			_, _ = e.Stderr.Write([]byte(stderr))
+		}).Return(err)
+	}
+
+	it.Before(func() {
+		executor = &mocks.Executor{}
+		out = &bytes.Buffer{}
+
+		smokeTest = native.SmokeTest{
+			Command:  "{{.Executable}} --version",
+			Executor: executor,
+			Logger:   bard.NewLogger(out),
+			Timeout:  30 * time.Second,
+		}
+	})
+
+	it("runs the command with a timeout", func() {
+		respond("1.0.0", "", nil)
+
+		Expect(smokeTest.Run("/layers/native-image/app", "/workspace")).To(Succeed())
+
+		e := executor.Calls[0].Arguments[0].(effect.Execution)
+		Expect(e.Command).To(Equal("timeout"))
+		Expect(e.Args).To(Equal([]string{"--kill-after=5s", "30s", "/layers/native-image/app", "--version"}))
+		Expect(e.Dir).To(Equal("/workspace"))
+		Expect(out.String()).To(ContainSubstring("Smoke test passed"))
+	})
+
+	it("fails on an unexpected exit code", func() {
+		respond("", "Exception in thread \"main\" java.lang.ClassNotFoundException: com.example.Missing\n", exitError(1))
+
+		err := smokeTest.Run("/layers/native-image/app", "/workspace")
+		Expect(err).To(MatchError(ContainSubstring("exited with 1, expected 0")))
+		Expect(err).To(MatchError(ContainSubstring("ClassNotFoundException: com.example.Missing")))
+		Expect(err).To(MatchError(ContainSubstring("class com.example.Missing was not found")))
+	})
+
+	it("accepts the expected exit code", func() {
+		respond("", "", exitError(2))
+		smokeTest.ExitCode = 2
+
+		Expect(smokeTest.Run("/layers/native-image/app", "/workspace")).To(Succeed())
+	})
+
+	it("checks the output against a regular expression", func() {
+		respond("Started Application", "", exitError(1))
+		smokeTest.ExitCode = native.AnyExitCode
+		smokeTest.Output = regexp.MustCompile(`Started \w+`)
+
+		Expect(smokeTest.Run("/layers/native-image/app", "/workspace")).To(Succeed())
+	})
+
+	it("fails if the output does not match", func() {
+		respond("Stopped", "", nil)
+		smokeTest.Output = regexp.MustCompile(`Started \w+`)
+
+		Expect(smokeTest.Run("/layers/native-image/app", "/workspace")).
+			To(MatchError(ContainSubstring("output does not match Started \\w+")))
+	})
+
+	it("fails if the command times out", func() {
+		respond("", "", exitError(124))
+
+		Expect(smokeTest.Run("/layers/native-image/app", "/workspace")).
+			To(MatchError(ContainSubstring("timed out after 30s")))
+	})
+
+	it("finds hints about missing reachability metadata", func() {
+		Expect(native.SmokeTestHints(`
+com.oracle.svm.core.jdk.MissingReflectionRegistrationError: The program tried to reflectively invoke method
+java.lang.NoClassDefFoundError: com/example/Other
+java.lang.NoClassDefFoundError: com/example/Other
+`)).To(Equal([]string{
+			"class com/example/Other was not found, it may have to be registered for reflection",
+			"MissingReflectionRegistrationError: reachability metadata is missing, it can be collected by running the application with -agentlib:native-image-agent",
+		}))
+	})
+}
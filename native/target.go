@@ -0,0 +1,69 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package native
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// distroGLIBCVersions is the version of glibc provided by each release of a distribution
+var distroGLIBCVersions = map[string]string{
+	"ubuntu 18.04": "2.27",
+	"ubuntu 22.04": "2.35",
+	"ubuntu 24.04": "2.39",
+	"debian 11":    "2.31",
+	"debian 12":    "2.36",
+}
+
+// Target is the platform that the native image is built for, as described by the platform with $CNB_TARGET_OS,
+// $CNB_TARGET_ARCH, $CNB_TARGET_DISTRO_NAME and $CNB_TARGET_DISTRO_VERSION
+type Target struct {
+	OS            string
+	Arch          string
+	DistroName    string
+	DistroVersion string
+}
+
+// NewTarget reads the target from the environment
+func NewTarget() Target {
+	return Target{
+		OS:            os.Getenv("CNB_TARGET_OS"),
+		Arch:          os.Getenv("CNB_TARGET_ARCH"),
+		DistroName:    os.Getenv("CNB_TARGET_DISTRO_NAME"),
+		DistroVersion: os.Getenv("CNB_TARGET_DISTRO_VERSION"),
+	}
+}
+
+// IsSet returns whether the platform provided a target. Platforms that only support stacks do not.
+func (t Target) IsSet() bool {
+	return t.OS != ""
+}
+
+// GLIBCVersion returns the version of glibc provided by the distribution of the target, empty if it is unknown
+func (t Target) GLIBCVersion() string {
+	return distroGLIBCVersions[strings.ToLower(fmt.Sprintf("%s %s", t.DistroName, t.DistroVersion))]
+}
+
+func (t Target) String() string {
+	s := fmt.Sprintf("%s/%s", t.OS, t.Arch)
+	if t.DistroName != "" {
+		s = fmt.Sprintf("%s %s %s", s, t.DistroName, t.DistroVersion)
+	}
+	return s
+}
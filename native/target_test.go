@@ -0,0 +1,56 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package native_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+
+	"github.com/paketo-buildpacks/native-image/v5/native"
+)
+
+func testTarget(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+	)
+
+	it("reads the target from the environment", func() {
+		t.Setenv("CNB_TARGET_OS", "linux")
+		t.Setenv("CNB_TARGET_ARCH", "arm64")
+		t.Setenv("CNB_TARGET_DISTRO_NAME", "ubuntu")
+		t.Setenv("CNB_TARGET_DISTRO_VERSION", "22.04")
+
+		target := native.NewTarget()
+		Expect(target).To(Equal(native.Target{OS: "linux", Arch: "arm64", DistroName: "ubuntu", DistroVersion: "22.04"}))
+		Expect(target.IsSet()).To(BeTrue())
+		Expect(target.String()).To(Equal("linux/arm64 ubuntu 22.04"))
+		Expect(target.GLIBCVersion()).To(Equal("2.35"))
+	})
+
+	it("is not set by platforms that only support stacks", func() {
+		t.Setenv("CNB_TARGET_OS", "")
+		t.Setenv("CNB_TARGET_ARCH", "amd64")
+
+		Expect(native.NewTarget().IsSet()).To(BeFalse())
+	})
+
+	it("does not know the glibc version of unknown distributions", func() {
+		Expect(native.Target{OS: "linux", DistroName: "alpine", DistroVersion: "3.20"}.GLIBCVersion()).To(BeEmpty())
+	})
+}
@@ -4,13 +4,15 @@ set -euo pipefail
 GOMOD=$(head -1 go.mod | awk '{print $2}')
 GOOS="linux" GOARCH="amd64" go build -ldflags='-s -w' -o "linux/amd64/bin/main" "$GOMOD/cmd/main"
 GOOS="linux" GOARCH="arm64" go build -ldflags='-s -w' -o "linux/arm64/bin/main" "$GOMOD/cmd/main"
+GOOS="linux" GOARCH="amd64" go build -ldflags='-s -w' -o "linux/amd64/bin/launcher" "$GOMOD/cmd/launcher"
+GOOS="linux" GOARCH="arm64" go build -ldflags='-s -w' -o "linux/arm64/bin/launcher" "$GOMOD/cmd/launcher"
 
 if [ "${STRIP:-false}" != "false" ]; then
-  strip linux/amd64/bin/main linux/arm64/bin/main
+  strip linux/amd64/bin/main linux/arm64/bin/main linux/amd64/bin/launcher linux/arm64/bin/launcher
 fi
 
 if [ "${COMPRESS:-none}" != "none" ]; then
-  $COMPRESS linux/amd64/bin/main linux/arm64/bin/main
+  $COMPRESS linux/amd64/bin/main linux/arm64/bin/main linux/amd64/bin/launcher linux/arm64/bin/launcher
 fi
 ln -fs main linux/amd64/bin/build
 ln -fs main linux/amd64/bin/detect